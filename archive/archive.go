@@ -0,0 +1,172 @@
+// Package archive backs up Tumblr blogs to a local directory and reads them back.
+//
+// An archive directory is laid out as:
+//
+//	manifest.json      what has been archived, see Manifest
+//	blog.json          the blog's information
+//	posts/<id>.json    published posts, with reblog and notes information
+//	drafts/<id>.json   draft posts, as of the last run
+//	queue/<id>.json    queued posts, as of the last run
+//	likes/<id>.json    posts the blog likes
+//	media/<id>/<file>  photos, audio and video of the posts, by post ID
+//
+// Posts are stored as the JSON Tumblr returned, including fields this package's
+// types don't model, so they can be read back with tumblr.UnmarshalRaw without
+// losing data.
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/atomicfile"
+)
+
+// The version of the directory layout written by this package
+const Version = 1
+
+// The collections of posts in an archive
+const (
+	Posts  = "posts"
+	Drafts = "drafts"
+	Queue  = "queue"
+	Likes  = "likes"
+)
+
+const (
+	manifestFile = "manifest.json"
+	blogFile     = "blog.json"
+	mediaDir     = "media"
+)
+
+// Returned when an archive was written by a newer version of this package
+var ErrUnsupportedVersion = errors.New("archive: unsupported archive version")
+
+// The record of what an archive holds, updated as a backup runs so an interrupted
+// backup resumes where it stopped
+type Manifest struct {
+	Version int               `json:"version"`          // The version of the directory layout
+	Blog    string            `json:"blog"`             // The hostname of the archived blog
+	Updated time.Time         `json:"updated"`          // When the archive was last written
+	Posts   Timeline          `json:"posts"`            // How much of the published posts is archived
+	Likes   Timeline          `json:"likes"`            // How much of the likes is archived
+	Drafts  int               `json:"drafts"`           // The number of drafts at the last run
+	Queue   int               `json:"queue"`            // The number of queued posts at the last run
+	Media   map[string]string `json:"media,omitempty"`  // The downloaded media files, relative to the archive, by URL
+	Failed  map[string]int    `json:"failed,omitempty"` // The media files that failed to download, by URL, with the ID of their post
+}
+
+// How much of a timeline, published posts or likes, newest first, is archived. The
+// timeline is archived from Newest back to its start, except for the items between
+// Resume and its start when an earlier walk was interrupted.
+type Timeline struct {
+	Newest int `json:"newest,omitempty"` // The timestamp of the newest archived item, 0 before the first run
+	Resume int `json:"resume,omitempty"` // The timestamp an interrupted walk continues before, 0 when none was interrupted
+	Count  int `json:"count"`            // The number of archived items
+}
+
+// This method reads the manifest of an archive, returning an empty manifest for a
+// new archive
+// dir - The archive directory
+func ReadManifest(dir string) (*Manifest, error) {
+	manifest := &Manifest{Version: Version, Media: make(map[string]string), Failed: make(map[string]int)}
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("archive: reading the manifest: %w", err)
+	}
+	if manifest.Version > Version {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, manifest.Version)
+	}
+	if manifest.Media == nil {
+		manifest.Media = make(map[string]string)
+	}
+	if manifest.Failed == nil {
+		manifest.Failed = make(map[string]int)
+	}
+	return manifest, nil
+}
+
+// This method writes the manifest of an archive
+// dir - The archive directory
+func (manifest *Manifest) write(dir string) error {
+	manifest.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(filepath.Join(dir, manifestFile), bytes.NewReader(data))
+}
+
+// This method reads the posts of a collection of an archive, newest first
+// dir - The archive directory
+// collection - Posts, Drafts, Queue or Likes
+func ReadPosts(dir string, collection string) ([]tumblr.Post, error) {
+	files, err := filepath.Glob(filepath.Join(dir, collection, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]tumblr.Post, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var post tumblr.Post
+		if err := tumblr.UnmarshalRaw(data, &post); err != nil {
+			return nil, fmt.Errorf("archive: reading %s: %w", file, err)
+		}
+		posts = append(posts, post)
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		if posts[i].Timestamp != posts[j].Timestamp {
+			return posts[i].Timestamp > posts[j].Timestamp
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return posts, nil
+}
+
+// This method reads the blog information of an archive
+// dir - The archive directory
+func ReadBlog(dir string) (tumblr.Blog, error) {
+	var blogInfo tumblr.BlogInfo
+	data, err := os.ReadFile(filepath.Join(dir, blogFile))
+	if err != nil {
+		return blogInfo.Blog, err
+	}
+	err = tumblr.UnmarshalRaw(data, &blogInfo)
+	return blogInfo.Blog, err
+}
+
+// This method returns the path of a post's file in an archive
+func postPath(dir string, collection string, id int) string {
+	return filepath.Join(dir, collection, strconv.Itoa(id)+".json")
+}
+
+// This method returns the name a media URL is stored under, e.g. media/123/tumblr_abc_1280.jpg
+func mediaPath(postID int, mediaURL string) string {
+	name := mediaURL
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	name = name[strings.LastIndex(name, "/")+1:]
+	if name == "" || name == "." || name == ".." {
+		name = "media"
+	}
+	return filepath.ToSlash(filepath.Join(mediaDir, strconv.Itoa(postID), name))
+}
@@ -0,0 +1,430 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/atomicfile"
+)
+
+// A backup of a blog to an archive directory. The first run archives the blog's
+// published posts, drafts, queue and likes and downloads their media; later runs
+// archive what was added since, and continue where an interrupted run stopped.
+// Drafts and the queue change as posts are published, so every run archives them
+// again, removing the ones that are gone.
+type Backup struct {
+	BlogHostname string       // The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+	Dir          string       // The archive directory
+	Media        bool         // Whether to download the posts' photos, audio and video. Default: true
+	Full         bool         // Whether to archive every published post and like again, e.g. to pick up edits
+	HTTPClient   *http.Client // The HTTP client media is downloaded with. Default: http.DefaultClient
+	Logger       *slog.Logger // Receives the progress of a run. Default: silent
+
+	api *tumblr.Tumblr
+}
+
+// The numbers of posts and media files a run wrote
+type Result struct {
+	Posts       int `json:"posts"`        // Published posts
+	Drafts      int `json:"drafts"`       // Draft posts
+	Queue       int `json:"queue"`        // Queued posts
+	Likes       int `json:"likes"`        // Liked posts
+	Media       int `json:"media"`        // Downloaded media files
+	MediaErrors int `json:"media_errors"` // Media files that failed to download, retried by the next run
+}
+
+// This method creates a backup of a blog
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// dir - The archive directory, created if needed
+func NewBackup(api *tumblr.Tumblr, blogHostname string, dir string) *Backup {
+	return &Backup{
+		BlogHostname: blogHostname,
+		Dir:          dir,
+		Media:        true,
+		HTTPClient:   http.DefaultClient,
+		Logger:       slog.New(slog.DiscardHandler),
+		api:          api,
+	}
+}
+
+// The state of one run
+type run struct {
+	*Backup
+	ctx      context.Context
+	api      *tumblr.Tumblr
+	manifest *Manifest
+	result   Result
+}
+
+// This method runs the backup. The manifest is written after every page of posts,
+// so a run that fails or whose context is canceled can be run again to continue.
+// ctx - The context of every request
+func (backup *Backup) Run(ctx context.Context) (Result, error) {
+	manifest, err := ReadManifest(backup.Dir)
+	if err != nil {
+		return Result{}, err
+	}
+	if manifest.Blog != "" && manifest.Blog != backup.BlogHostname {
+		return Result{}, fmt.Errorf("archive: %s is an archive of %s", backup.Dir, manifest.Blog)
+	}
+	manifest.Blog = backup.BlogHostname
+
+	r := &run{Backup: backup, ctx: ctx, manifest: manifest}
+	r.api = backup.api.WithContext(ctx)
+	r.api.SetPreserveRaw(true)
+
+	var blogInfo tumblr.BlogInfo
+	if err := r.api.Try(ctx, func(api *tumblr.Tumblr) { blogInfo = api.BlogInfo(backup.BlogHostname) }); err != nil {
+		return r.result, err
+	}
+	data, err := json.MarshalIndent(blogInfo, "", "  ")
+	if err == nil {
+		err = atomicfile.Write(filepath.Join(backup.Dir, blogFile), bytes.NewReader(data))
+	}
+	if err != nil {
+		return r.result, err
+	}
+
+	if backup.Media {
+		if err := r.retryMedia(); err != nil {
+			return r.result, err
+		}
+	}
+	if backup.Full {
+		manifest.Posts.Newest, manifest.Posts.Resume = 0, 0
+		manifest.Likes.Newest, manifest.Likes.Resume = 0, 0
+	}
+	err = r.timeline(Posts, &manifest.Posts, func(api *tumblr.Tumblr, before int) []tumblr.Post {
+		params := map[string]string{"reblog_info": "true", "notes_info": "true"}
+		if before > 0 {
+			params["before"] = strconv.Itoa(before)
+		}
+		return api.BlogPosts(backup.BlogHostname, params).Posts
+	}, func(post tumblr.Post) int {
+		return post.Timestamp
+	})
+	if err == nil {
+		err = r.timeline(Likes, &manifest.Likes, func(api *tumblr.Tumblr, before int) []tumblr.Post {
+			params := make(map[string]string)
+			if before > 0 {
+				params["before"] = strconv.Itoa(before)
+			}
+			return api.BlogLikes(backup.BlogHostname, params).LikedPost
+		}, likedTimestamp)
+	}
+	if err == nil {
+		err = r.snapshot(Drafts, &manifest.Drafts, func(api *tumblr.Tumblr, page []tumblr.Post, count int) []tumblr.Post {
+			params := make(map[string]string)
+			if len(page) > 0 {
+				params["before_id"] = strconv.Itoa(page[len(page)-1].ID)
+			}
+			return api.BlogDraftPosts(backup.BlogHostname, params).Posts
+		})
+	}
+	if err == nil {
+		err = r.snapshot(Queue, &manifest.Queue, func(api *tumblr.Tumblr, page []tumblr.Post, count int) []tumblr.Post {
+			return api.BlogQueuedPosts(backup.BlogHostname, map[string]string{"offset": strconv.Itoa(count)}).Posts
+		})
+	}
+	if writeErr := manifest.write(backup.Dir); err == nil {
+		err = writeErr
+	}
+	return r.result, err
+}
+
+// This method calls the API, returning the posts and whether the call failed,
+// including a response that couldn't be decoded, which returns no posts like the
+// end of a timeline
+func (r *run) fetch(call func(api *tumblr.Tumblr) []tumblr.Post) ([]tumblr.Post, error) {
+	var posts []tumblr.Post
+	err := r.api.Try(r.ctx, func(api *tumblr.Tumblr) { posts = call(api) })
+	return posts, err
+}
+
+// This method archives a timeline: first what was added since the last run, then
+// what an interrupted run left, or the whole timeline on the first run
+// collection - Posts or Likes
+// state - The timeline's state in the manifest
+// fetch - Fetches the page of items before a timestamp, 0 for the newest items
+// cursor - Returns the timestamp of an item
+func (r *run) timeline(collection string, state *Timeline, fetch func(api *tumblr.Tumblr, before int) []tumblr.Post, cursor func(tumblr.Post) int) error {
+	seen := make(map[int]bool)
+	if state.Newest > 0 {
+		newest := state.Newest
+		err := r.walk(collection, seen, fetch, cursor, 0, state.Newest, func(top, next int) error {
+			newest = max(newest, top)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		state.Newest = newest
+		if err := r.manifest.write(r.Dir); err != nil {
+			return err
+		}
+	}
+	if state.Newest > 0 && state.Resume == 0 {
+		return nil
+	}
+	err := r.walk(collection, seen, fetch, cursor, state.Resume, 0, func(top, next int) error {
+		state.Newest = max(state.Newest, top)
+		state.Resume = next
+		return r.manifest.write(r.Dir)
+	})
+	if err != nil {
+		return err
+	}
+	state.Resume = 0
+	return r.manifest.write(r.Dir)
+}
+
+// This method walks a timeline back from a timestamp, archiving every item not yet
+// seen, until it reaches the timeline's start or an item no newer than stop
+// seen - The IDs of the items seen by earlier walks of the run
+// before - The timestamp to start before, 0 for the newest items
+// stop - The timestamp to stop at, 0 to walk to the start
+// page - Called after every page with the newest timestamp of the page and the
+// timestamp the next page starts before
+func (r *run) walk(collection string, seen map[int]bool, fetch func(api *tumblr.Tumblr, before int) []tumblr.Post, cursor func(tumblr.Post) int,
+	before, stop int, page func(top, next int) error) error {
+	for {
+		posts, err := r.fetch(func(api *tumblr.Tumblr) []tumblr.Post { return fetch(api, before) })
+		if err != nil || len(posts) == 0 {
+			return err
+		}
+		top, oldest, fresh := 0, cursor(posts[0]), 0
+		for _, post := range posts {
+			top, oldest = max(top, cursor(post)), min(oldest, cursor(post))
+			if seen[post.ID] {
+				continue
+			}
+			seen[post.ID] = true
+			fresh++
+			if err := r.save(collection, post); err != nil {
+				return err
+			}
+		}
+		// Items sharing the oldest timestamp may continue on the next page, so it
+		// starts one second later, unless the page held nothing new
+		next := oldest + 1
+		if fresh == 0 {
+			next = oldest
+		}
+		if err := page(top, next); err != nil {
+			return err
+		}
+		r.Logger.Info("archived page", "collection", collection, "posts", fresh, "before", next)
+		if (stop > 0 && oldest <= stop) || next == before {
+			return nil
+		}
+		before = next
+	}
+}
+
+// This method archives every post of a collection that changes between runs and
+// removes the archived posts that are gone
+// collection - Drafts or Queue
+// count - The collection's number of posts in the manifest, set when it is archived
+// fetch - Fetches the page after the last page and count posts
+func (r *run) snapshot(collection string, count *int, fetch func(api *tumblr.Tumblr, page []tumblr.Post, count int) []tumblr.Post) error {
+	seen := make(map[string]bool)
+	var page []tumblr.Post
+	for {
+		last := page
+		var err error
+		page, err = r.fetch(func(api *tumblr.Tumblr) []tumblr.Post { return fetch(api, last, len(seen)) })
+		if err != nil {
+			return err
+		}
+		fresh := 0
+		for _, post := range page {
+			name := strconv.Itoa(post.ID) + ".json"
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			fresh++
+			if err := r.save(collection, post); err != nil {
+				return err
+			}
+		}
+		if fresh == 0 {
+			break
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(r.Dir, collection, "*.json"))
+	for _, file := range files {
+		if !seen[filepath.Base(file)] {
+			if err := os.Remove(file); err != nil {
+				return err
+			}
+		}
+	}
+	*count = len(seen)
+	return r.manifest.write(r.Dir)
+}
+
+// This method writes a post to a collection and downloads its media
+func (r *run) save(collection string, post tumblr.Post) error {
+	data, err := json.MarshalIndent(post, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := postPath(r.Dir, collection, post.ID)
+	_, err = os.Stat(path)
+	isNew := errors.Is(err, os.ErrNotExist)
+	if err := atomicfile.Write(path, bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	switch collection {
+	case Posts:
+		r.result.Posts++
+		if isNew {
+			r.manifest.Posts.Count++
+		}
+	case Likes:
+		r.result.Likes++
+		if isNew {
+			r.manifest.Likes.Count++
+		}
+	case Drafts:
+		r.result.Drafts++
+	case Queue:
+		r.result.Queue++
+	}
+
+	if !r.Media {
+		return nil
+	}
+	for _, mediaURL := range MediaURLs(post) {
+		if _, found := r.manifest.Media[mediaURL]; found {
+			continue
+		}
+		err := r.download(post.ID, mediaURL)
+		if r.ctx.Err() != nil {
+			return r.ctx.Err()
+		}
+		if err != nil {
+			r.result.MediaErrors++
+			r.manifest.Failed[mediaURL] = post.ID
+			r.Logger.Warn("media download failed", "post", post.ID, "url", mediaURL, "error", err)
+		}
+	}
+	return nil
+}
+
+// This method downloads the media files that failed to download in earlier runs,
+// whose posts the timelines won't visit again
+func (r *run) retryMedia() error {
+	urls := make([]string, 0, len(r.manifest.Failed))
+	for mediaURL := range r.manifest.Failed {
+		urls = append(urls, mediaURL)
+	}
+	sort.Strings(urls)
+	for _, mediaURL := range urls {
+		postID := r.manifest.Failed[mediaURL]
+		if _, found := r.manifest.Media[mediaURL]; found {
+			delete(r.manifest.Failed, mediaURL)
+			continue
+		}
+		err := r.download(postID, mediaURL)
+		if r.ctx.Err() != nil {
+			return r.ctx.Err()
+		}
+		if err != nil {
+			r.result.MediaErrors++
+			r.Logger.Warn("media download failed again", "post", postID, "url", mediaURL, "error", err)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	return r.manifest.write(r.Dir)
+}
+
+// This method downloads a media file of a post into the archive
+func (r *run) download(postID int, mediaURL string) error {
+	request, err := http.NewRequestWithContext(r.ctx, "GET", mediaURL, nil)
+	if err != nil {
+		return err
+	}
+	response, err := r.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("archive: downloading %s: %s", mediaURL, response.Status)
+	}
+
+	name := mediaPath(postID, mediaURL)
+	path := filepath.Join(r.Dir, filepath.FromSlash(name))
+	if err := atomicfile.Write(path, response.Body); err != nil {
+		return err
+	}
+	r.manifest.Media[mediaURL] = name
+	delete(r.manifest.Failed, mediaURL)
+	r.result.Media++
+	return nil
+}
+
+// This method returns the URLs of the media files of a post: the original size of
+// its photos, Tumblr hosted audio and video, and the media of its content blocks
+// and of the content blocks of its reblog trail
+func MediaURLs(post tumblr.Post) []string {
+	var urls []string
+	for _, photo := range post.Photos {
+		urls = append(urls, photo.OriginalSize.URL)
+	}
+	for _, media := range []struct{ url, kind string }{{"audio_url", "audio_type"}, {"video_url", "video_type"}} {
+		var mediaURL, kind string
+		json.Unmarshal(post.Extra[media.url], &mediaURL)
+		json.Unmarshal(post.Extra[media.kind], &kind)
+		if kind == "tumblr" {
+			urls = append(urls, mediaURL)
+		}
+	}
+	blocks := post.Content
+	for _, trail := range post.Trail {
+		blocks = append(blocks[:len(blocks):len(blocks)], trail.Content...)
+	}
+	for _, block := range blocks {
+		hosted := block.Provider == "" || block.Provider == "tumblr"
+		if (block.Type == "image" || hosted && (block.Type == "audio" || block.Type == "video")) && len(block.Media) > 0 {
+			// The media of a block are listed largest first
+			urls = append(urls, block.Media[0].URL)
+		}
+	}
+
+	seen := make(map[string]bool)
+	unique := urls[:0]
+	for _, mediaURL := range urls {
+		if mediaURL != "" && !seen[mediaURL] && (strings.HasPrefix(mediaURL, "https://") || strings.HasPrefix(mediaURL, "http://")) {
+			seen[mediaURL] = true
+			unique = append(unique, mediaURL)
+		}
+	}
+	return unique
+}
+
+// This method returns when a liked post was liked, falling back to when it was posted
+func likedTimestamp(post tumblr.Post) int {
+	var timestamp int
+	if json.Unmarshal(post.Extra["liked_timestamp"], &timestamp) == nil && timestamp > 0 {
+		return timestamp
+	}
+	return post.Timestamp
+}
@@ -0,0 +1,245 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/mattcunningham/gumblr/internal/tumblrtest"
+)
+
+// A blog served by a test server, two posts per page
+type testBlog struct {
+	sync.Mutex
+	posts     []map[string]interface{} // newest first
+	likes     []map[string]interface{}
+	drafts    []map[string]interface{}
+	failAfter int  // fail post pages before this timestamp, 0 to never fail
+	truncate  bool // whether failing post pages and the drafts are answered with truncated JSON
+	media     int  // number of media downloads
+	mediaDown bool // whether media downloads fail
+}
+
+// This method answers a request with a response whose JSON is cut off
+func truncated(w http.ResponseWriter) {
+	fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[{"id":`)
+}
+
+func post(id int, timestamp int) map[string]interface{} {
+	return map[string]interface{}{
+		"id": id, "timestamp": timestamp, "type": "photo", "custom_field": "kept",
+		"photos": []interface{}{map[string]interface{}{"original_size": map[string]interface{}{"url": fmt.Sprintf("https://64.media.tumblr.com/%d/photo.jpg", id)}}},
+	}
+}
+
+func (blog *testBlog) page(w http.ResponseWriter, r *http.Request, key string, items []map[string]interface{}) {
+	before, _ := strconv.Atoi(r.URL.Query().Get("before"))
+	cursor := "timestamp"
+	if key == "liked_posts" {
+		cursor = "liked_timestamp"
+	}
+	var page []map[string]interface{}
+	for _, item := range items {
+		if (before == 0 || item[cursor].(int) < before) && len(page) < 2 {
+			page = append(page, item)
+		}
+	}
+	data, _ := json.Marshal(map[string]interface{}{"meta": map[string]interface{}{"status": 200, "msg": "OK"}, "response": map[string]interface{}{key: page}})
+	w.Write(data)
+}
+
+func (blog *testBlog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	blog.Lock()
+	defer blog.Unlock()
+	switch r.URL.Path {
+	case "/v2/blog/staff.tumblr.com/info":
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff","uuid":"t:abc"}}}`)
+	case "/v2/blog/staff.tumblr.com/posts":
+		if r.URL.Query().Get("reblog_info") != "true" || r.URL.Query().Get("notes_info") != "true" {
+			http.Error(w, "missing params", http.StatusBadRequest)
+			return
+		}
+		if before, _ := strconv.Atoi(r.URL.Query().Get("before")); blog.failAfter > 0 && before > 0 && before < blog.failAfter && blog.truncate {
+			truncated(w)
+			return
+		} else if blog.failAfter > 0 && before > 0 && before < blog.failAfter {
+			http.Error(w, `{"meta":{"status":500,"msg":"Server Error"}}`, http.StatusInternalServerError)
+			return
+		}
+		blog.page(w, r, "posts", blog.posts)
+	case "/v2/blog/staff.tumblr.com/likes":
+		blog.page(w, r, "liked_posts", blog.likes)
+	case "/v2/blog/staff.tumblr.com/posts/draft":
+		if blog.truncate {
+			truncated(w)
+			return
+		}
+		if r.URL.Query().Get("before_id") != "" {
+			blog.page(w, r, "posts", nil)
+			return
+		}
+		blog.page(w, r, "posts", blog.drafts)
+	case "/v2/blog/staff.tumblr.com/posts/queue":
+		blog.page(w, r, "posts", nil)
+	default:
+		if r.Host == "64.media.tumblr.com" && blog.mediaDown {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Host == "64.media.tumblr.com" {
+			blog.media++
+			fmt.Fprint(w, "image data")
+			return
+		}
+		http.NotFound(w, r)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	blog := &testBlog{}
+	for i := 5; i >= 1; i-- {
+		blog.posts = append(blog.posts, post(i, i*100))
+	}
+	like := post(900, 10)
+	like["liked_timestamp"] = 950
+	blog.likes = []map[string]interface{}{like}
+	server, client := tumblrtest.NewServer(t, blog)
+	httpClient := tumblrtest.HTTPClient(server)
+	client.SetMaxRetries(0)
+	dir := t.TempDir()
+
+	backup := NewBackup(client, "staff.tumblr.com", dir)
+	backup.HTTPClient = httpClient
+
+	// The first run is interrupted after two pages
+	blog.drafts = []map[string]interface{}{post(7, 700)}
+	blog.failAfter = 402
+	if _, err := backup.Run(context.Background()); err == nil {
+		t.Fatal("Interrupted run succeeded")
+	}
+	manifest, _ := ReadManifest(dir)
+	if manifest.Posts.Newest != 500 || manifest.Posts.Resume != 401 || manifest.Posts.Count != 2 {
+		t.Errorf("Interrupted run left %+v", manifest.Posts)
+	}
+
+	// The second run continues where it stopped
+	blog.failAfter = 0
+	result, err := backup.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, _ = ReadManifest(dir)
+	if manifest.Posts.Resume != 0 || manifest.Posts.Count != 5 || manifest.Likes.Newest != 950 || manifest.Drafts != 1 {
+		t.Errorf("Resumed run left %+v", manifest)
+	}
+	if result.Likes != 1 || result.Drafts != 1 || blog.media != 7 || len(manifest.Media) != 7 {
+		t.Errorf("Resumed run returned %+v with %d media downloads", result, blog.media)
+	}
+
+	// Later runs archive only what was added, and the drafts that remain
+	blog.posts = append([]map[string]interface{}{post(6, 600)}, blog.posts...)
+	blog.drafts = nil
+	result, err = backup.Run(context.Background())
+	if err != nil || result.Posts != 2 || result.Media != 1 || result.Drafts != 0 {
+		t.Errorf("Incremental run returned %+v with error %v", result, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, Drafts, "7.json")); !os.IsNotExist(err) {
+		t.Error("Published draft is still archived")
+	}
+
+	posts, err := ReadPosts(dir, Posts)
+	if err != nil || len(posts) != 6 {
+		t.Fatalf("Read %d posts with error %v", len(posts), err)
+	}
+	var ids []int
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	if !sort.IsSorted(sort.Reverse(sort.IntSlice(ids))) || string(posts[0].Extra["custom_field"]) != `"kept"` {
+		t.Errorf("Read posts %v with extra fields %v", ids, posts[0].Extra)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "media", "6", "photo.jpg")); err != nil || string(data) != "image data" {
+		t.Errorf("Media file holds %q with error %v", data, err)
+	}
+	if info, err := ReadBlog(dir); err != nil || info.Name != "staff" || string(info.Extra["uuid"]) != `"t:abc"` {
+		t.Errorf("Read blog %+v with error %v", info, err)
+	}
+
+	if _, err := NewBackup(client, "other.tumblr.com", dir).Run(context.Background()); err == nil {
+		t.Error("Backed up another blog into the archive")
+	}
+}
+
+func TestBackupUndecodablePages(t *testing.T) {
+	blog := &testBlog{drafts: []map[string]interface{}{post(7, 700)}}
+	for i := 5; i >= 1; i-- {
+		blog.posts = append(blog.posts, post(i, i*100))
+	}
+	server, client := tumblrtest.NewServer(t, blog)
+	httpClient := tumblrtest.HTTPClient(server)
+	client.SetMaxRetries(0)
+	dir := t.TempDir()
+	backup := NewBackup(client, "staff.tumblr.com", dir)
+	backup.HTTPClient = httpClient
+
+	// A page that can't be decoded fails the run rather than ending the timeline
+	blog.failAfter, blog.truncate = 402, true
+	if _, err := backup.Run(context.Background()); err == nil {
+		t.Fatal("Run with an undecodable page succeeded")
+	}
+	manifest, _ := ReadManifest(dir)
+	if manifest.Posts.Newest != 500 || manifest.Posts.Resume != 401 || manifest.Posts.Count != 2 {
+		t.Errorf("Run with an undecodable page left %+v", manifest.Posts)
+	}
+
+	// Nor does it empty the drafts
+	blog.failAfter, blog.truncate = 0, false
+	if _, err := backup.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	blog.truncate = true
+	if _, err := backup.Run(context.Background()); err == nil {
+		t.Error("Run with undecodable drafts succeeded")
+	}
+	manifest, _ = ReadManifest(dir)
+	if _, err := os.Stat(filepath.Join(dir, Drafts, "7.json")); err != nil || manifest.Drafts != 1 {
+		t.Errorf("Draft was removed by a failed run, leaving %d drafts: %v", manifest.Drafts, err)
+	}
+}
+
+func TestBackupRetriesMedia(t *testing.T) {
+	blog := &testBlog{mediaDown: true}
+	for i := 5; i >= 1; i-- {
+		blog.posts = append(blog.posts, post(i, i*100))
+	}
+	server, client := tumblrtest.NewServer(t, blog)
+	httpClient := tumblrtest.HTTPClient(server)
+	client.SetMaxRetries(0)
+	dir := t.TempDir()
+	backup := NewBackup(client, "staff.tumblr.com", dir)
+	backup.HTTPClient = httpClient
+
+	result, err := backup.Run(context.Background())
+	manifest, _ := ReadManifest(dir)
+	if err != nil || result.MediaErrors != 5 || len(manifest.Failed) != 5 || manifest.Failed["https://64.media.tumblr.com/1/photo.jpg"] != 1 {
+		t.Fatalf("Run returned %+v with error %v, leaving failed media %v", result, err, manifest.Failed)
+	}
+
+	// The next run only visits the newest page of posts, but downloads all the media
+	blog.mediaDown = false
+	result, err = backup.Run(context.Background())
+	manifest, _ = ReadManifest(dir)
+	if err != nil || result.Posts != 2 || result.Media != 5 || result.MediaErrors != 0 || len(manifest.Failed) != 0 || len(manifest.Media) != 5 {
+		t.Errorf("Second run returned %+v with error %v, leaving failed media %v", result, err, manifest.Failed)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "media", "1", "photo.jpg")); err != nil || string(data) != "image data" {
+		t.Errorf("Retried media file holds %q with error %v", data, err)
+	}
+}
@@ -0,0 +1,427 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/atomicfile"
+)
+
+// Tumblr's limits on posting
+const (
+	DefaultPerDay     = 250 // posts a blog can publish per day
+	DefaultQueueLimit = 300 // posts a blog's queue can hold
+)
+
+// A restore of an archive into a blog, e.g. to recover a deleted blog or migrate
+// one. Posts are created oldest first with their original dates, tags, slugs and
+// formats, and media is uploaded again from the archive. The new ID of every
+// restored post is recorded in a mapping file, so running a restore again skips
+// what was restored and continues with the rest.
+//
+// A blog can only publish PerDay posts a day. Published posts beyond what is left
+// of today's limit are placed in the queue, PerDay for each following day, until
+// the queue holds QueueLimit posts; the rest is left for a later run. Tumblr
+// dates queued posts when they are published, so their original date is lost.
+type Restore struct {
+	BlogHostname string       // The blog to restore into
+	Dir          string       // The archive directory
+	Mapping      string       // The file the old and new post IDs are written to. Default: restore-<blog>.json in the archive
+	Collections  []string     // The collections to restore. Default: Posts, Drafts and Queue
+	DryRun       bool         // Whether to only return what would be restored, without creating posts
+	PerDay       int          // The number of posts published per day. Default: DefaultPerDay
+	QueueLimit   int          // The number of posts the queue can hold. Default: DefaultQueueLimit
+	HTTPClient   *http.Client // The HTTP client media missing from the archive is downloaded with. Default: http.DefaultClient
+	Logger       *slog.Logger // Receives the progress of a run. Default: silent
+
+	api *tumblr.Tumblr
+	now func() time.Time
+}
+
+// The mapping of archived posts to restored posts
+type Mapping struct {
+	Version int                  `json:"version"` // The version of the mapping file
+	Blog    string               `json:"blog"`    // The blog the posts were restored into
+	Posts   map[int]RestoredPost `json:"posts"`   // The restored posts by their archived ID
+}
+
+// A post restored, or to be restored in a dry run
+type RestoredPost struct {
+	OldID      int       `json:"old_id"`               // The ID of the archived post
+	ID         int       `json:"id,omitempty"`         // The ID of the new post, 0 in a dry run
+	Collection string    `json:"collection"`           // The collection of the archived post
+	Type       string    `json:"type"`                 // The type of the new post
+	State      string    `json:"state"`                // The state of the new post: published, private, draft or queue
+	PublishOn  time.Time `json:"publish_on,omitzero"`  // When a post placed in the queue to spread the work is published
+	Restored   time.Time `json:"restored,omitzero"`    // When the post was created
+	Params     []string  `json:"params,omitempty"`     // The names of the parameters the post was created with
+	Unrestored string    `json:"unrestored,omitempty"` // Why the post couldn't be restored
+}
+
+// The outcome of a restore run
+type RestoreResult struct {
+	Restored    []RestoredPost `json:"restored"`    // The posts restored by this run, or that would be in a dry run
+	Skipped     int            `json:"skipped"`     // The posts restored by earlier runs
+	Unsupported []RestoredPost `json:"unsupported"` // The posts that can't be restored, e.g. of unknown types
+	Remaining   int            `json:"remaining"`   // The posts left for a later run by the daily limit and the queue
+}
+
+// This method creates a restore of an archive
+// dir - The archive directory
+// blogHostname - The blog to restore into (e.g., example.tumblr.com, example.com)
+func NewRestore(api *tumblr.Tumblr, dir string, blogHostname string) *Restore {
+	return &Restore{
+		BlogHostname: blogHostname,
+		Dir:          dir,
+		Mapping:      filepath.Join(dir, "restore-"+blogHostname+".json"),
+		Collections:  []string{Posts, Drafts, Queue},
+		PerDay:       DefaultPerDay,
+		QueueLimit:   DefaultQueueLimit,
+		HTTPClient:   http.DefaultClient,
+		Logger:       slog.New(slog.DiscardHandler),
+		api:          api,
+		now:          time.Now,
+	}
+}
+
+// This method reads a mapping file, returning an empty mapping when it doesn't exist
+// path - The mapping file
+func ReadMapping(path string) (*Mapping, error) {
+	mapping := &Mapping{Version: Version, Posts: make(map[int]RestoredPost)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return mapping, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("archive: reading the mapping: %w", err)
+	}
+	if mapping.Posts == nil {
+		mapping.Posts = make(map[int]RestoredPost)
+	}
+	return mapping, nil
+}
+
+// An archived post waiting to be restored
+type pending struct {
+	tumblr.Post
+	collection string
+}
+
+// This method runs the restore, stopping at the first post that fails to be created
+// ctx - The context of every request
+func (restore *Restore) Run(ctx context.Context) (RestoreResult, error) {
+	var result RestoreResult
+	if restore.PerDay < 1 || restore.QueueLimit < 0 {
+		return result, fmt.Errorf("archive: restoring needs a daily limit of at least 1 and a queue limit of at least 0, not %d and %d",
+			restore.PerDay, restore.QueueLimit)
+	}
+	mapping, err := ReadMapping(restore.Mapping)
+	if err != nil {
+		return result, err
+	}
+	if mapping.Blog != "" && mapping.Blog != restore.BlogHostname {
+		return result, fmt.Errorf("archive: %s maps posts restored into %s", restore.Mapping, mapping.Blog)
+	}
+	mapping.Blog = restore.BlogHostname
+	manifest, err := ReadManifest(restore.Dir)
+	if err != nil {
+		return result, err
+	}
+
+	var posts []pending
+	for _, collection := range restore.Collections {
+		archived, err := ReadPosts(restore.Dir, collection)
+		if err != nil {
+			return result, err
+		}
+		for _, post := range archived {
+			if _, found := mapping.Posts[post.ID]; found {
+				result.Skipped++
+				continue
+			}
+			posts = append(posts, pending{post, collection})
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].Timestamp < posts[j].Timestamp })
+
+	api := restore.api.WithContext(ctx)
+	schedule := restore.newSchedule(api, mapping)
+	for i, post := range posts {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		restored := RestoredPost{OldID: post.ID, Collection: post.collection, Type: post.Type, State: restoreState(post)}
+		params, err := restore.params(ctx, manifest, post.Post)
+		if err != nil {
+			restored.Unrestored = err.Error()
+			result.Unsupported = append(result.Unsupported, restored)
+			restore.Logger.Warn("post can't be restored", "post", post.ID, "error", err)
+			continue
+		}
+		if !schedule.place(&restored) {
+			result.Remaining++
+			continue
+		}
+		params["state"] = restored.State
+		if !restored.PublishOn.IsZero() {
+			params["publish_on"] = restored.PublishOn.Format(time.RFC3339)
+		}
+		restored.Type = params["type"]
+		for name := range params {
+			restored.Params = append(restored.Params, name)
+		}
+		sort.Strings(restored.Params)
+		if restore.DryRun {
+			result.Restored = append(result.Restored, restored)
+			continue
+		}
+
+		createdPost := api.CreatePost(restore.BlogHostname, params)
+		if createdPost.Meta.Status < 200 || createdPost.Meta.Status >= 300 || createdPost.ID == 0 {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			result.Remaining += len(posts) - i
+			return result, fmt.Errorf("archive: restoring post %d: %d %s", post.ID, createdPost.Meta.Status, createdPost.Meta.Msg)
+		}
+		restored.ID = createdPost.ID
+		restored.Restored = restore.now().UTC()
+		mapping.Posts[post.ID] = restored
+		if err := mapping.write(restore.Mapping); err != nil {
+			return result, err
+		}
+		result.Restored = append(result.Restored, restored)
+		restore.Logger.Info("restored post", "post", post.ID, "id", restored.ID, "state", restored.State)
+	}
+	return result, nil
+}
+
+// This method writes a mapping file
+func (mapping *Mapping) write(path string) error {
+	data, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(path, bytes.NewReader(data))
+}
+
+// This method returns the state an archived post is restored in
+func restoreState(post pending) string {
+	switch {
+	case post.collection == Drafts:
+		return "draft"
+	case post.collection == Queue:
+		return "queue"
+	case post.State == "private":
+		return "private"
+	}
+	return "published"
+}
+
+// The posts a run may still publish today and place in the queue
+type schedule struct {
+	today  int            // posts left to publish today
+	perDay int            // posts published on each following day
+	queued int            // posts left to place in the queue
+	days   map[int]int    // posts placed in the queue by day from today
+	start  time.Time      // the start of tomorrow
+	used   map[string]int // posts of earlier runs by day they're published on
+}
+
+// This method creates the schedule of a run. The limits left today are read from
+// the user's limits when Tumblr returns them, or else counted from the mapping.
+func (restore *Restore) newSchedule(api *tumblr.Tumblr, mapping *Mapping) *schedule {
+	now := restore.now().UTC()
+	s := &schedule{
+		today:  restore.PerDay,
+		perDay: restore.PerDay,
+		queued: restore.QueueLimit,
+		days:   make(map[int]int),
+		start:  now.Truncate(24 * time.Hour).Add(24 * time.Hour),
+		used:   make(map[string]int),
+	}
+	for _, restored := range mapping.Posts {
+		switch {
+		case restored.State == "published":
+			s.used[restored.Restored.Format(time.DateOnly)]++
+		case restored.State == "queue" && restored.PublishOn.IsZero():
+			s.queued--
+		case restored.State == "queue":
+			s.used[restored.PublishOn.Format(time.DateOnly)]++
+			if restored.PublishOn.After(now) {
+				s.queued--
+			}
+		}
+	}
+	s.today -= s.used[now.Format(time.DateOnly)]
+	if restore.DryRun {
+		return s
+	}
+	if limits := api.UserLimits().User.Posts; limits.Limit > 0 {
+		s.today = min(s.today, limits.Remaining)
+	}
+	return s
+}
+
+// This method fits a post into the schedule, returning false when there is no
+// room left for it
+func (s *schedule) place(restored *RestoredPost) bool {
+	switch restored.State {
+	case "draft", "private":
+		return true
+	case "queue":
+		if s.queued <= 0 {
+			return false
+		}
+		s.queued--
+		return true
+	}
+	if s.today > 0 {
+		s.today--
+		return true
+	}
+	if s.queued <= 0 {
+		return false
+	}
+	for day := 0; ; day++ {
+		publishOn := s.start.AddDate(0, 0, day)
+		if s.days[day]+s.used[publishOn.Format(time.DateOnly)] < s.perDay {
+			s.days[day]++
+			s.queued--
+			restored.State = "queue"
+			restored.PublishOn = publishOn
+			return true
+		}
+	}
+}
+
+// This method returns the params that create a post like an archived post
+func (restore *Restore) params(ctx context.Context, manifest *Manifest, post tumblr.Post) (map[string]string, error) {
+	params := map[string]string{"type": post.Type}
+	if post.Date != "" {
+		params["date"] = post.Date
+	}
+	if len(post.Tags) > 0 {
+		params["tags"] = strings.Join(post.Tags, ",")
+	}
+	if post.Format != "" {
+		params["format"] = post.Format
+	}
+	if post.Slug != "" {
+		params["slug"] = post.Slug
+	}
+	setParam := func(name string, value string) {
+		if value != "" {
+			params[name] = value
+		}
+	}
+
+	switch post.Type {
+	case "text":
+		setParam("title", post.Title)
+		setParam("body", post.Body)
+	case "photo":
+		setParam("caption", post.Caption)
+		var link string
+		json.Unmarshal(post.Extra["link_url"], &link)
+		setParam("link", link)
+		for i, photo := range post.Photos {
+			data, err := restore.media(ctx, manifest, photo.OriginalSize.URL)
+			if err != nil {
+				return nil, err
+			}
+			params["data["+strconv.Itoa(i)+"]"] = string(data)
+		}
+		if len(post.Photos) == 0 {
+			return nil, errors.New("photo post without photos")
+		}
+	case "quote":
+		setParam("quote", post.Text)
+		setParam("source", post.Source)
+	case "link":
+		setParam("title", post.Title)
+		setParam("url", post.URL)
+		setParam("description", post.Description)
+	case "chat":
+		setParam("title", post.Title)
+		var lines []string
+		for _, line := range post.Dialogue {
+			lines = append(lines, strings.TrimSpace(line.Label+" "+line.Phrase))
+		}
+		setParam("conversation", strings.Join(lines, "\n"))
+	case "audio", "video":
+		setParam("caption", post.Caption)
+		mediaURLs := MediaURLs(tumblr.Post{RawFields: post.RawFields})
+		if len(mediaURLs) > 0 {
+			data, err := restore.media(ctx, manifest, mediaURLs[0])
+			if err != nil {
+				return nil, err
+			}
+			params["data"] = string(data)
+		} else if post.Type == "audio" {
+			var externalURL string
+			json.Unmarshal(post.Extra["audio_source_url"], &externalURL)
+			if externalURL == "" {
+				return nil, errors.New("audio post without audio")
+			}
+			params["external_url"] = externalURL
+		} else {
+			if len(post.Player) == 0 {
+				return nil, errors.New("video post without video")
+			}
+			params["embed"] = post.Player[len(post.Player)-1].EmbedCode
+		}
+	case "answer":
+		// Answers can't be created, so they become text posts quoting the question
+		params["type"] = "text"
+		asker := post.AskingName
+		if asker == "" {
+			asker = "Anonymous"
+		}
+		params["body"] = fmt.Sprintf("<p><b>%s asked:</b></p><blockquote>%s</blockquote>%s", asker, post.Question, post.Answer)
+		params["format"] = "html"
+	default:
+		return nil, fmt.Errorf("posts of type %q can't be restored", post.Type)
+	}
+	return params, nil
+}
+
+// This method returns a media file of a post, from the archive when it was
+// downloaded, or else from its URL
+func (restore *Restore) media(ctx context.Context, manifest *Manifest, mediaURL string) ([]byte, error) {
+	if path, found := manifest.Media[mediaURL]; found {
+		return os.ReadFile(filepath.Join(restore.Dir, filepath.FromSlash(path)))
+	}
+	if restore.DryRun {
+		return nil, nil
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", mediaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := restore.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("archive: downloading %s: %s", mediaURL, response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
@@ -0,0 +1,163 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/atomicfile"
+	"github.com/mattcunningham/gumblr/internal/tumblrtest"
+)
+
+// This method writes an archived post
+func archivePost(t *testing.T, dir string, collection string, post string) {
+	var decoded tumblr.Post
+	if err := json.Unmarshal([]byte(post), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := atomicfile.Write(postPath(dir, collection, decoded.ID), strings.NewReader(post)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	archivePost(t, dir, Posts, `{"id":1,"type":"text","timestamp":100,"date":"2015-01-01 10:00:00 GMT","title":"First","body":"<p>Hi</p>","tags":["a","b"],"slug":"first","format":"html"}`)
+	archivePost(t, dir, Posts, `{"id":2,"type":"photo","timestamp":200,"caption":"Photo","photos":[{"original_size":{"url":"https://64.media.tumblr.com/2/photo.jpg"}}]}`)
+	archivePost(t, dir, Posts, `{"id":3,"type":"answer","timestamp":300,"asking_name":"david","question":"Why?","answer":"<p>Because</p>"}`)
+	archivePost(t, dir, Posts, `{"id":4,"type":"quote","timestamp":400,"text":"Quote"}`)
+	archivePost(t, dir, Posts, `{"id":5,"type":"blocks","timestamp":500}`)
+	archivePost(t, dir, Drafts, `{"id":6,"type":"text","timestamp":600,"body":"Draft"}`)
+	atomicfile.Write(filepath.Join(dir, "media", "2", "photo.jpg"), strings.NewReader("image data"))
+	manifest := &Manifest{Version: Version, Media: map[string]string{"https://64.media.tumblr.com/2/photo.jpg": "media/2/photo.jpg"}}
+	manifest.write(dir)
+
+	var mutex sync.Mutex
+	var created []url.Values
+	_, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch r.URL.Path {
+		case "/v2/user/limits":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"user":{"posts":{"limit":250,"remaining":2}}}}`)
+		case "/v2/blog/mirror.tumblr.com/post":
+			r.ParseForm()
+			created = append(created, r.PostForm)
+			fmt.Fprintf(w, `{"meta":{"status":201,"msg":"Created"},"response":{"id":%d,"state":"%s"}}`, 100+len(created), r.PostForm.Get("state"))
+		default:
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+	}))
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	restore := NewRestore(client, dir, "mirror.tumblr.com")
+	restore.now = func() time.Time { return now }
+	restore.PerDay = 1
+	restore.QueueLimit = 1
+
+	// A dry run creates nothing
+	restore.DryRun = true
+	result, err := restore.Run(context.Background())
+	if err != nil || len(created) != 0 || len(result.Restored) != 3 || result.Remaining != 2 || len(result.Unsupported) != 1 {
+		t.Fatalf("Dry run returned %+v with error %v after creating %d posts", result, err, len(created))
+	}
+
+	// Today's limit is 1, the next post is queued for tomorrow and the rest waits,
+	// except for the draft
+	restore.DryRun = false
+	result, err = restore.Run(context.Background())
+	if err != nil || len(result.Restored) != 3 || result.Remaining != 2 {
+		t.Fatalf("Restore returned %+v with error %v", result, err)
+	}
+	first := created[0]
+	if first.Get("type") != "text" || first.Get("date") != "2015-01-01 10:00:00 GMT" || first.Get("tags") != "a,b" ||
+		first.Get("slug") != "first" || first.Get("state") != "published" || first.Get("format") != "html" {
+		t.Errorf("First post was created with %v", first)
+	}
+	if photo := created[1]; photo.Get("data[0]") != "image data" || photo.Get("state") != "queue" || photo.Get("publish_on") != "2024-05-02T00:00:00Z" {
+		t.Errorf("Photo post was created with %v", photo)
+	}
+
+	// The next day continues with the rest, leaving the restored posts alone
+	now = now.Add(24 * time.Hour)
+	restore.PerDay = 3
+	restore.QueueLimit = 5
+	result, err = restore.Run(context.Background())
+	if err != nil || result.Skipped != 3 || len(result.Restored) != 2 || result.Remaining != 0 {
+		t.Fatalf("Second restore returned %+v with error %v", result, err)
+	}
+	if len(result.Unsupported) != 1 || result.Unsupported[0].OldID != 5 {
+		t.Errorf("Unsupported posts are %+v", result.Unsupported)
+	}
+	if draft := created[2]; draft.Get("state") != "draft" || draft.Get("body") != "Draft" {
+		t.Errorf("Draft was restored with %v", draft)
+	}
+	if answer := created[3]; answer.Get("type") != "text" || !strings.Contains(answer.Get("body"), "david asked") {
+		t.Errorf("Answer was restored with %v", answer)
+	}
+	if quote := created[4]; quote.Get("quote") != "Quote" || quote.Get("state") != "published" {
+		t.Errorf("Quote was restored with %v", quote)
+	}
+
+	mapping, err := ReadMapping(filepath.Join(dir, "restore-mirror.tumblr.com.json"))
+	if err != nil || len(mapping.Posts) != 5 || mapping.Posts[1].ID != 101 || mapping.Posts[6].ID != 103 {
+		t.Errorf("Mapping holds %+v with error %v", mapping, err)
+	}
+	other := NewRestore(client, dir, "other.tumblr.com")
+	other.Mapping = restore.Mapping
+	if _, err := other.Run(context.Background()); err == nil {
+		t.Error("Mapping of another blog was used")
+	}
+}
+
+func TestRestoreLimits(t *testing.T) {
+	dir := t.TempDir()
+	archivePost(t, dir, Posts, `{"id":1,"type":"text","timestamp":100,"body":"Hello"}`)
+	for _, limits := range [][2]int{{0, 10}, {-1, 10}, {10, -1}} {
+		restore := NewRestore(nil, dir, "mirror.tumblr.com")
+		restore.PerDay, restore.QueueLimit = limits[0], limits[1]
+		restore.DryRun = true
+		if _, err := restore.Run(context.Background()); err == nil {
+			t.Errorf("Restore with a daily limit of %d and a queue limit of %d ran", limits[0], limits[1])
+		}
+	}
+}
+
+func TestRestoreVideo(t *testing.T) {
+	dir := t.TempDir()
+	archivePost(t, dir, Posts, `{"id":1,"type":"video","timestamp":100,"caption":"<p>Watch</p>","video_type":"youtube","player":[
+		{"width":250,"embed_code":"<iframe width=\"250\" src=\"https://www.youtube.com/embed/1\"></iframe>"},
+		{"width":500,"embed_code":"<iframe width=\"500\" src=\"https://www.youtube.com/embed/1\"></iframe>"}]}`)
+
+	var created url.Values
+	_, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/user/limits":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"user":{"posts":{"limit":250,"remaining":250}}}}`)
+		case "/v2/blog/mirror.tumblr.com/post":
+			r.ParseForm()
+			created = r.PostForm
+			fmt.Fprint(w, `{"meta":{"status":201,"msg":"Created"},"response":{"id":101,"state":"published"}}`)
+		default:
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+	}))
+
+	result, err := NewRestore(client, dir, "mirror.tumblr.com").Run(context.Background())
+	if err != nil || len(result.Restored) != 1 || len(result.Unsupported) != 0 {
+		t.Fatalf("Restore returned %+v with error %v", result, err)
+	}
+	// The largest player is embedded
+	if created.Get("type") != "video" || created.Get("embed") != `<iframe width="500" src="https://www.youtube.com/embed/1"></iframe>` ||
+		created.Get("caption") != "<p>Watch</p>" {
+		t.Errorf("Video post was created with %v", created)
+	}
+}
@@ -0,0 +1,81 @@
+package tumblr
+
+import "sync"
+
+// The default number of requests a batch method sends at once, see SetBatchConcurrency
+const defaultBatchConcurrency = 8
+
+// The result of one blog of a BatchBlogInfo request
+type BlogInfoResult struct {
+	BlogHostname string   // The requested blog
+	BlogInfo     BlogInfo // The blog's info, empty when Err is set
+	Err          error    // The error that occurred requesting the blog, if any
+}
+
+// The result of one post of a BatchGetPost or PostMutePopular request
+type PostResult struct {
+	ID   int   // The requested post ID
+	Post Post  // The post, empty when BatchGetPost couldn't retrieve it
+	Err  error // The error that occurred retrieving or muting the post, if any
+}
+
+// This method retrieves the info of many blogs in parallel. The results are in the
+// same order as blogHostnames, with an error for each blog that couldn't be retrieved.
+// blogHostnames - The standard or custom blog hostnames (e.g., example.tumblr.com, example.com)
+func (api *Tumblr) BatchBlogInfo(blogHostnames []string) []BlogInfoResult {
+	api, span := api.startCall("BatchBlogInfo")
+	defer span.End()
+	results := make([]BlogInfoResult, len(blogHostnames))
+	api.batch(len(blogHostnames), func(i int) {
+		blogInfo, err := api.blogInfo(blogHostnames[i])
+		results[i] = BlogInfoResult{BlogHostname: blogHostnames[i], BlogInfo: blogInfo, Err: err}
+	})
+	return results
+}
+
+// This method retrieves many posts of a blog in parallel. The results are in the
+// same order as ids, with an error for each post that couldn't be retrieved.
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// ids - The IDs of the posts
+// params - The list of possible parameters are listed above the GetPost method
+func (api *Tumblr) BatchGetPost(blogHostname string, ids []int, params map[string]string) []PostResult {
+	api, span := api.startCall("BatchGetPost")
+	defer span.End()
+	results := make([]PostResult, len(ids))
+	api.batch(len(ids), func(i int) {
+		post, err := api.getPost(blogHostname, ids[i], params)
+		results[i] = PostResult{ID: ids[i], Post: post, Err: err}
+	})
+	return results
+}
+
+// This method calls work for every index below n on a bounded pool of workers
+// and waits for all of them to finish
+// n - The number of items
+// work - The function handling one item
+func (api *Tumblr) batch(n int, work func(i int)) {
+	workers := api.batchConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				work(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
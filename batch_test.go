@@ -0,0 +1,153 @@
+package tumblr
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A test server answering blog info requests with the requested blog's name,
+// and 404 for blogs named missing
+func blogInfoServer() (*Tumblr, func()) {
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/blog/"), "/info")
+		name = strings.TrimSuffix(name, ".tumblr.com")
+		if name == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"meta":{"status":404,"msg":"Not Found"},"response":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":%q}}}`, name)
+	})
+	return newTestClient(server), server.Close
+}
+
+func TestBatchBlogInfo(t *testing.T) {
+	client, closeServer := blogInfoServer()
+	defer closeServer()
+
+	var blogs []string
+	for i := 0; i < 50; i++ {
+		blogs = append(blogs, fmt.Sprintf("blog%d.tumblr.com", i))
+	}
+	blogs[17] = "missing.tumblr.com"
+
+	results := client.BatchBlogInfo(blogs)
+	if len(results) != len(blogs) {
+		t.Fatalf("%d results returned for %d blogs", len(results), len(blogs))
+	}
+	for i, result := range results {
+		if result.BlogHostname != blogs[i] {
+			t.Errorf("Result %d is for %s instead of %s", i, result.BlogHostname, blogs[i])
+		}
+		if i == 17 {
+			var apiError *APIError
+			if !errors.As(result.Err, &apiError) || apiError.Meta.Status != 404 {
+				t.Errorf("Missing blog returned %v instead of a 404 APIError", result.Err)
+			}
+			continue
+		}
+		if result.Err != nil || result.BlogInfo.Blog.Name != fmt.Sprintf("blog%d", i) {
+			t.Errorf("Result %d returned blog %q with error %v", i, result.BlogInfo.Blog.Name, result.Err)
+		}
+	}
+}
+
+func TestBatchConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"id":1}}`)
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetBatchConcurrency(3)
+	results := client.BatchGetPost("staff.tumblr.com", make([]int, 20), nil)
+	for _, result := range results {
+		if result.Err != nil {
+			t.Error(result.Err)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("%d requests were in flight with a concurrency of 3", maxInFlight)
+	}
+}
+
+func TestConcurrentClient(t *testing.T) {
+	client, closeServer := blogInfoServer()
+	defer closeServer()
+	client.SetRateLimit(1000, 100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("blog%d", i)
+			if blogInfo := client.BlogInfo(name + ".tumblr.com"); blogInfo.Blog.Name != name {
+				t.Errorf("Goroutine %d received blog %q", i, blogInfo.Blog.Name)
+			}
+			client.BatchBlogInfo([]string{name, "other" + name})
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRetryRateLimited(t *testing.T) {
+	var attempts int32
+	server := newTestServer(false, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "id=123" {
+			t.Errorf("Attempt sent body %q", body)
+		}
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"meta":{"status":429,"msg":"Limit Exceeded"},"response":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":[]}`)
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	if response := client.PostDelete("staff.tumblr.com", 123); response.Status != 200 {
+		t.Errorf("Rate limited request returned %d after retrying", response.Status)
+	}
+	if attempts != 3 {
+		t.Errorf("Request was sent %d times instead of 3", attempts)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	client.SetMaxRetries(0)
+	if response := client.PostDelete("staff.tumblr.com", 123); response.Status != 429 {
+		t.Errorf("Request without retries returned %d instead of 429", response.Status)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	client, closeServer := blogInfoServer()
+	defer closeServer()
+	client.SetRateLimit(100, 1)
+
+	start := time.Now()
+	client.BatchBlogInfo([]string{"a", "b", "c", "d", "e", "f"})
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("6 requests at 100 per second took %s", elapsed)
+	}
+}
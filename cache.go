@@ -0,0 +1,244 @@
+package tumblr
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The cache lifetimes used when SetCache is given no TTLs, by endpoint
+var DefaultCacheTTLs = map[string]time.Duration{
+	"blog/info":       5 * time.Minute,
+	"blog/avatar":     time.Hour,
+	"blog/posts":      time.Minute,
+	"blog/posts/{id}": time.Minute,
+	"blog/followers":  5 * time.Minute,
+	"blog/following":  5 * time.Minute,
+	"blog/likes":      5 * time.Minute,
+	"tagged":          time.Minute,
+}
+
+// A Cache stores the bodies of GET responses. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the entry stored under key
+	Get(key string) (CacheEntry, bool)
+	// Set stores an entry under key
+	Set(key string, entry CacheEntry)
+	// DeletePrefix removes every entry whose key starts with prefix
+	DeletePrefix(prefix string)
+}
+
+// A cached response
+type CacheEntry struct {
+	Body         []byte    `json:"body"`          // The response body
+	ETag         string    `json:"etag"`          // The ETag header, used to revalidate the entry
+	LastModified string    `json:"last_modified"` // The Last-Modified header, used to revalidate the entry
+	Expires      time.Time `json:"expires"`       // When the entry must be revalidated
+}
+
+// This method sets the cache consulted by GET requests. Responses are cached per
+// endpoint (e.g. blog/info, blog/avatar, blog/posts) for the given TTL and
+// revalidated with ETag and If-Modified-Since when they expire. Responses to
+// requests authorized as the user, e.g. the dashboard, likes and drafts, are
+// cached per user, so a cache can be shared by clients with different credentials.
+// Creating, editing or deleting a post removes the blog's cached posts and info.
+// Endpoints without a TTL aren't cached.
+// cache - The cache to use, nil disables caching
+// ttls - The lifetime of cached responses by endpoint, nil uses DefaultCacheTTLs
+func (api *Tumblr) SetCache(cache Cache, ttls map[string]time.Duration) {
+	if ttls == nil {
+		ttls = DefaultCacheTTLs
+	}
+	api.cache = cache
+	api.cacheTTLs = ttls
+}
+
+// This method returns the cache key of a request URL: the host, path and sorted
+// query without the api key and OAuth parameters, with the blog named by its
+// hostname. Requests that aren't authorized by the api key alone may be answered
+// for the user, so their key ends with the identity of the credentials.
+// requestURL - The request URL
+// identity - The identity of the credentials, see Tumblr.identity
+func cacheKey(requestURL *url.URL, identity string) string {
+	query := requestURL.Query()
+	public := query.Get("api_key") != ""
+	for key := range query {
+		if key == "api_key" || strings.HasPrefix(key, "oauth_") {
+			query.Del(key)
+		}
+	}
+	path := requestURL.Path
+	if blog := blogHostname(requestURL); blog != "" {
+		path = strings.Replace(path, "/blog/"+blog, "/blog/"+canonicalBlog(blog), 1)
+	}
+	key := requestURL.Host + path
+	if len(query) > 0 {
+		key += "?" + query.Encode()
+	}
+	if !public {
+		key += "#" + identity
+	}
+	return key
+}
+
+// This method returns the hostname a blog is cached under: the name of a blog
+// given by name (e.g. staff) becomes its standard hostname
+// blog - The blog name, standard or custom hostname, or UUID
+func canonicalBlog(blog string) string {
+	blog = strings.ToLower(blog)
+	if !strings.ContainsAny(blog, ".:") {
+		blog += ".tumblr.com"
+	}
+	return blog
+}
+
+// This method returns a short hash of the credentials requests are authorized with,
+// so responses cached for one user aren't served to another
+func (api *Tumblr) identity() string {
+	credentials := "oauth1:" + api.apiKey + ":" + api.config.AccessTokenKey
+	if api.oauth2Token != "" {
+		credentials = "oauth2:" + api.oauth2Token
+	}
+	sum := sha256.Sum256([]byte(credentials))
+	return hex.EncodeToString(sum[:8])
+}
+
+// An in-memory Cache evicting the least recently used entries
+type MemoryCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // most recently used first
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// This method creates an in-memory cache
+// capacity - The maximum number of entries
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (cache *MemoryCache) Get(key string) (CacheEntry, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	element, found := cache.entries[key]
+	if !found {
+		return CacheEntry{}, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*memoryCacheItem).entry, true
+}
+
+func (cache *MemoryCache) Set(key string, entry CacheEntry) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if element, found := cache.entries[key]; found {
+		element.Value.(*memoryCacheItem).entry = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(&memoryCacheItem{key: key, entry: entry})
+	for cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+func (cache *MemoryCache) DeletePrefix(prefix string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for key, element := range cache.entries {
+		if strings.HasPrefix(key, prefix) {
+			cache.order.Remove(element)
+			delete(cache.entries, key)
+		}
+	}
+}
+
+// A Cache storing one file per entry in a directory
+type DiskCache struct {
+	mutex sync.Mutex
+	dir   string
+}
+
+type diskCacheFile struct {
+	Key   string     `json:"key"`
+	Entry CacheEntry `json:"entry"`
+}
+
+// This method creates a cache in a directory, creating the directory if needed
+// dir - The directory to store entries in
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (cache *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(cache.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (cache *DiskCache) Get(key string) (CacheEntry, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	data, err := ioutil.ReadFile(cache.path(key))
+	if err != nil {
+		return CacheEntry{}, false
+	}
+	var file diskCacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Key != key {
+		return CacheEntry{}, false
+	}
+	return file.Entry, true
+}
+
+func (cache *DiskCache) Set(key string, entry CacheEntry) {
+	data, err := json.Marshal(diskCacheFile{Key: key, Entry: entry})
+	if err != nil {
+		return
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	// Write to a temporary file first so readers never see a partial entry.
+	temp := cache.path(key) + ".tmp"
+	if err := ioutil.WriteFile(temp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(temp, cache.path(key))
+}
+
+func (cache *DiskCache) DeletePrefix(prefix string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	paths, _ := filepath.Glob(filepath.Join(cache.dir, "*.json"))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var file diskCacheFile
+		if json.Unmarshal(data, &file) == nil && strings.HasPrefix(file.Key, prefix) {
+			os.Remove(path)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package tumblr
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	requestURL, _ := url.Parse(apiBlogUrl + "staff.tumblr.com/posts?tag=gif&api_key=secret&limit=20&oauth_token=token")
+	if key := cacheKey(requestURL, "user"); key != "api.tumblr.com/v2/blog/staff.tumblr.com/posts?limit=20&tag=gif" {
+		t.Errorf("Incorrect cache key %s", key)
+	}
+
+	// Requests authorized as the user are keyed by their identity, and blogs by hostname
+	requestURL, _ = url.Parse(apiBlogUrl + "Staff/posts/draft?oauth_token=token")
+	if key := cacheKey(requestURL, "user"); key != "api.tumblr.com/v2/blog/staff.tumblr.com/posts/draft#user" {
+		t.Errorf("Incorrect cache key %s", key)
+	}
+	if identity := New("consumer-key", "", "other-key", "").identity(); identity == New("consumer-key", "", "oauth-key", "").identity() {
+		t.Errorf("Tokens share the identity %s", identity)
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	endpoints := map[string]string{
+		apiBlogUrl + "staff.tumblr.com/info":               "blog/info",
+		apiBlogUrl + "staff.tumblr.com/avatar/64":          "blog/avatar",
+		apiBlogUrl + "staff.tumblr.com/posts?limit=20":     "blog/posts",
+		apiBlogUrl + "staff.tumblr.com/posts/queue":        "blog/posts/queue",
+		apiBlogUrl + "staff.tumblr.com/posts/1234/mute":    "blog/posts/{id}/mute",
+		apiBlogUrl + "staff.tumblr.com/post/edit":          "blog/post/edit",
+		apiUserUrl + "dashboard?limit=20":                  "user/dashboard",
+		apiUserUrl + "filtered_tags/spoilers":              "user/filtered_tags",
+		apiTaggedUrl + "tag=gif":                           "tagged",
+		"http://api.tumblr.com/v2/blog/staff.tumblr.com":   "blog",
+		"http://api.tumblr.com/v2/blog/staff.tumblr.com/x": "blog/x",
+	}
+	for rawURL, expected := range endpoints {
+		requestURL, _ := url.Parse(rawURL)
+		if name := endpoint(requestURL); name != expected {
+			t.Errorf("Endpoint of %s is %s instead of %s", rawURL, name, expected)
+		}
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := NewMemoryCache(2)
+	cache.Set("a", CacheEntry{Body: []byte("a")})
+	cache.Set("b", CacheEntry{Body: []byte("b")})
+	cache.Get("a")
+	cache.Set("c", CacheEntry{Body: []byte("c")})
+	if _, found := cache.Get("b"); found {
+		t.Error("Least recently used entry was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if entry, found := cache.Get(key); !found || string(entry.Body) != key {
+			t.Errorf("Entry %s was evicted", key)
+		}
+	}
+}
+
+func testCacheDeletePrefix(t *testing.T, cache Cache) {
+	cache.Set("host/v2/blog/staff/posts?limit=20", CacheEntry{Body: []byte("posts")})
+	cache.Set("host/v2/blog/staff/posts/queue", CacheEntry{Body: []byte("queue")})
+	cache.Set("host/v2/blog/staff/info", CacheEntry{Body: []byte("info")})
+	cache.DeletePrefix("host/v2/blog/staff/posts")
+	if _, found := cache.Get("host/v2/blog/staff/posts?limit=20"); found {
+		t.Error("Posts entry was not deleted")
+	}
+	if _, found := cache.Get("host/v2/blog/staff/posts/queue"); found {
+		t.Error("Queue entry was not deleted")
+	}
+	if entry, found := cache.Get("host/v2/blog/staff/info"); !found || string(entry.Body) != "info" {
+		t.Error("Info entry was deleted")
+	}
+}
+
+func TestMemoryCacheDeletePrefix(t *testing.T) {
+	testCacheDeletePrefix(t, NewMemoryCache(10))
+}
+
+func TestDiskCache(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Minute).Round(0)
+	cache.Set("key", CacheEntry{Body: []byte("body"), ETag: `"etag"`, Expires: expires})
+	entry, found := cache.Get("key")
+	if !found || string(entry.Body) != "body" || entry.ETag != `"etag"` || !entry.Expires.Equal(expires) {
+		t.Errorf("Incorrect entry %+v returned", entry)
+	}
+	testCacheDeletePrefix(t, cache)
+}
+
+func TestCachedRequests(t *testing.T) {
+	var requests, revalidations int32
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Method != "GET" {
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{}}`)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidations, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff"},"total_posts":1}}`)
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetCache(NewMemoryCache(10), map[string]time.Duration{
+		"blog/info":  time.Hour,
+		"blog/posts": time.Nanosecond,
+	})
+
+	for i := 0; i < 3; i++ {
+		if blogInfo := client.BlogInfo("staff.tumblr.com"); blogInfo.Blog.Name != "staff" {
+			t.Fatalf("Cached blog info returned name %q", blogInfo.Blog.Name)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Blog info was requested %d times instead of once", requests)
+	}
+
+	// Expired entries are revalidated with their ETag.
+	client.BlogPosts("staff.tumblr.com", nil)
+	time.Sleep(time.Millisecond)
+	if blogPosts := client.BlogPosts("staff.tumblr.com", nil); blogPosts.TotalPosts != 1 {
+		t.Errorf("Revalidated posts returned %d total posts", blogPosts.TotalPosts)
+	}
+	if revalidations != 1 {
+		t.Errorf("Posts were revalidated %d times instead of once", revalidations)
+	}
+
+	// Deleting a post drops the blog's cached posts and info, whose post count changed,
+	// even when the blog is named differently
+	client.SetCache(client.cache, map[string]time.Duration{"blog/info": time.Hour, "blog/posts": time.Hour})
+	client.BlogPosts("staff.tumblr.com", nil)
+	atomic.StoreInt32(&requests, 0)
+	client.PostDelete("staff", 1234)
+	client.BlogPosts("staff.tumblr.com", nil)
+	client.BlogInfo("staff.tumblr.com")
+	if requests != 3 {
+		t.Errorf("%d requests were sent after deleting a post instead of 3", requests)
+	}
+}
+
+func TestCachePerUser(t *testing.T) {
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		fmt.Fprintf(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[{"id":%s}]}}`, id)
+	})
+	defer server.Close()
+
+	// Clients sharing a cache are each served their own dashboard
+	cache := NewMemoryCache(10)
+	for _, token := range []string{"1", "2", "1"} {
+		client := newTestClient(server)
+		client.SetOAuth2Token(token)
+		client.SetCache(cache, map[string]time.Duration{"user/dashboard": time.Hour})
+		if posts := client.UserDashboard(nil).Posts; len(posts) != 1 || strconv.Itoa(posts[0].ID) != token {
+			t.Errorf("Dashboard of user %s holds %+v", token, posts)
+		}
+	}
+	if len(cache.entries) != 2 {
+		t.Errorf("Cache holds %d dashboards instead of 2", len(cache.entries))
+	}
+}
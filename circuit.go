@@ -0,0 +1,274 @@
+package tumblr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The endpoint families a circuit breaker keeps a circuit for
+const (
+	CircuitBlogReads = "blog_reads" // GET requests for blogs, posts and tags
+	CircuitUserReads = "user_reads" // GET requests for the authenticated user
+	CircuitWrites    = "writes"     // every other request
+)
+
+// The state of a circuit
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // requests are sent
+	CircuitOpen                         // requests fail fast with ErrCircuitOpen
+	CircuitHalfOpen                     // probe requests are sent to see if Tumblr has recovered
+)
+
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(state))
+}
+
+// Matched with errors.Is by the errors of requests refused by an open circuit
+var ErrCircuitOpen = errors.New("tumblr: circuit open")
+
+// Returned instead of sending a request while the circuit of its family is open
+type CircuitOpenError struct {
+	Family string    // The endpoint family, e.g. CircuitBlogReads
+	Until  time.Time // When the circuit half-opens to let probe requests through
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("tumblr: circuit open for %s until %s", e.Family, e.Until.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// The configuration of a circuit breaker. Zero fields take their defaults.
+type CircuitBreakerConfig struct {
+	Failures    int           // consecutive failures that open a circuit. Default: 5
+	OpenTimeout time.Duration // how long a circuit stays open before half-opening. Default: 30 seconds
+	Probes      int           // probe requests sent at once while half-open, all of which must succeed to close it. Default: 1
+
+	// OnStateChange, when set, is called after a circuit changes state. It must not block.
+	OnStateChange func(family string, from, to CircuitState)
+}
+
+// This method sets a circuit breaker between the client and Tumblr. Each endpoint
+// family has its own circuit, which opens after consecutive transport errors or 5xx
+// responses. While a circuit is open its requests fail at once with a
+// *CircuitOpenError matching ErrCircuitOpen. Once the open timeout passes the circuit
+// half-opens: probe requests are sent, and it closes when they succeed or opens
+// again when one fails. By default there is no circuit breaker.
+// config - The circuit breaker configuration, nil removes the circuit breaker
+func (api *Tumblr) SetCircuitBreaker(config *CircuitBreakerConfig) {
+	if config == nil {
+		api.breaker = nil
+		return
+	}
+	api.breaker = newCircuitBreaker(*config)
+}
+
+// This method returns the state of the circuit of an endpoint family, closed when
+// there is no circuit breaker
+// family - The endpoint family, e.g. CircuitBlogReads
+func (api *Tumblr) CircuitState(family string) CircuitState {
+	if api.breaker == nil {
+		return CircuitClosed
+	}
+	api.breaker.mutex.Lock()
+	defer api.breaker.mutex.Unlock()
+	if c, found := api.breaker.circuits[family]; found {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// This method returns the endpoint family of a request
+// request - The request
+func circuitFamily(request *http.Request) string {
+	switch {
+	case request.Method != "GET":
+		return CircuitWrites
+	case strings.HasPrefix(endpoint(request.URL), "user/"):
+		return CircuitUserReads
+	}
+	return CircuitBlogReads
+}
+
+type circuitBreaker struct {
+	mutex    sync.Mutex
+	config   CircuitBreakerConfig
+	circuits map[string]*circuit // by endpoint family
+}
+
+type circuit struct {
+	state     CircuitState
+	failures  int       // consecutive failures while closed
+	openedAt  time.Time // when the circuit last opened
+	probes    int       // probe requests in flight
+	successes int       // successful probes since half-opening
+}
+
+// A change of state, reported once the breaker is unlocked
+type circuitTransition struct {
+	family   string
+	from, to CircuitState
+}
+
+func newCircuitBreaker(config CircuitBreakerConfig) *circuitBreaker {
+	if config.Failures < 1 {
+		config.Failures = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.Probes < 1 {
+		config.Probes = 1
+	}
+	return &circuitBreaker{config: config, circuits: make(map[string]*circuit)}
+}
+
+// This middleware sends requests through the circuit of their family. A nil
+// breaker sends every request.
+// next - The rest of the chain
+func (breaker *circuitBreaker) guard(next RoundTrip) RoundTrip {
+	if breaker == nil {
+		return next
+	}
+	return func(request *http.Request) (*http.Response, error) {
+		family := circuitFamily(request)
+		probe, err := breaker.allow(family)
+		if err != nil {
+			return nil, err
+		}
+		response, err := next(request)
+		switch {
+		case err != nil && request.Context().Err() != nil:
+			// The caller gave up, which says nothing about Tumblr
+			breaker.release(family, probe)
+		case err != nil || response.StatusCode >= 500:
+			breaker.record(family, probe, false)
+		default:
+			breaker.record(family, probe, true)
+		}
+		return response, err
+	}
+}
+
+// This method reports whether a request may be sent, and whether it is a probe
+// family - The endpoint family of the request
+func (breaker *circuitBreaker) allow(family string) (bool, error) {
+	breaker.mutex.Lock()
+	var transitions []circuitTransition
+	defer func() {
+		breaker.mutex.Unlock()
+		breaker.notify(transitions)
+	}()
+
+	c := breaker.circuit(family)
+	if c.state == CircuitOpen {
+		until := c.openedAt.Add(breaker.config.OpenTimeout)
+		if time.Now().Before(until) {
+			return false, &CircuitOpenError{Family: family, Until: until}
+		}
+		transitions = append(transitions, breaker.transition(family, c, CircuitHalfOpen))
+	}
+	if c.state == CircuitHalfOpen {
+		if c.probes >= breaker.config.Probes {
+			return false, &CircuitOpenError{Family: family, Until: time.Now()}
+		}
+		c.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// This method records the outcome of a request
+// family - The endpoint family of the request
+// probe - Whether the request was a probe
+// succeeded - Whether Tumblr answered without a server error
+func (breaker *circuitBreaker) record(family string, probe bool, succeeded bool) {
+	breaker.mutex.Lock()
+	var transitions []circuitTransition
+	defer func() {
+		breaker.mutex.Unlock()
+		breaker.notify(transitions)
+	}()
+
+	c := breaker.circuit(family)
+	if probe {
+		c.probes--
+	}
+	switch c.state {
+	case CircuitClosed:
+		if succeeded {
+			c.failures = 0
+		} else if c.failures++; c.failures >= breaker.config.Failures {
+			transitions = append(transitions, breaker.transition(family, c, CircuitOpen))
+		}
+	case CircuitHalfOpen:
+		if !probe {
+			return
+		}
+		if !succeeded {
+			transitions = append(transitions, breaker.transition(family, c, CircuitOpen))
+		} else if c.successes++; c.successes >= breaker.config.Probes {
+			transitions = append(transitions, breaker.transition(family, c, CircuitClosed))
+		}
+	}
+}
+
+// This method gives back the probe slot of a request whose outcome isn't recorded
+// family - The endpoint family of the request
+// probe - Whether the request was a probe
+func (breaker *circuitBreaker) release(family string, probe bool) {
+	if !probe {
+		return
+	}
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	breaker.circuit(family).probes--
+}
+
+// This method returns the circuit of a family, creating it closed. The breaker must be locked.
+func (breaker *circuitBreaker) circuit(family string) *circuit {
+	c, found := breaker.circuits[family]
+	if !found {
+		c = &circuit{}
+		breaker.circuits[family] = c
+	}
+	return c
+}
+
+// This method changes the state of a circuit. The breaker must be locked.
+func (breaker *circuitBreaker) transition(family string, c *circuit, to CircuitState) circuitTransition {
+	from := c.state
+	c.state = to
+	c.failures = 0
+	c.successes = 0
+	if to == CircuitOpen {
+		c.openedAt = time.Now()
+	}
+	return circuitTransition{family: family, from: from, to: to}
+}
+
+// This method reports state changes to the callback. The breaker must not be locked.
+func (breaker *circuitBreaker) notify(transitions []circuitTransition) {
+	if breaker.config.OnStateChange == nil {
+		return
+	}
+	for _, t := range transitions {
+		breaker.config.OnStateChange(t.family, t.from, t.to)
+	}
+}
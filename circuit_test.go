@@ -0,0 +1,141 @@
+package tumblr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var down int32 = 1
+	var requests int32
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&down) == 1 && strings.HasPrefix(r.URL.Path, "/v2/blog/") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"meta":{"status":503,"msg":"Service Unavailable"},"response":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff"}}}`)
+	})
+	defer server.Close()
+
+	var mutex sync.Mutex
+	var changes []string
+	client := newTestClient(server)
+	client.SetCircuitBreaker(&CircuitBreakerConfig{
+		Failures:    3,
+		OpenTimeout: 50 * time.Millisecond,
+		OnStateChange: func(family string, from, to CircuitState) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			changes = append(changes, family+" "+from.String()+" -> "+to.String())
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.blogInfo("staff.tumblr.com"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Request %d failed fast before the circuit opened", i)
+		}
+	}
+	if client.CircuitState(CircuitBlogReads) != CircuitOpen {
+		t.Fatalf("Circuit is %s after 3 failures", client.CircuitState(CircuitBlogReads))
+	}
+	_, err := client.blogInfo("staff.tumblr.com")
+	var openError *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &openError) || openError.Family != CircuitBlogReads {
+		t.Errorf("Request through an open circuit returned %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("%d requests reached the server instead of 3", requests)
+	}
+	if err := client.info(apiUserUrl+"info", &UserInfo{}); err != nil {
+		t.Errorf("User read failed with %v while blog reads were open", err)
+	}
+
+	// The first probe fails and opens the circuit again, the next one closes it
+	time.Sleep(60 * time.Millisecond)
+	client.blogInfo("staff.tumblr.com")
+	if client.CircuitState(CircuitBlogReads) != CircuitOpen {
+		t.Errorf("Circuit is %s after a failed probe", client.CircuitState(CircuitBlogReads))
+	}
+	atomic.StoreInt32(&down, 0)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.blogInfo("staff.tumblr.com"); err != nil {
+		t.Errorf("Probe failed with %v", err)
+	}
+	if client.CircuitState(CircuitBlogReads) != CircuitClosed {
+		t.Errorf("Circuit is %s after a successful probe", client.CircuitState(CircuitBlogReads))
+	}
+
+	expected := []string{
+		"blog_reads closed -> open",
+		"blog_reads open -> half-open",
+		"blog_reads half-open -> open",
+		"blog_reads open -> half-open",
+		"blog_reads half-open -> closed",
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if strings.Join(changes, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("State changes were %v instead of %v", changes, expected)
+	}
+}
+
+func TestCircuitProbes(t *testing.T) {
+	release := make(chan struct{})
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":[]}`)
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetCircuitBreaker(&CircuitBreakerConfig{Failures: 1, OpenTimeout: time.Millisecond, Probes: 2})
+	client.breaker.record(CircuitWrites, false, false)
+	time.Sleep(5 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	var failedFast int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if response := client.PostDelete("staff.tumblr.com", 123); response.Status == 0 {
+				atomic.AddInt32(&failedFast, 1)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if failedFast != 3 {
+		t.Errorf("%d requests failed fast instead of 3 with 2 probes", failedFast)
+	}
+	if client.CircuitState(CircuitWrites) != CircuitClosed {
+		t.Errorf("Circuit is %s after 2 successful probes", client.CircuitState(CircuitWrites))
+	}
+}
+
+func TestCircuitFamily(t *testing.T) {
+	tests := map[string]string{
+		"GET " + apiBlogUrl + "staff.tumblr.com/posts":      CircuitBlogReads,
+		"GET " + apiTaggedUrl + "tag=gif":                   CircuitBlogReads,
+		"GET " + apiUserUrl + "dashboard":                   CircuitUserReads,
+		"POST " + apiUserUrl + "like":                       CircuitWrites,
+		"DELETE " + apiBlogUrl + "staff.tumblr.com/posts/1": CircuitWrites,
+	}
+	for request, family := range tests {
+		method, requestURL, _ := strings.Cut(request, " ")
+		httpRequest, _ := http.NewRequest(method, requestURL, nil)
+		if circuitFamily(httpRequest) != family {
+			t.Errorf("%s is in family %s instead of %s", request, circuitFamily(httpRequest), family)
+		}
+	}
+}
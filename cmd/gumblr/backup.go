@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/mattcunningham/gumblr/archive"
+)
+
+func backup(c *cli, args []string) (interface{}, error) {
+	blog := hostname(args[0])
+	backup := archive.NewBackup(c.client, blog, blog)
+	if dir, found := c.take("dir"); found {
+		backup.Dir = dir
+	}
+	if media, found := c.take("media"); found {
+		backup.Media = media != "false"
+	}
+	_, backup.Full = c.take("full")
+	backup.Logger = slog.New(slog.NewTextHandler(c.stderr, nil))
+
+	// The manifest is written after every page, so an interrupted backup continues
+	// where it stopped when run again
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return backup.Run(ctx)
+}
+
+func restore(c *cli, args []string) (interface{}, error) {
+	blog, err := c.writeBlog()
+	if err != nil {
+		return nil, err
+	}
+	restore := archive.NewRestore(c.client, args[0], blog)
+	if mapping, found := c.take("mapping"); found {
+		restore.Mapping = mapping
+	}
+	for _, limit := range []struct {
+		option string
+		value  *int
+		min    int
+	}{{"per_day", &restore.PerDay, 1}, {"queue_limit", &restore.QueueLimit, 0}} {
+		if value, found := c.take(limit.option); found {
+			if *limit.value, err = strconv.Atoi(value); err != nil || *limit.value < limit.min {
+				return nil, errUsage
+			}
+		}
+	}
+	_, restore.DryRun = c.take("dry_run")
+	restore.Logger = slog.New(slog.NewTextHandler(c.stderr, nil))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return restore.Run(ctx)
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/feed"
+)
+
+// A subcommand, mirroring a method of the client
+type command struct {
+	name  string // the words naming the command, e.g. "blog info"
+	usage string // the arguments, e.g. "blog info <blog>"
+	help  string // what the command does
+	args  int    // the number of positional arguments, -1 for one or more, -2 for at most one
+	run   func(c *cli, args []string) (interface{}, error)
+}
+
+var commands = []command{
+	{"blog info", "blog info <blog>", "Show a blog's information", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogInfo(hostname(args[0])), nil
+	}},
+	{"blog avatar", "blog avatar <blog> [--size 64]", "Write a blog's avatar image to standard output", 1, func(c *cli, args []string) (interface{}, error) {
+		size := 64
+		if value, found := c.take("size"); found {
+			var err error
+			if size, err = strconv.Atoi(value); err != nil {
+				return nil, errUsage
+			}
+		}
+		return raw(c.client.BlogAvatarAndSize(hostname(args[0]), size)), nil
+	}},
+	{"blog likes", "blog likes <blog>", "List the posts a blog likes", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogLikes(hostname(args[0]), c.params), nil
+	}},
+	{"blog followers", "blog followers <blog>", "List a blog's followers", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogFollowers(hostname(args[0]), c.params), nil
+	}},
+	{"blog following", "blog following <blog>", "List the blogs a blog follows", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogFollowing(hostname(args[0]), c.params), nil
+	}},
+	{"blog followed-by", "blog followed-by <blog> <other blog>", "Check whether a blog is followed by another", 2, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogFollowedBy(hostname(args[0]), args[1]), nil
+	}},
+	{"blog posts", "blog posts <blog>", "List a blog's published posts", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogPosts(hostname(args[0]), c.params), nil
+	}},
+	{"blog post", "blog post <blog> <id>", "Show one post", 2, func(c *cli, args []string) (interface{}, error) {
+		id, err := postID(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return c.client.GetPost(hostname(args[0]), id, c.params), nil
+	}},
+	{"blog queue", "blog queue <blog>", "List a blog's queued posts", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogQueuedPosts(hostname(args[0]), c.params), nil
+	}},
+	{"blog notifications", "blog notifications <blog>", "List a blog's activity", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogNotifications(hostname(args[0]), c.params), nil
+	}},
+	{"blog submissions", "blog submissions <blog>", "List a blog's submissions and asks", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogSubmissions(hostname(args[0]), c.params), nil
+	}},
+	{"blog asks", "blog asks <blog>", "List a blog's unanswered asks", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.BlogAsks(hostname(args[0]), c.params), nil
+	}},
+
+	{"post create", "post create [--blog <blog>] --type <type> [--<param> <value>...]", "Create a post", 0, func(c *cli, args []string) (interface{}, error) {
+		blog, err := c.writeBlog()
+		if err != nil {
+			return nil, err
+		}
+		return c.client.Post(blog, c.params), nil
+	}},
+	{"post edit", "post edit [--blog <blog>] <id> [--<param> <value>...]", "Edit a post", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.withPost(args[0], func(blog string, id int) tumblr.Meta {
+			return c.client.PostEdit(blog, id, c.params)
+		})
+	}},
+	{"post reblog", "post reblog [--blog <blog>] <id> <reblog key> [--comment <text>]", "Reblog a post", 2, func(c *cli, args []string) (interface{}, error) {
+		return c.withPost(args[0], func(blog string, id int) tumblr.Meta {
+			return c.client.PostReblog(blog, id, args[1], c.params)
+		})
+	}},
+	{"post delete", "post delete [--blog <blog>] <id>", "Delete a post", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.withPost(args[0], c.client.PostDelete)
+	}},
+	{"post mute", "post mute [--blog <blog>] <id> [--duration 24h]", "Mute a post's notifications, forever without a duration", 1, func(c *cli, args []string) (interface{}, error) {
+		var duration time.Duration
+		if value, found := c.take("duration"); found {
+			var err error
+			if duration, err = time.ParseDuration(value); err != nil {
+				return nil, errUsage
+			}
+		}
+		return c.withPost(args[0], func(blog string, id int) tumblr.Meta {
+			return c.client.PostMute(blog, id, duration)
+		})
+	}},
+	{"post unmute", "post unmute [--blog <blog>] <id>", "Unmute a post's notifications", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.withPost(args[0], c.client.PostUnmute)
+	}},
+
+	{"ask answer", "ask answer [--blog <blog>] <id> <answer> [--state published]", "Answer an ask", 2, func(c *cli, args []string) (interface{}, error) {
+		state, found := c.take("state")
+		if !found {
+			state = "published"
+		}
+		return c.withPost(args[0], func(blog string, id int) tumblr.Meta {
+			return c.client.AskAnswer(blog, id, args[1], state)
+		})
+	}},
+	{"ask delete", "ask delete [--blog <blog>] <id>", "Delete an ask", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.withPost(args[0], c.client.AskDelete)
+	}},
+
+	{"user info", "user info", "Show the user's information", 0, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserInfo(), nil
+	}},
+	{"user limits", "user limits", "Show the user's limits", 0, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserLimits(), nil
+	}},
+	{"user likes", "user likes", "List the posts the user likes", 0, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserLikes(c.params), nil
+	}},
+	{"user following", "user following", "List the blogs the user follows", 0, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserFollowing(c.params), nil
+	}},
+	{"user follow", "user follow <blog>", "Follow a blog", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserFollow(hostname(args[0])), nil
+	}},
+	{"user unfollow", "user unfollow <blog>", "Unfollow a blog", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserUnfollow(hostname(args[0])), nil
+	}},
+	{"user like", "user like <id> <reblog key>", "Like a post", 2, func(c *cli, args []string) (interface{}, error) {
+		id, err := postID(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return c.client.UserLike(id, args[1]), nil
+	}},
+	{"user unlike", "user unlike <id> <reblog key>", "Unlike a post", 2, func(c *cli, args []string) (interface{}, error) {
+		id, err := postID(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return c.client.UserUnlike(id, args[1]), nil
+	}},
+	{"user filtered-tags", "user filtered-tags", "List the user's tag filters", 0, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserFilteredTags(), nil
+	}},
+	{"user filter-tags", "user filter-tags <tag>...", "Filter tags from the user's dashboard", -1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserFilterTags(args), nil
+	}},
+	{"user unfilter-tag", "user unfilter-tag <tag>", "Remove a tag filter", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserUnfilterTag(args[0]), nil
+	}},
+	{"user filtered-content", "user filtered-content", "List the user's content filters", 0, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserFilteredContent(), nil
+	}},
+	{"user filter-content", "user filter-content <text>...", "Filter posts containing text from the user's dashboard", -1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserFilterContent(args), nil
+	}},
+	{"user unfilter-content", "user unfilter-content <text>", "Remove a content filter", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserUnfilterContent(args[0]), nil
+	}},
+
+	{"dashboard", "dashboard", "List the posts on the user's dashboard", 0, func(c *cli, args []string) (interface{}, error) {
+		return c.client.UserDashboard(c.params), nil
+	}},
+	{"tagged", "tagged <tag>", "List posts with a tag", 1, func(c *cli, args []string) (interface{}, error) {
+		return c.client.TaggedPosts(args[0], c.params), nil
+	}},
+
+	{"backup", "backup <blog> [--dir <dir>] [--full] [--media false]", "Back up a blog's posts, drafts, queue, likes and media to a directory", 1, backup},
+	{"restore", "restore <archive> [--blog <blog>] [--dry-run] [--per-day 250] [--queue-limit 300] [--mapping <file>]", "Restore an archive's posts into a blog", 1, restore},
+	{"export", "export [<blog>] [--archive <dir>] [--dir site] [--assets false]", "Export a blog's or an archive's posts to Markdown files for a static site", -2, export},
+	{"wxr", "wxr [<blog>] [--archive <dir>] [--author <login>] [--out <file>]", "Export a blog's or an archive's posts to WXR, for the WordPress importer", -2, exportWXR},
+	{"sync", "sync <dir> [--blog <blog>] [--delete] [--dry-run] [--state <file>]", "Post a directory of Markdown files to a blog, updating the posts of changed files", 1, syncDir},
+
+	{"feed blog", "feed blog <blog>... [--tag <tag>] [--type <type>] [--limit 20] [--atom]", "Write an RSS or Atom feed of the posts of one or more blogs", -1, func(c *cli, args []string) (interface{}, error) {
+		tag, _ := c.take("tag")
+		postType, _ := c.take("type")
+		var sources []feed.Source
+		for _, blog := range args {
+			sources = append(sources, feed.Blog(hostname(blog), tag, postType))
+		}
+		return writeFeed(c, sources...)
+	}},
+	{"feed tagged", "feed tagged <tag> [--limit 20] [--atom]", "Write an RSS or Atom feed of the posts with a tag", 1, func(c *cli, args []string) (interface{}, error) {
+		return writeFeed(c, feed.Tagged(args[0]))
+	}},
+	{"feed likes", "feed likes [--limit 20] [--atom]", "Write an RSS or Atom feed of the posts the user likes", 0, func(c *cli, args []string) (interface{}, error) {
+		return writeFeed(c, feed.Likes())
+	}},
+	{"feed serve", "feed serve [--addr 127.0.0.1:8080]", "Serve feeds of blogs, tags and likes over HTTP", 0, serveFeeds},
+	{"bridge", "bridge <feed file or URL> [--blog <blog>] [--type link|text] [--title <template>] [--body <template>] [--tags <tag,tag>] [--item-tags] [--queue] [--limit <n>] [--state <file>] [--dry-run]",
+		"Post the new items of an RSS or Atom feed to a blog", 1, bridge},
+
+	{"login", "login [<profile>] [--consumer-key <key> --consumer-secret <secret>] [--paste]", "Authorize gumblr and save the token to a profile", -2, login},
+	{"profile list", "profile list", "List the profiles of the config file", 0, listProfiles},
+	{"profile show", "profile show [<name>]", "Show a profile, with its secrets masked", -2, showProfile},
+	{"profile set-default", "profile set-default <name>", "Make a profile the default", 1, setDefaultProfile},
+	{"profile delete", "profile delete <name>", "Delete a profile", 1, deleteProfile},
+	{"profile encrypt", "profile encrypt", "Encrypt the config file's secrets with $GUMBLR_PASSPHRASE", 0, encryptProfiles},
+	{"profile decrypt", "profile decrypt", "Store the config file's secrets unencrypted", 0, decryptProfiles},
+}
+
+// This method reports whether a command works without a profile's credentials
+func (command *command) local() bool {
+	return command.name == "login" || strings.HasPrefix(command.name, "profile ")
+}
+
+// This method finds the command named by the first positional arguments, returning
+// the remaining arguments, or nil when there is no such command or the number of
+// arguments is wrong
+// positional - The positional arguments
+func findCommand(positional []string) (*command, []string) {
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(positional) < len(words) || strings.Join(positional[:len(words)], " ") != commands[i].name {
+			continue
+		}
+		args := positional[len(words):]
+		switch {
+		case commands[i].args >= 0 && len(args) != commands[i].args,
+			commands[i].args == -1 && len(args) == 0,
+			commands[i].args == -2 && len(args) > 1:
+			return nil, nil
+		}
+		return &commands[i], args
+	}
+	return nil, nil
+}
+
+// This method writes the usage of the commands starting with the given words
+// w - The writer to write to
+// positional - The words typed so far
+func printUsage(w io.Writer, positional []string) {
+	prefix := ""
+	if len(positional) > 0 {
+		prefix = positional[0]
+	}
+	fmt.Fprintln(w, "usage: gumblr <command> [--json | --output json|table] [--debug] [--<param> <value>...]")
+	fmt.Fprintln(w)
+	for _, command := range commands {
+		if prefix == "" || strings.HasPrefix(command.name, prefix) || !hasCommand(prefix) {
+			fmt.Fprintf(w, "  %-60s %s\n", command.usage, command.help)
+		}
+	}
+}
+
+// This method reports whether any command starts with a word
+func hasCommand(word string) bool {
+	for _, command := range commands {
+		if strings.Fields(command.name)[0] == word {
+			return true
+		}
+	}
+	return false
+}
+
+// This method removes an option that isn't a request parameter
+// name - The option name
+func (c *cli) take(name string) (string, bool) {
+	value, found := c.params[name]
+	delete(c.params, name)
+	return value, found
+}
+
+// This method returns the blog to write to: the --blog option, or the user's
+// primary blog
+func (c *cli) writeBlog() (string, error) {
+	if c.blog != "" {
+		return c.blog, nil
+	}
+	for _, blog := range c.client.UserInfo().User.Blogs {
+		if blog.Primary {
+			return hostname(blog.Name), nil
+		}
+	}
+	if c.status != 0 && (c.status < 200 || c.status >= 300) {
+		return "", fmt.Errorf("the user's blogs could not be listed (%d), pass --blog", c.status)
+	}
+	return "", errors.New("the user has no primary blog, pass --blog")
+}
+
+// This method calls a write method of a post of the blog being written to
+// id - The post ID argument
+// write - The client method
+func (c *cli) withPost(id string, write func(blog string, id int) tumblr.Meta) (interface{}, error) {
+	postID, err := postID(id)
+	if err != nil {
+		return nil, err
+	}
+	blog, err := c.writeBlog()
+	if err != nil {
+		return nil, err
+	}
+	return write(blog, postID), nil
+}
+
+// This method parses a post ID argument
+func postID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, errUsage
+	}
+	return id, nil
+}
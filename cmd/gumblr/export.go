@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/archive"
+	"github.com/mattcunningham/gumblr/markdown"
+	"github.com/mattcunningham/gumblr/wxr"
+)
+
+func export(c *cli, args []string) (interface{}, error) {
+	archiveDir, fromArchive := c.take("archive")
+	if len(args) == 0 && !fromArchive || len(args) > 0 && fromArchive {
+		return nil, errUsage
+	}
+	exporter := markdown.NewExporter("site")
+	if dir, found := c.take("dir"); found {
+		exporter.Dir = dir
+	}
+	if assets, found := c.take("assets"); found {
+		exporter.Assets = assets != "false"
+	}
+	exporter.Logger = slog.New(slog.NewTextHandler(c.stderr, nil))
+
+	var posts []tumblr.Post
+	if fromArchive {
+		var err error
+		if posts, err = archive.ReadPosts(archiveDir, archive.Posts); err != nil {
+			return nil, err
+		}
+		exporter.Archive = archiveDir
+	} else {
+		blog := hostname(args[0])
+		for offset := 0; ; {
+			page := c.client.BlogPosts(blog, map[string]string{"offset": strconv.Itoa(offset), "limit": "20"})
+			if c.status < 200 || c.status >= 300 {
+				return nil, fmt.Errorf("listing the posts of %s failed after %d posts", blog, len(posts))
+			}
+			posts = append(posts, page.Posts...)
+			offset += len(page.Posts)
+			if len(page.Posts) == 0 || offset >= page.TotalPosts {
+				break
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return exporter.Export(ctx, posts)
+}
+
+func exportWXR(c *cli, args []string) (interface{}, error) {
+	archiveDir, fromArchive := c.take("archive")
+	if len(args) == 0 && !fromArchive || len(args) > 0 && fromArchive {
+		return nil, errUsage
+	}
+	out, toFile := c.take("out")
+	var document bytes.Buffer
+	var result wxr.ExportResult
+	var err error
+	if fromArchive {
+		var blog tumblr.Blog
+		var posts []tumblr.Post
+		if blog, err = archive.ReadBlog(archiveDir); err != nil {
+			return nil, err
+		}
+		if posts, err = archive.ReadPosts(archiveDir, archive.Posts); err != nil {
+			return nil, err
+		}
+		exporter := wxr.NewExporter(c.client, hostname(blog.Name))
+		exporter.Author, _ = c.take("author")
+		result, err = exporter.Write(&document, blog, posts)
+	} else {
+		exporter := wxr.NewExporter(c.client, hostname(args[0]))
+		exporter.Author, _ = c.take("author")
+		exporter.Logger = slog.New(slog.NewTextHandler(c.stderr, nil))
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		result, err = exporter.Export(ctx, &document)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !toFile {
+		return raw(document.Bytes()), nil
+	}
+	return result, os.WriteFile(out, document.Bytes(), 0644)
+}
+
+func syncDir(c *cli, args []string) (interface{}, error) {
+	blog, err := c.writeBlog()
+	if err != nil {
+		return nil, err
+	}
+	sync := markdown.NewSync(c.client, args[0], blog)
+	if state, found := c.take("state"); found {
+		sync.State = state
+	}
+	_, sync.Delete = c.take("delete")
+	sync.Logger = slog.New(slog.NewTextHandler(c.stderr, nil))
+	plan, err := sync.Plan()
+	if err != nil {
+		return nil, err
+	}
+	if _, dryRun := c.take("dry_run"); dryRun {
+		return plan, nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return sync.Apply(ctx, plan)
+}
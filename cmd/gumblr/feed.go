@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/feed"
+)
+
+// This method generates a feed of sources, as RSS or with --atom as Atom
+func writeFeed(c *cli, sources ...feed.Source) (interface{}, error) {
+	f := feed.New(c.client, sources...)
+	if limit, found := c.take("limit"); found {
+		var err error
+		if f.Limit, err = strconv.Atoi(limit); err != nil {
+			return nil, errUsage
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var data []byte
+	var err error
+	if _, atom := c.take("atom"); atom {
+		data, err = f.Atom(ctx)
+	} else {
+		data, err = f.RSS(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return raw(data), nil
+}
+
+func serveFeeds(c *cli, args []string) (interface{}, error) {
+	addr := "127.0.0.1:8080"
+	if value, found := c.take("addr"); found {
+		addr = value
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: feed.Handler(c.client)}
+	fmt.Fprintf(c.stderr, "Serving feeds at http://%s/blog/<blog>, /tagged/<tag> and /likes, add .atom for Atom\n", listener.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return nil, err
+	}
+	return tumblr.Meta{Status: http.StatusOK, Msg: "OK"}, nil
+}
+
+func bridge(c *cli, args []string) (interface{}, error) {
+	blog, err := c.writeBlog()
+	if err != nil {
+		return nil, err
+	}
+	bridge := feed.NewBridge(c.client, args[0], blog)
+	for _, option := range []struct {
+		name  string
+		value *string
+	}{{"type", &bridge.PostType}, {"title", &bridge.Title}, {"body", &bridge.Body}, {"state", &bridge.State}} {
+		if value, found := c.take(option.name); found {
+			*option.value = value
+		}
+	}
+	if tags, found := c.take("tags"); found {
+		bridge.Tags = strings.Split(tags, ",")
+	}
+	if limit, found := c.take("limit"); found {
+		if bridge.Limit, err = strconv.Atoi(limit); err != nil {
+			return nil, errUsage
+		}
+	}
+	_, bridge.ItemTags = c.take("item_tags")
+	_, bridge.Queue = c.take("queue")
+	_, bridge.DryRun = c.take("dry_run")
+	bridge.Logger = slog.New(slog.NewTextHandler(c.stderr, nil))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return bridge.Run(ctx)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+)
+
+// How long login waits for the browser to come back to the loopback listener
+const loginTimeout = 5 * time.Minute
+
+// Where a pasted verifier is read from, replaced in tests
+var stdin io.Reader = os.Stdin
+
+// This method opens a URL in the user's browser, replaced in tests
+var openBrowser = func(url string) error {
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("open", url)
+	case "windows":
+		command = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		command = exec.Command("xdg-open", url)
+	}
+	return command.Start()
+}
+
+// The result of the loopback callback
+type callback struct {
+	verifier string
+	err      error
+}
+
+func login(c *cli, args []string) (interface{}, error) {
+	config, path, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := c.profile
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if name == "" {
+		name = c.getenv("GUMBLR_PROFILE")
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	profile := tumblr.Profile{Name: name}
+	if existing, found := config.Profiles[name]; found {
+		profile = *existing
+	}
+	for _, credential := range []struct {
+		field  *string
+		option string
+		env    string
+	}{
+		{&profile.ConsumerKey, "consumer_key", "TUMBLR_CONSUMER_KEY"},
+		{&profile.ConsumerSecret, "consumer_secret", "TUMBLR_CONSUMER_SECRET"},
+	} {
+		if value, found := c.take(credential.option); found {
+			*credential.field = value
+		} else if value := c.getenv(credential.env); value != "" {
+			*credential.field = value
+		}
+	}
+	if profile.ConsumerKey == "" || profile.ConsumerSecret == "" {
+		return nil, errors.New("login needs the application's --consumer-key and --consumer-secret, register one at https://www.tumblr.com/oauth/apps")
+	}
+
+	_, paste := c.take("paste")
+	client := newClient(&tumblr.Profile{ConsumerKey: profile.ConsumerKey, ConsumerSecret: profile.ConsumerSecret})
+	login, verifier, err := c.authorize(client, paste)
+	if err != nil {
+		return nil, err
+	}
+	profile.OAuthToken, profile.OAuthTokenSecret, err = login.Finish(verifier)
+	if err != nil {
+		return nil, err
+	}
+	profile.OAuth2AccessToken, profile.OAuth2RefreshToken, profile.OAuth2ExpiresAt = "", "", 0
+
+	c.client = newClient(&profile)
+	c.client.Use(c.recordStatus)
+	userInfo := c.client.UserInfo()
+	if c.status < 200 || c.status >= 300 {
+		return nil, fmt.Errorf("verifying the new token: %d %s", c.status, http.StatusText(c.status))
+	}
+	if c.blog != "" {
+		profile.DefaultBlog = c.blog
+	}
+	if profile.DefaultBlog == "" {
+		for _, blog := range userInfo.User.Blogs {
+			if blog.Primary {
+				profile.DefaultBlog = hostname(blog.Name)
+			}
+		}
+	}
+
+	config.SetProfile(profile)
+	if err := config.Save(path); err != nil {
+		return nil, err
+	}
+	return tumblr.Meta{Status: 200, Msg: fmt.Sprintf("Logged in as %s, saved to profile %s", userInfo.User.Name, name)}, nil
+}
+
+// This method has the user authorize gumblr, returning the login and its verifier.
+// The verifier comes back to a loopback listener, or is pasted when paste is set.
+// client - A client with the application's consumer key and secret
+// paste - Whether to read the verifier from standard input
+func (c *cli) authorize(client *tumblr.Tumblr, paste bool) (*tumblr.Login, string, error) {
+	if paste {
+		login, err := client.StartLogin("")
+		if err != nil {
+			return nil, "", err
+		}
+		fmt.Fprintf(c.stderr, "Visit this URL to authorize gumblr, then paste the URL you were redirected to:\n\n  %s\n\n> ", login.URL)
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, "", fmt.Errorf("reading the verifier: %w", err)
+		}
+		verifier, err := login.ParseVerifier(line)
+		return login, verifier, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, "", err
+	}
+	login, err := client.StartLogin("http://" + listener.Addr().String() + "/callback")
+	if err != nil {
+		listener.Close()
+		return nil, "", err
+	}
+	callbacks := make(chan callback, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		verifier, err := login.Verifier(r)
+		if err != nil {
+			http.Error(w, "Authorization failed: "+err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "gumblr is authorized, you can close this window.")
+		}
+		select {
+		case callbacks <- callback{verifier, err}:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Fprintf(c.stderr, "Visit this URL to authorize gumblr:\n\n  %s\n\n", login.URL)
+	if err := openBrowser(login.URL); err == nil {
+		fmt.Fprintln(c.stderr, "Waiting for the browser...")
+	}
+	select {
+	case result := <-callbacks:
+		return login, result.verifier, result.err
+	case <-time.After(loginTimeout):
+		return nil, "", errors.New("timed out waiting for the authorization, try --paste")
+	}
+}
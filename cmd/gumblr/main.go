@@ -0,0 +1,262 @@
+// Command gumblr calls the Tumblr API from the shell.
+//
+//	gumblr blog info staff
+//	gumblr blog posts staff --type photo --limit 5
+//	gumblr post create --blog staff --type text --title Hello --body "Hello world"
+//	gumblr dashboard --limit 20 --json
+//
+// Every --name value option not listed below is sent to Tumblr as a request
+// parameter, with dashes in its name replaced by underscores. Credentials are
+// read from a profile of the config file (see tumblr.LoadProfile), chosen with
+// --profile or $GUMBLR_PROFILE, and can be overridden by the TUMBLR_CONSUMER_KEY,
+// TUMBLR_CONSUMER_SECRET, TUMBLR_OAUTH_TOKEN, TUMBLR_OAUTH_TOKEN_SECRET,
+// TUMBLR_OAUTH2_ACCESS_TOKEN and TUMBLR_BLOG environment variables. An encrypted
+// config file is decrypted with $GUMBLR_PASSPHRASE. gumblr login authorizes gumblr
+// in the browser and saves the token to a profile.
+//
+// The exit status is 0 when Tumblr responds with a 2xx status, 1 when no response
+// was received, 2 for usage errors, 3 for 401 and 403, 4 for 404, 5 for 429, 6 for
+// other 4xx statuses and 7 for 5xx statuses.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	tumblr "github.com/mattcunningham/gumblr"
+)
+
+// Exit statuses
+const (
+	exitOK           = 0
+	exitNoResponse   = 1
+	exitUsage        = 2
+	exitUnauthorized = 3
+	exitNotFound     = 4
+	exitRateLimited  = 5
+	exitClientError  = 6
+	exitServerError  = 7
+)
+
+// Returned by commands called with the wrong arguments
+var errUsage = errors.New("usage")
+
+// The state of one invocation
+type cli struct {
+	client   *tumblr.Tumblr
+	stdout   io.Writer
+	stderr   io.Writer
+	getenv   func(string) string
+	format   string            // json or table
+	profile  string            // the --profile option
+	blog     string            // the --blog option, or the profile's default blog
+	params   map[string]string // the request parameters given as options
+	status   int               // the HTTP status of the last response, 0 when none was received
+	requests int               // the number of requests sent
+	mutex    sync.Mutex        // guards status and requests for commands sending requests concurrently
+}
+
+// This method creates the client of an invocation, replaced in tests
+var newClient = tumblr.NewFromProfile
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stdout, os.Stderr))
+}
+
+// This method runs a command line, returning the exit status
+// args - The arguments after the program name
+// getenv - Looks up environment variables
+// stdout - Where results are written
+// stderr - Where errors, usage and debug dumps are written
+func run(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	positional, options, err := parseArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "gumblr: %s\n", err)
+		return exitUsage
+	}
+	command, commandArgs := findCommand(positional)
+	if options["help"] != "" || command == nil {
+		printUsage(stderr, positional)
+		if command == nil && options["help"] == "" {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	c := &cli{stdout: stdout, stderr: stderr, getenv: getenv, format: "table", params: make(map[string]string)}
+	for name, value := range options {
+		switch name {
+		case "json":
+			if options["output"] == "" {
+				c.format = "json"
+			}
+		case "output":
+			if value != "json" && value != "table" {
+				fmt.Fprintf(stderr, "gumblr: unknown output format %q\n", value)
+				return exitUsage
+			}
+			c.format = value
+		case "blog":
+			c.blog = hostname(value)
+		case "profile":
+			c.profile = value
+		case "debug":
+		default:
+			c.params[name] = value
+		}
+	}
+
+	if command.local() {
+		result, err := command.run(c, commandArgs)
+		if err == nil {
+			err = c.write(result)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "gumblr: %s\n", err)
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	profile, err := tumblr.LoadProfileEnv(c.profile, getenv)
+	if err == nil && profile.ConsumerKey == "" && profile.OAuth2AccessToken == "" {
+		err = errors.New("no credentials, add a profile to the config file or set TUMBLR_CONSUMER_KEY")
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gumblr: %s\n", err)
+		return exitUsage
+	}
+	if c.blog == "" {
+		c.blog = hostname(profile.DefaultBlog)
+	}
+	c.client = newClient(profile)
+	c.client.SetLogger(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+	c.client.SetPreserveRaw(c.format == "json")
+	if options["debug"] != "" {
+		c.client.SetDebug(stderr)
+	}
+	c.client.Use(c.recordStatus)
+
+	result, err := command.run(c, commandArgs)
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(stderr, "usage: gumblr %s\n", command.usage)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gumblr: %s\n", err)
+		if c.status >= 300 {
+			return exitStatus(c.status)
+		}
+		return exitNoResponse
+	}
+
+	status := c.status
+	if meta, ok := result.(tumblr.Meta); ok && meta.Status != 0 {
+		status = meta.Status
+	} else if c.requests == 0 {
+		status = http.StatusOK
+	}
+	if status >= 200 && status < 300 {
+		if err := c.write(result); err != nil {
+			fmt.Fprintf(stderr, "gumblr: %s\n", err)
+			return exitNoResponse
+		}
+	} else if status != 0 {
+		fmt.Fprintf(stderr, "gumblr: %d %s\n", status, http.StatusText(status))
+	}
+	return exitStatus(status)
+}
+
+// This middleware records the status of every response
+// next - The rest of the chain
+func (c *cli) recordStatus(next tumblr.RoundTrip) tumblr.RoundTrip {
+	return func(request *http.Request) (*http.Response, error) {
+		response, err := next(request)
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.requests++
+		c.status = 0
+		if err == nil {
+			c.status = response.StatusCode
+		}
+		return response, err
+	}
+}
+
+// This method maps an HTTP status to an exit status
+// status - The HTTP status, 0 when no response was received
+func exitStatus(status int) int {
+	switch {
+	case status == 0:
+		return exitNoResponse
+	case status < 400:
+		return exitOK
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return exitUnauthorized
+	case status == http.StatusNotFound:
+		return exitNotFound
+	case status == http.StatusTooManyRequests:
+		return exitRateLimited
+	case status < 500:
+		return exitClientError
+	}
+	return exitServerError
+}
+
+// Options that take no value
+var flags = map[string]bool{"json": true, "debug": true, "help": true, "paste": true, "full": true, "dry_run": true, "delete": true, "atom": true, "item_tags": true, "queue": true}
+
+// This method splits arguments into positional arguments and options. Options are
+// written --name value or --name=value, except the flags, which take no value.
+// Dashes in option names are replaced by underscores, and "--" ends the options.
+// args - The arguments
+func parseArgs(args []string) ([]string, map[string]string, error) {
+	var positional []string
+	options := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if arg == "-h" {
+			arg = "--help"
+		}
+		if arg == "-o" {
+			arg = "--output"
+		}
+		if !strings.HasPrefix(arg, "--") || len(arg) == 2 {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		name = strings.ReplaceAll(name, "-", "_")
+		switch {
+		case flags[name]:
+			options[name] = "true"
+		case found:
+			options[name] = value
+		case i+1 < len(args):
+			i++
+			options[name] = args[i]
+		default:
+			return nil, nil, fmt.Errorf("option --%s needs a value", name)
+		}
+	}
+	return positional, options, nil
+}
+
+// This method turns a blog name into a hostname, e.g. staff into staff.tumblr.com
+// name - The blog name or hostname
+func hostname(name string) string {
+	if name == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return name + ".tumblr.com"
+}
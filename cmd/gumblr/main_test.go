@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/tumblrtest"
+)
+
+// This method runs a command line against a test server
+func runTest(t *testing.T, handler http.HandlerFunc, args ...string) (int, string, string) {
+	server := useServer(handler)
+	defer server.Close()
+
+	env := map[string]string{
+		"GUMBLR_CONFIG":       filepath.Join(t.TempDir(), "config.json"),
+		"TUMBLR_CONSUMER_KEY": "consumer-key",
+	}
+	return runEnv(env, args...)
+}
+
+// This method starts a test server and makes new clients send their requests to it
+func useServer(handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewTLSServer(handler)
+	newClient = func(profile *tumblr.Profile) *tumblr.Tumblr {
+		client := tumblr.NewFromProfile(profile)
+		client.SetHTTPClient(tumblrtest.HTTPClient(server))
+		return client
+	}
+	return server
+}
+
+// This method runs a command line with an environment
+func runEnv(env map[string]string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, func(name string) string { return env[name] }, &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestCommandBlogInfo(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/blog/staff.tumblr.com/info" {
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff","title":"Tumblr Staff","uuid":"t:abc"}}}`)
+	}
+
+	status, stdout, stderr := runTest(t, handler, "blog", "info", "staff")
+	if status != exitOK || !strings.Contains(stdout, "Title:        Tumblr Staff") {
+		t.Errorf("Table output exited %d with %q and %q", status, stdout, stderr)
+	}
+
+	status, stdout, _ = runTest(t, handler, "blog", "info", "staff", "--json")
+	var blogInfo map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &blogInfo); err != nil || status != exitOK {
+		t.Fatalf("JSON output exited %d with %q", status, stdout)
+	}
+	if blogInfo["blog"]["uuid"] != "t:abc" {
+		t.Errorf("JSON output lost unmodeled fields: %s", stdout)
+	}
+}
+
+func TestCommandPostCreate(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path != "/v2/blog/staff.tumblr.com/post" || r.PostForm.Get("type") != "text" || r.PostForm.Get("title") != "Hello" {
+			t.Errorf("Requested %s with %v", r.URL.Path, r.PostForm)
+		}
+		if r.PostForm.Get("native_inline_images") != "true" {
+			t.Errorf("Dashed option was sent as %v", r.PostForm)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"meta":{"status":400,"msg":"Bad Request"},"response":[]}`)
+	}
+	status, _, stderr := runTest(t, handler, "post", "create", "--blog", "staff", "--type", "text", "--title=Hello", "--native-inline-images", "true")
+	if status != exitClientError || !strings.Contains(stderr, "400 Bad Request") {
+		t.Errorf("Failed post exited %d with %q", status, stderr)
+	}
+}
+
+func TestCommandUsage(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Requested %s", r.URL.Path)
+	}
+	for _, args := range [][]string{{}, {"blog"}, {"blog", "info"}, {"post", "delete", "abc"}, {"dashboard", "--limit"}} {
+		if status, _, stderr := runTest(t, handler, args...); status != exitUsage || !strings.Contains(stderr, "usage") && !strings.Contains(stderr, "needs a value") {
+			t.Errorf("%q exited %d with %q", args, status, stderr)
+		}
+	}
+	if status, _, stderr := runTest(t, handler, "blog", "--help"); status != exitOK || !strings.Contains(stderr, "blog posts <blog>") || strings.Contains(stderr, "dashboard ") {
+		t.Errorf("Help exited %d with %q", status, stderr)
+	}
+}
+
+func TestCommandExitStatus(t *testing.T) {
+	tests := map[int]int{0: exitNoResponse, 200: exitOK, 201: exitOK, 401: exitUnauthorized, 403: exitUnauthorized,
+		404: exitNotFound, 429: exitRateLimited, 400: exitClientError, 500: exitServerError, 503: exitServerError}
+	for status, expected := range tests {
+		if exitStatus(status) != expected {
+			t.Errorf("Status %d exits %d instead of %d", status, exitStatus(status), expected)
+		}
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"meta":{"status":404,"msg":"Not Found"},"response":[]}`)
+	}
+	if status, _, _ := runTest(t, handler, "dashboard", "--limit", "20"); status != exitNotFound {
+		t.Errorf("Read of a missing resource exited %d", status)
+	}
+
+	// A command that fails without a response exits like a call without one
+	dropped := func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) }
+	if status, _, stderr := runTest(t, dropped, "export", "staff", "--dir", t.TempDir()); status != exitNoResponse {
+		t.Errorf("Export without a response exited %d with %q", status, stderr)
+	}
+}
+
+func TestCommandParseArgs(t *testing.T) {
+	positional, options, err := parseArgs([]string{"blog", "posts", "staff", "--json", "--limit", "5", "--reblog-info=true", "-o", "table", "--", "--tag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(positional, " ") != "blog posts staff --tag" {
+		t.Errorf("Positional arguments are %q", positional)
+	}
+	expected := map[string]string{"json": "true", "limit": "5", "reblog_info": "true", "output": "table"}
+	for name, value := range expected {
+		if options[name] != value {
+			t.Errorf("Option %s is %q instead of %q", name, options[name], value)
+		}
+	}
+}
+
+func TestCommandProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := &tumblr.Config{}
+	config.SetProfile(tumblr.Profile{Name: "work", ConsumerKey: "work-key", ConsumerSecret: "work-consumer-secret", DefaultBlog: "staff.tumblr.com"})
+	config.SetProfile(tumblr.Profile{Name: "bot", OAuth2AccessToken: "bot-access-token"})
+	config.DefaultProfile = "work"
+	if err := config.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"GUMBLR_CONFIG": path}
+
+	status, stdout, stderr := runEnv(env, "profile", "list")
+	if status != exitOK || !strings.Contains(stdout, "work  true     oauth1") || !strings.Contains(stdout, "bot   false    oauth2") {
+		t.Errorf("Profile list exited %d with %q and %q", status, stdout, stderr)
+	}
+	if status, stdout, _ := runEnv(env, "profile", "show"); status != exitOK || !strings.Contains(stdout, "****cret") || strings.Contains(stdout, "work-consumer-secret") {
+		t.Errorf("Profile show exited %d with %q", status, stdout)
+	}
+	if status, _, _ := runEnv(env, "profile", "set-default", "bot"); status != exitOK {
+		t.Errorf("Setting the default profile exited %d", status)
+	}
+	if status, _, stderr := runEnv(env, "profile", "delete", "missing"); status != exitUsage || !strings.Contains(stderr, "missing") {
+		t.Errorf("Deleting a missing profile exited %d with %q", status, stderr)
+	}
+
+	// The --profile option picks the credentials and default blog of requests
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), `oauth_consumer_key="work-key"`) || r.URL.Path != "/v2/blog/staff.tumblr.com/post/delete" {
+			t.Errorf("Requested %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"id":1}}`)
+	}
+	server := useServer(handler)
+	defer server.Close()
+	if status, _, stderr := runEnv(env, "post", "delete", "1", "--profile", "work"); status != exitOK {
+		t.Errorf("Request with --profile exited %d with %q", status, stderr)
+	}
+
+	env["GUMBLR_PASSPHRASE"] = "correct horse"
+	if status, _, stderr := runEnv(env, "profile", "encrypt"); status != exitOK {
+		t.Fatalf("Encrypting exited %d with %q", status, stderr)
+	}
+	if encrypted, err := tumblr.LoadConfig(path, "correct horse"); err != nil || !encrypted.Encrypted() || encrypted.DefaultProfile != "bot" {
+		t.Errorf("Encrypted config loaded as %+v with error %v", encrypted, err)
+	}
+	delete(env, "GUMBLR_PASSPHRASE")
+	if status, _, stderr := runEnv(env, "profile", "list"); status != exitUsage || !strings.Contains(stderr, "passphrase") {
+		t.Errorf("Listing encrypted profiles without a passphrase exited %d with %q", status, stderr)
+	}
+}
+
+func TestCommandLogin(t *testing.T) {
+	var callbackURL string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/oauth/request_token":
+			callbackURL = r.PostForm.Get("oauth_callback")
+			fmt.Fprint(w, "oauth_token=request-token&oauth_token_secret=request-secret&oauth_callback_confirmed=true")
+		case "/oauth/access_token":
+			if r.PostForm.Get("oauth_verifier") != "the-verifier" {
+				t.Errorf("Access token was requested with %v", r.PostForm)
+			}
+			fmt.Fprint(w, "oauth_token=access-token&oauth_token_secret=access-secret")
+		case "/v2/user/info":
+			if !strings.Contains(r.Header.Get("Authorization"), `oauth_token="access-token"`) {
+				t.Errorf("Token was verified with %q", r.Header.Get("Authorization"))
+			}
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"user":{"name":"staff","blogs":[{"name":"staff","primary":true}]}}}`)
+		default:
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+	}
+	server := useServer(handler)
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "config.json")
+	env := map[string]string{"GUMBLR_CONFIG": path, "TUMBLR_CONSUMER_KEY": "consumer-key", "TUMBLR_CONSUMER_SECRET": "consumer-secret"}
+
+	// The browser comes back to the loopback listener
+	openBrowser = func(authorizeURL string) error {
+		if authorizeURL != "https://www.tumblr.com/oauth/authorize?oauth_token=request-token" {
+			t.Errorf("Opened %s", authorizeURL)
+		}
+		response, err := http.Get(callbackURL + "?oauth_token=request-token&oauth_verifier=the-verifier")
+		if err != nil || response.StatusCode != http.StatusOK {
+			t.Errorf("Callback to %q returned %v", callbackURL, err)
+		}
+		return nil
+	}
+	status, stdout, stderr := runEnv(env, "login", "work")
+	if status != exitOK || !strings.Contains(stdout, "Logged in as staff") {
+		t.Fatalf("Login exited %d with %q and %q", status, stdout, stderr)
+	}
+	if !strings.HasPrefix(callbackURL, "http://127.0.0.1:") {
+		t.Errorf("Callback URL is %q", callbackURL)
+	}
+	config, _ := tumblr.LoadConfig(path, "")
+	profile, err := config.Profile("work")
+	if err != nil || profile.ConsumerSecret != "consumer-secret" || profile.OAuthTokenSecret != "access-secret" || profile.DefaultBlog != "staff.tumblr.com" {
+		t.Errorf("Saved profile %+v with error %v", profile, err)
+	}
+
+	// The verifier can be pasted instead
+	stdin = strings.NewReader("https://example.com/?oauth_token=request-token&oauth_verifier=the-verifier#_=_\n")
+	if status, _, stderr := runEnv(env, "login", "--paste", "--blog", "other"); status != exitOK || callbackURL != "" {
+		t.Errorf("Pasted login exited %d with %q and callback %q", status, stderr, callbackURL)
+	}
+	config, _ = tumblr.LoadConfig(path, "")
+	if profile, err := config.Profile("default"); err != nil || profile.OAuthToken != "access-token" || profile.DefaultBlog != "other.tumblr.com" {
+		t.Errorf("Pasted login saved %+v with error %v", profile, err)
+	}
+
+	stdin = strings.NewReader("https://example.com/?oauth_token=request-token&denied=request-token\n")
+	if status, _, stderr := runEnv(env, "login", "--paste"); status != exitUsage || !strings.Contains(stderr, "denied") {
+		t.Errorf("Denied login exited %d with %q", status, stderr)
+	}
+}
+
+func TestCommandBackup(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/blog/staff.tumblr.com/info":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff"}}}`)
+		case r.URL.Path == "/v2/blog/staff.tumblr.com/posts" && r.URL.Query().Get("before") == "":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[{"id":1,"timestamp":100,"type":"text"}]}}`)
+		case r.URL.Path == "/v2/blog/staff.tumblr.com/likes" && r.URL.Query().Get("before") != "":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"meta":{"status":503,"msg":"Service Unavailable"},"response":[]}`)
+		default:
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[],"liked_posts":[{"id":2,"timestamp":50}]}}`)
+		}
+	}
+	dir := filepath.Join(t.TempDir(), "archive")
+	status, _, stderr := runTest(t, handler, "backup", "staff", "--dir", dir)
+	if status != exitServerError || !strings.Contains(stderr, "503") {
+		t.Errorf("Failed backup exited %d with %q", status, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "posts", "1.json")); err != nil {
+		t.Errorf("Post wasn't archived: %v", err)
+	}
+}
+
+func TestCommandRestore(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Dry run requested %s", r.URL.Path)
+	}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "posts"), 0755)
+	os.WriteFile(filepath.Join(dir, "posts", "1.json"), []byte(`{"id":1,"type":"text","timestamp":100,"body":"Hello"}`), 0644)
+
+	status, stdout, stderr := runTest(t, handler, "restore", dir, "--blog", "mirror", "--dry-run")
+	if status != exitOK || !strings.Contains(stdout, "1       -   text  published") {
+		t.Errorf("Dry run exited %d with %q and %q", status, stdout, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "restore-mirror.tumblr.com.json")); err == nil {
+		t.Error("Dry run wrote a mapping file")
+	}
+	for _, limit := range [][]string{{"--per-day", "0"}, {"--queue-limit", "-1"}} {
+		if status, _, _ := runTest(t, handler, append([]string{"restore", dir, "--blog", "mirror", "--dry-run"}, limit...)...); status != exitUsage {
+			t.Errorf("Restore with %q exited %d", limit, status)
+		}
+	}
+}
+
+func TestCommandExport(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/blog/staff.tumblr.com/posts" {
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("offset") == "0" {
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"total_posts":2,"posts":[{"id":1,"type":"text","timestamp":100,"slug":"hello","body":"<p>Hello</p>"}]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"total_posts":2,"posts":[{"id":2,"type":"quote","timestamp":200,"text":"Quoted"}]}}`)
+	}
+	dir := t.TempDir()
+	status, stdout, stderr := runTest(t, handler, "export", "staff", "--dir", dir)
+	if status != exitOK || !strings.Contains(stdout, "Posts:   2") {
+		t.Errorf("Export exited %d with %q and %q", status, stdout, stderr)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "posts", "1970-01-01-hello.md")); err != nil || !strings.HasSuffix(string(data), "\nHello\n") {
+		t.Errorf("Exported post holds %q with error %v", data, err)
+	}
+	if status, _, _ := runTest(t, handler, "export"); status != exitUsage {
+		t.Errorf("Export without a blog or archive exited %d", status)
+	}
+}
+
+func TestCommandWXR(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/blog/staff.tumblr.com/info":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff","title":"Staff"}}}`)
+		case "/v2/blog/staff.tumblr.com/posts":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"total_posts":1,"posts":[{"id":1,"type":"quote","timestamp":100,"tags":["zen"],"text":"Quoted"}]}}`)
+		default:
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+	}
+	status, stdout, stderr := runTest(t, handler, "wxr", "staff", "--author", "admin")
+	if status != exitOK || !strings.HasPrefix(stdout, "<?xml") || !strings.Contains(stdout, "<dc:creator><![CDATA[admin]]></dc:creator>") ||
+		!strings.Contains(stdout, `<category domain="post_format" nicename="post-format-quote"><![CDATA[Quote]]></category>`) {
+		t.Errorf("WXR export exited %d with %q and %q", status, stdout, stderr)
+	}
+	out := filepath.Join(t.TempDir(), "staff.xml")
+	status, stdout, stderr = runTest(t, handler, "wxr", "staff", "--out", out)
+	if status != exitOK || !strings.Contains(stdout, "Tags:         1") {
+		t.Errorf("WXR export to a file exited %d with %q and %q", status, stdout, stderr)
+	}
+	if data, err := os.ReadFile(out); err != nil || !strings.Contains(string(data), "<wp:tag_slug>zen</wp:tag_slug>") {
+		t.Errorf("Exported file holds %q with error %v", data, err)
+	}
+	if status, _, _ := runTest(t, handler, "wxr"); status != exitUsage {
+		t.Errorf("WXR export without a blog or archive exited %d", status)
+	}
+}
+
+func TestCommandSync(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/blog/staff.tumblr.com/post" {
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"meta":{"status":201,"msg":"Created"},"response":{"id":42}}`)
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.md"), []byte("---\ntitle: Hello\n---\n\nHello world\n"), 0644)
+
+	status, stdout, stderr := runTest(t, handler, "sync", dir, "--blog", "staff", "--dry-run")
+	if status != exitOK || !strings.Contains(strings.Join(strings.Fields(stdout), " "), "create - hello.md Hello") {
+		t.Errorf("Dry run exited %d with %q and %q", status, stdout, stderr)
+	}
+	status, stdout, stderr = runTest(t, handler, "sync", dir, "--blog", "staff")
+	if status != exitOK || !strings.Contains(stdout, "Created:  1") {
+		t.Errorf("Sync exited %d with %q and %q", status, stdout, stderr)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "hello.md")); !strings.Contains(string(data), "tumblr_id: 42") {
+		t.Errorf("Synced file holds %q", data)
+	}
+}
+
+func TestCommandFeed(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/blog/staff.tumblr.com/posts" || r.URL.Query().Get("tag") != "art" {
+			t.Errorf("Requested %s", r.URL)
+		}
+		if r.URL.Query().Get("offset") != "0" {
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[{"id":1,"type":"text","timestamp":100,"post_url":"https://staff.tumblr.com/post/1","title":"Hello"}]}}`)
+	}
+	status, stdout, stderr := runTest(t, handler, "feed", "blog", "staff", "--tag", "art", "--atom")
+	if status != exitOK || !strings.Contains(stdout, `<feed xmlns="http://www.w3.org/2005/Atom">`) || !strings.Contains(stdout, "<title>Hello</title>") {
+		t.Errorf("Feed exited %d with %q and %q", status, stdout, stderr)
+	}
+	if status, _, _ := runTest(t, handler, "feed", "blog", "staff", "--limit", "many"); status != exitUsage {
+		t.Errorf("Feed with a bad limit exited %d", status)
+	}
+}
+
+func TestCommandBridge(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Dry run requested %s", r.URL.Path)
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "company.rss")
+	os.WriteFile(source, []byte(`<rss version="2.0"><channel><item><title>Hello</title><link>https://example.com/hello</link><guid>hello</guid></item></channel></rss>`), 0644)
+
+	status, stdout, stderr := runTest(t, handler, "bridge", source, "--blog", "staff", "--queue", "--dry-run", "--state", filepath.Join(dir, "state.json"))
+	if status != exitOK || !strings.Contains(strings.Join(strings.Fields(stdout), " "), "hello - queue Hello") {
+		t.Errorf("Dry run exited %d with %q and %q", status, stdout, stderr)
+	}
+	if status, _, _ := runTest(t, handler, "bridge", source, "--blog", "staff", "--limit", "some"); status != exitUsage {
+		t.Errorf("Bridge with a bad limit exited %d", status)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/archive"
+	"github.com/mattcunningham/gumblr/feed"
+	"github.com/mattcunningham/gumblr/markdown"
+	"github.com/mattcunningham/gumblr/wxr"
+)
+
+// A result written to standard output as is, e.g. an avatar image or a feed
+type raw []byte
+
+// This method writes a result as JSON or as a table
+// result - The result of a command
+func (c *cli) write(result interface{}) error {
+	if data, ok := result.(raw); ok {
+		_, err := c.stdout.Write(data)
+		return err
+	}
+	if c.format == "json" {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	writeTable(w, result)
+	return w.Flush()
+}
+
+// This method writes a result as a table
+// w - The tab writer to write to
+// result - The result of a command
+func writeTable(w io.Writer, result interface{}) {
+	switch result := result.(type) {
+	case tumblr.Meta:
+		fmt.Fprintf(w, "%d\t%s\n", result.Status, result.Msg)
+	case tumblr.BlogInfo:
+		writeBlog(w, result.Blog)
+	case tumblr.BlogPosts:
+		writePosts(w, result.Posts)
+	case tumblr.BlogList:
+		writePosts(w, result.Posts)
+	case tumblr.Likes:
+		writePosts(w, result.LikedPost)
+	case []tumblr.Post:
+		writePosts(w, result)
+	case tumblr.Post:
+		writePosts(w, []tumblr.Post{result})
+	case tumblr.BlogFollowers:
+		fmt.Fprintln(w, "NAME\tURL\tUPDATED")
+		for _, user := range result.Users {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", user.Name, user.URL, formatTime(user.Updated))
+		}
+	case tumblr.BlogFollowing:
+		fmt.Fprintln(w, "NAME\tTITLE\tURL\tUPDATED")
+		for _, blog := range result.Blogs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", blog.Name, summarize(blog.Title), blog.URL, formatTime(blog.Updated))
+		}
+	case tumblr.UserFollowing:
+		fmt.Fprintln(w, "NAME\tTITLE\tURL\tUPDATED")
+		for _, blog := range result.Blogs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", blog.Name, summarize(blog.Title), blog.URL, formatTime(blog.Updated))
+		}
+	case tumblr.BlogFollowedBy:
+		fmt.Fprintf(w, "Followed by:\t%t\n", result.FollowedBy)
+	case tumblr.BlogNotifications:
+		fmt.Fprintln(w, "TIME\tTYPE\tFROM\tPOST")
+		for _, notification := range result.Notifications {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatTime(notification.Timestamp), notification.Type,
+				notification.FromTumblelogName, notification.TargetPostID)
+		}
+	case tumblr.UserInfo:
+		fmt.Fprintf(w, "Name:\t%s\nLikes:\t%d\nFollowing:\t%d\n\n", result.User.Name, result.User.Likes, result.User.Following)
+		fmt.Fprintln(w, "BLOG\tTITLE\tFOLLOWERS\tPRIMARY")
+		for _, blog := range result.User.Blogs {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%t\n", blog.Name, summarize(blog.Title), blog.Followers, blog.Primary)
+		}
+	case tumblr.UserLimits:
+		fmt.Fprintln(w, "LIMIT\tREMAINING\tTOTAL\tRESETS")
+		limits := result.User
+		for _, limit := range []struct {
+			name string
+			tumblr.Limit
+		}{
+			{"blogs", limits.Blogs}, {"follows", limits.Follows}, {"likes", limits.Likes}, {"photos", limits.Photos},
+			{"posts", limits.Posts}, {"video_seconds", limits.VideoSeconds}, {"videos", limits.Videos},
+		} {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", limit.name, limit.Remaining, limit.Limit.Limit, formatTime(limit.ResetAt))
+		}
+	case tumblr.UserFilteredTags:
+		for _, tag := range result.FilteredTags {
+			fmt.Fprintln(w, tag)
+		}
+	case tumblr.UserFilteredContent:
+		for _, content := range result.FilteredContent {
+			fmt.Fprintln(w, content)
+		}
+	case archive.Result:
+		fmt.Fprintf(w, "Posts:\t%d\nDrafts:\t%d\nQueued:\t%d\nLikes:\t%d\nMedia:\t%d\n", result.Posts, result.Drafts,
+			result.Queue, result.Likes, result.Media)
+		if result.MediaErrors > 0 {
+			fmt.Fprintf(w, "Failed media:\t%d\n", result.MediaErrors)
+		}
+	case markdown.ExportResult:
+		fmt.Fprintf(w, "Posts:\t%d\nImages:\t%d\n", result.Posts, result.Assets)
+		if result.AssetErrors > 0 {
+			fmt.Fprintf(w, "Failed images:\t%d\n", result.AssetErrors)
+		}
+	case wxr.ExportResult:
+		fmt.Fprintf(w, "Posts:\t%d\nAttachments:\t%d\nTags:\t%d\n", result.Posts, result.Attachments, result.Tags)
+	case markdown.SyncPlan:
+		fmt.Fprintln(w, "ACTION\tID\tFILE\tTITLE")
+		for _, action := range result.Actions {
+			id := strconv.Itoa(action.ID)
+			if action.ID == 0 {
+				id = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", action.Op, id, action.File, action.Title)
+		}
+		if result.Unchanged > 0 {
+			fmt.Fprintf(w, "unchanged\t-\t%d files\t\n", result.Unchanged)
+		}
+		for _, file := range result.Skipped {
+			fmt.Fprintf(w, "skipped\t-\t%s\t\n", file)
+		}
+	case markdown.SyncResult:
+		fmt.Fprintf(w, "Created:\t%d\nUpdated:\t%d\nDeleted:\t%d\n", result.Created, result.Updated, result.Deleted)
+	case feed.BridgeResult:
+		fmt.Fprintln(w, "GUID\tID\tSTATE\tTITLE")
+		for _, item := range result.Posted {
+			id := strconv.Itoa(item.ID)
+			if item.ID == 0 {
+				id = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.GUID, id, item.State, item.Title)
+		}
+		if result.Remaining > 0 {
+			fmt.Fprintf(w, "%d more items are left for the next run\n", result.Remaining)
+		}
+	case archive.RestoreResult:
+		fmt.Fprintln(w, "OLD ID\tID\tTYPE\tSTATE\tPUBLISH ON")
+		for _, post := range append(result.Restored, result.Unsupported...) {
+			id, publishOn := strconv.Itoa(post.ID), ""
+			if post.ID == 0 {
+				id = "-"
+			}
+			if !post.PublishOn.IsZero() {
+				publishOn = post.PublishOn.Format(time.DateOnly)
+			}
+			if post.Unrestored != "" {
+				publishOn = post.Unrestored
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", post.OldID, id, post.Type, post.State, publishOn)
+		}
+		fmt.Fprintf(w, "\nSkipped:\t%d\nRemaining:\t%d\n", result.Skipped, result.Remaining)
+	case profileList:
+		fmt.Fprintln(w, "NAME\tDEFAULT\tAUTH\tBLOG")
+		for _, profile := range result {
+			fmt.Fprintf(w, "%s\t%t\t%s\t%s\n", profile.Name, profile.Default, profile.Auth, profile.DefaultBlog)
+		}
+	case tumblr.Profile:
+		fmt.Fprintf(w, "Name:\t%s\nConsumer key:\t%s\nConsumer secret:\t%s\n", result.Name, result.ConsumerKey, result.ConsumerSecret)
+		fmt.Fprintf(w, "OAuth token:\t%s\nOAuth token secret:\t%s\n", result.OAuthToken, result.OAuthTokenSecret)
+		fmt.Fprintf(w, "OAuth 2.0 access token:\t%s\nDefault blog:\t%s\n", result.OAuth2AccessToken, result.DefaultBlog)
+	default:
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintf(w, "%s\n", data)
+	}
+}
+
+// This method writes a blog as a list of fields
+func writeBlog(w io.Writer, blog tumblr.Blog) {
+	fmt.Fprintf(w, "Name:\t%s\n", blog.Name)
+	fmt.Fprintf(w, "Title:\t%s\n", blog.Title)
+	fmt.Fprintf(w, "Posts:\t%d\n", blog.PostCount)
+	fmt.Fprintf(w, "Likes:\t%d\n", blog.Likes)
+	fmt.Fprintf(w, "Updated:\t%s\n", formatTime(blog.Updated))
+	fmt.Fprintf(w, "Asks:\t%t\n", blog.Ask)
+	fmt.Fprintf(w, "Description:\t%s\n", summarize(blog.Description))
+}
+
+// This method writes a table of posts
+func writePosts(w io.Writer, posts []tumblr.Post) {
+	fmt.Fprintln(w, "ID\tTYPE\tDATE\tNOTES\tSTATE\tSUMMARY")
+	for _, post := range posts {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n", post.ID, post.Type, formatTime(post.Timestamp),
+			post.NoteCount, post.State, summarize(postSummary(post)))
+	}
+}
+
+// This method returns the most descriptive text of a post
+func postSummary(post tumblr.Post) string {
+	for _, block := range post.Content {
+		if block.Text != "" {
+			return block.Text
+		}
+	}
+	for _, text := range []string{post.Title, post.Question, post.Text, post.Caption, post.Body, post.Description, post.URL} {
+		if text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// This method shortens text to one line of at most 60 characters without markup
+func summarize(text string) string {
+	text = strings.Join(strings.Fields(htmlTag.ReplaceAllString(text, " ")), " ")
+	if runes := []rune(text); len(runes) > 60 {
+		return string(runes[:59]) + "…"
+	}
+	return text
+}
+
+// This method formats a time in seconds since the epoch
+func formatTime(seconds int) string {
+	if seconds == 0 {
+		return "-"
+	}
+	return time.Unix(int64(seconds), 0).UTC().Format("2006-01-02 15:04")
+}
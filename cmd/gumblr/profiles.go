@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	tumblr "github.com/mattcunningham/gumblr"
+)
+
+// A list of profiles, written as a table or as JSON
+type profileList []profileSummary
+
+type profileSummary struct {
+	Name        string `json:"name"`
+	Default     bool   `json:"default"`
+	Auth        string `json:"auth"`
+	DefaultBlog string `json:"default_blog,omitempty"`
+}
+
+// This method loads the config file, or returns an empty config when it doesn't exist
+func (c *cli) loadConfig() (*tumblr.Config, string, error) {
+	path, err := tumblr.DefaultConfigPath(c.getenv)
+	if err != nil {
+		return nil, "", err
+	}
+	config, err := tumblr.LoadConfig(path, c.getenv("GUMBLR_PASSPHRASE"))
+	if errors.Is(err, os.ErrNotExist) {
+		return &tumblr.Config{Profiles: make(map[string]*tumblr.Profile)}, path, nil
+	}
+	return config, path, err
+}
+
+func listProfiles(c *cli, args []string) (interface{}, error) {
+	config, _, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	list := profileList{}
+	defaultProfile, _ := config.Profile("")
+	for _, name := range config.ProfileNames() {
+		profile := config.Profiles[name]
+		auth := "oauth1"
+		if profile.OAuth2AccessToken != "" {
+			auth = "oauth2"
+		}
+		list = append(list, profileSummary{
+			Name:        name,
+			Default:     profile == defaultProfile,
+			Auth:        auth,
+			DefaultBlog: profile.DefaultBlog,
+		})
+	}
+	return list, nil
+}
+
+func showProfile(c *cli, args []string) (interface{}, error) {
+	config, _, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := c.profile
+	if len(args) > 0 {
+		name = args[0]
+	}
+	profile, err := config.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+	masked := *profile
+	for _, secret := range []*string{&masked.ConsumerSecret, &masked.OAuthToken, &masked.OAuthTokenSecret,
+		&masked.OAuth2AccessToken, &masked.OAuth2RefreshToken} {
+		*secret = mask(*secret)
+	}
+	return masked, nil
+}
+
+func setDefaultProfile(c *cli, args []string) (interface{}, error) {
+	config, path, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := config.Profile(args[0]); err != nil {
+		return nil, err
+	}
+	config.DefaultProfile = args[0]
+	return tumblr.Meta{Status: 200, Msg: "Default profile set"}, config.Save(path)
+}
+
+func deleteProfile(c *cli, args []string) (interface{}, error) {
+	config, path, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := config.Profile(args[0]); err != nil {
+		return nil, err
+	}
+	delete(config.Profiles, args[0])
+	if config.DefaultProfile == args[0] {
+		config.DefaultProfile = ""
+	}
+	return tumblr.Meta{Status: 200, Msg: "Profile deleted"}, config.Save(path)
+}
+
+func encryptProfiles(c *cli, args []string) (interface{}, error) {
+	passphrase := c.getenv("GUMBLR_PASSPHRASE")
+	if passphrase == "" {
+		return nil, errors.New("set GUMBLR_PASSPHRASE to the passphrase to encrypt with")
+	}
+	config, path, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.SetPassphrase(passphrase)
+	return tumblr.Meta{Status: 200, Msg: "Config file encrypted"}, config.Save(path)
+}
+
+func decryptProfiles(c *cli, args []string) (interface{}, error) {
+	config, path, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.SetPassphrase("")
+	return tumblr.Meta{Status: 200, Msg: "Config file decrypted"}, config.Save(path)
+}
+
+// This method masks all but the last 4 characters of a secret
+func mask(secret string) string {
+	if len(secret) <= 8 {
+		if secret == "" {
+			return ""
+		}
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
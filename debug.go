@@ -0,0 +1,143 @@
+package tumblr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Serializes dumps so that those of concurrent requests sharing a writer don't interleave
+var debugMutex sync.Mutex
+
+// The context key of the writer requests made with a context are dumped to
+type debugKey struct{}
+
+// The debug writer set on a context, wrapped so that a nil writer can be told from none
+type debugSetting struct {
+	w io.Writer
+}
+
+// This method sets the writer every request and response is dumped to: the method,
+// URL, headers and body of each attempt as it is sent, signed, and the status,
+// headers and body of its response. The api key, OAuth tokens and signatures and
+// cookies are masked. By default nothing is dumped.
+// w - The writer to dump to, e.g. os.Stderr, nil disables dumping
+func (api *Tumblr) SetDebug(w io.Writer) {
+	api.debug = w
+}
+
+// This method returns a context whose requests are dumped to w, as with SetDebug,
+// for clients it is passed to with WithContext
+// ctx - The parent context
+// w - The writer to dump to, nil disables dumping even when the client dumps requests
+func WithDebug(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, debugKey{}, debugSetting{w: w})
+}
+
+// This method returns the writer a request is dumped to, or nil
+// request - The request
+func (api *Tumblr) debugWriter(request *http.Request) io.Writer {
+	if setting, found := request.Context().Value(debugKey{}).(debugSetting); found {
+		return setting.w
+	}
+	return api.debug
+}
+
+// This middleware dumps signed requests and their responses when debugging is on
+// next - The rest of the chain
+func (api *Tumblr) dump(next RoundTrip) RoundTrip {
+	return func(request *http.Request) (*http.Response, error) {
+		w := api.debugWriter(request)
+		if w == nil {
+			return next(request)
+		}
+		var out bytes.Buffer
+		dumpRequest(&out, request)
+		start := time.Now()
+		response, err := next(request)
+		if err != nil {
+			fmt.Fprintf(&out, "--- tumblr error (%s) ---\n%s\n\n", time.Since(start), redactError(err))
+		} else {
+			fmt.Fprintf(&out, "--- tumblr response (%s) ---\n", time.Since(start))
+			err = api.dumpResponse(&out, response)
+		}
+		debugMutex.Lock()
+		w.Write(out.Bytes())
+		debugMutex.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+}
+
+// This method writes a request with its secrets masked
+// out - The buffer to write to
+// request - The request
+func dumpRequest(out *bytes.Buffer, request *http.Request) {
+	fmt.Fprintf(out, "--- tumblr request ---\n%s %s\n", request.Method, redactURL(request.URL))
+	writeHeader(out, redactHeader(request.Header))
+	out.WriteString("\n")
+	if request.GetBody == nil {
+		return
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	if strings.HasPrefix(request.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(data)); err == nil {
+			for key := range form {
+				if isSecretParam(key) {
+					form.Set(key, redacted)
+				}
+			}
+			data = []byte(form.Encode())
+		}
+	}
+	if len(data) > 0 {
+		out.Write(data)
+		out.WriteString("\n\n")
+	}
+}
+
+// This method writes a response, reading its body and replacing it with a copy
+// out - The buffer to write to
+// response - The response
+func (api *Tumblr) dumpResponse(out *bytes.Buffer, response *http.Response) error {
+	fmt.Fprintf(out, "%s %s\n", response.Proto, response.Status)
+	writeHeader(out, redactHeader(response.Header))
+	out.WriteString("\n")
+	body, err := io.ReadAll(api.limit(response.Body))
+	closeBody(response.Body)
+	out.Write(body)
+	out.WriteString("\n\n")
+	if err != nil {
+		return err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+// This method writes headers in a stable order
+func writeHeader(out *bytes.Buffer, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			fmt.Fprintf(out, "%s: %s\n", key, value)
+		}
+	}
+}
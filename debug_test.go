@@ -0,0 +1,88 @@
+package tumblr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	var authorization string
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"meta":{"status":400,"msg":"Bad Request"},"response":{"errors":["Post body is required"]}}`)
+	})
+	defer server.Close()
+
+	var output bytes.Buffer
+	client := newTestClient(server)
+	client.SetDebug(&output)
+	response := client.Post("staff.tumblr.com", map[string]string{"type": "text", "title": "Hello"})
+	if response.Status != 400 {
+		t.Errorf("Dumped request returned %d instead of 400", response.Status)
+	}
+
+	dump := output.String()
+	for _, expected := range []string{
+		"POST http://api.tumblr.com/v2/blog/staff.tumblr.com/post\n",
+		`Authorization: OAuth oauth_consumer_key="REDACTED"`,
+		"Content-Type: application/x-www-form-urlencoded\n",
+		"title=Hello&type=text\n",
+		"HTTP/2.0 400 Bad Request\n",
+		"X-Request-Id: abc\n",
+		`"errors":["Post body is required"]`,
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("Dump does not contain %q:\n%s", expected, dump)
+		}
+	}
+
+	// The credentials sent to the server, whatever the signer puts in the header
+	secrets := []string{"consumer-key", "oauth-key"}
+	for _, param := range strings.Split(strings.TrimPrefix(authorization, "OAuth "), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(value, `"`)
+		if (key == "oauth_signature" || key == "oauth_token") && value != "" {
+			unescaped, _ := url.QueryUnescape(value)
+			secrets = append(secrets, value, unescaped)
+		}
+	}
+	if len(secrets) < 4 {
+		t.Fatalf("Server received no signature or token in %q", authorization)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(dump, secret) {
+			t.Errorf("Dump contains %q:\n%s", secret, dump)
+		}
+	}
+}
+
+func TestDebugContext(t *testing.T) {
+	client, closeServer := blogInfoServer()
+	defer closeServer()
+
+	var clientOutput, contextOutput bytes.Buffer
+	client.BlogInfo("staff.tumblr.com")
+	client.WithContext(WithDebug(context.Background(), &contextOutput)).BlogInfo("staff.tumblr.com")
+	if !strings.Contains(contextOutput.String(), `{"name":"staff"}`) {
+		t.Errorf("Context dump is %q", contextOutput.String())
+	}
+
+	client.SetDebug(&clientOutput)
+	client.WithContext(WithDebug(context.Background(), nil)).BlogInfo("staff.tumblr.com")
+	if clientOutput.Len() > 0 {
+		t.Errorf("Request with debugging disabled by its context was dumped: %s", clientOutput.String())
+	}
+	if blogInfo := client.BlogInfo("staff.tumblr.com"); blogInfo.Blog.Name != "staff" {
+		t.Errorf("Dumped response was decoded as %q", blogInfo.Blog.Name)
+	}
+	if clientOutput.Len() == 0 {
+		t.Error("Client dump is empty")
+	}
+}
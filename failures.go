@@ -0,0 +1,49 @@
+package tumblr
+
+import (
+	"context"
+	"sync"
+)
+
+// The failure of the API calls made within Try
+type failure struct {
+	mutex sync.Mutex
+	err   error
+}
+
+// The context key of the failure of the Try in progress
+type failureKey struct{}
+
+// This method calls fn with a copy of the client whose requests use ctx, like
+// WithContext, and returns the first failure of the API calls fn makes with it: a
+// request that failed, or that Tumblr answered with an error status once retries
+// ran out, or a response that couldn't be decoded. Without one, ctx's error is
+// returned. The client's methods only report failures in what they return, e.g.
+// as an empty BlogPosts, so this tells a failed call from an empty result.
+// ctx - The context of the requests
+// fn - Makes API calls with the copy of the client
+func (api *Tumblr) Try(ctx context.Context, fn func(api *Tumblr)) error {
+	f := &failure{}
+	fn(api.WithContext(context.WithValue(ctx, failureKey{}, f)))
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err == nil {
+		return ctx.Err()
+	}
+	return f.err
+}
+
+// This method records the failure of a request made within Try, keeping the first
+// ctx - The context of the request
+// err - The failure, nil if the request succeeded
+func recordFailure(ctx context.Context, err error) {
+	f, ok := ctx.Value(failureKey{}).(*failure)
+	if !ok || err == nil {
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err == nil {
+		f.err = err
+	}
+}
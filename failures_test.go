@@ -0,0 +1,67 @@
+package tumblr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTry(t *testing.T) {
+	var attempts int32
+	server := newTestServer(false, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/blog/retried.tumblr.com/posts":
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"meta":{"status":429,"msg":"Limit Exceeded"},"response":[]}`)
+				return
+			}
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[]}}`)
+		case "/v2/blog/missing.tumblr.com/posts":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"meta":{"status":404,"msg":"Not Found"},"response":[]}`)
+		case "/v2/blog/truncated.tumblr.com/posts":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[{"id":1,`)
+		case "/v2/blog/large.tumblr.com/posts":
+			fmt.Fprintf(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[{"id":1,"body":"%s"}]}}`, strings.Repeat("a", 4096))
+		default:
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[]}}`)
+		}
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetMaxResponseSize(1024)
+	try := func(ctx context.Context, blogs ...string) error {
+		return client.Try(ctx, func(api *Tumblr) {
+			for _, blog := range blogs {
+				api.BlogPosts(blog, nil)
+			}
+		})
+	}
+
+	// A request that succeeds once retried and an empty result aren't failures
+	if err := try(context.Background(), "retried.tumblr.com", "empty.tumblr.com"); err != nil || attempts != 2 {
+		t.Errorf("Try returned %v after %d attempts", err, attempts)
+	}
+	var apiError *APIError
+	if err := try(context.Background(), "missing.tumblr.com", "empty.tumblr.com"); !errors.As(err, &apiError) || apiError.Meta.Status != 404 {
+		t.Errorf("Try of a missing blog returned %v", err)
+	}
+	if err := try(context.Background(), "truncated.tumblr.com"); err == nil {
+		t.Error("Try of a truncated response returned no error")
+	}
+	if err := try(context.Background(), "large.tumblr.com"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Try of a response too large returned %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := try(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Try with a canceled context returned %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/atomicfile"
+)
+
+// The templates of a bridge when Title and Body aren't set, by post type
+var defaultTemplates = map[string][2]string{
+	"link": {"{{.Title}}", "{{.Summary}}"},
+	"text": {"{{.Title}}", `{{.Content}}<p><a href="{{.Link}}">{{.Link}}</a></p>`},
+}
+
+// A bridge posting the items of an RSS or Atom feed to a blog. Every run posts the
+// items that weren't posted before, oldest first, and records their GUIDs in a
+// state file, so the bridge can run on a schedule.
+type Bridge struct {
+	BlogHostname string       // The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+	Source       string       // The feed: a file, or an http or https URL
+	State        string       // The state file. Default: bridge-<blog>.json
+	PostType     string       // The type of the posts, link or text. Default: link
+	Title        string       // The template of a post's title, executed with an Item. Default: {{.Title}}
+	Body         string       // The template of a link post's description or a text post's body. Default: the summary, or the content and link
+	Tags         []string     // The tags of every post
+	ItemTags     bool         // Whether to tag posts with their item's categories too
+	Queue        bool         // Whether to queue the posts rather than publish them
+	Limit        int          // The most items posted per run, 0 for no limit
+	DryRun       bool         // Whether to only report what would be posted
+	HTTPClient   *http.Client // The HTTP client the feed is fetched with. Default: http.DefaultClient
+	Logger       *slog.Logger // Receives the progress of a run. Default: silent
+
+	api *tumblr.Tumblr
+}
+
+// The record of the items a bridge posted
+type bridgeState struct {
+	Blog   string                 `json:"blog"`   // the hostname of the blog posted to
+	Posted map[string]BridgedItem `json:"posted"` // the posted items, by GUID
+}
+
+// An item a bridge posted
+type BridgedItem struct {
+	GUID   string    `json:"guid"`             // The item's GUID
+	Title  string    `json:"title"`            // The post's title
+	ID     int       `json:"id,omitempty"`     // The ID of the post, 0 in a dry run or when it couldn't be recorded
+	State  string    `json:"state"`            // published or queue
+	Posted time.Time `json:"posted,omitzero"`  // When the item was posted
+	Params []string  `json:"params,omitempty"` // The parameters of the post, in a dry run
+}
+
+// The items a run posted, or would post in a dry run
+type BridgeResult struct {
+	Posted    []BridgedItem `json:"posted"`    // The posted items, oldest first
+	Seen      int           `json:"seen"`      // The number of items posted by earlier runs
+	Remaining int           `json:"remaining"` // The number of new items left for later runs by Limit
+}
+
+// This method creates a bridge from a feed to a blog
+// source - The feed: a file, or an http or https URL
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+func NewBridge(api *tumblr.Tumblr, source string, blogHostname string) *Bridge {
+	return &Bridge{
+		BlogHostname: blogHostname,
+		Source:       source,
+		State:        "bridge-" + blogHostname + ".json",
+		PostType:     "link",
+		HTTPClient:   http.DefaultClient,
+		Logger:       slog.New(slog.DiscardHandler),
+		api:          api,
+	}
+}
+
+// This method runs the bridge. The state file is written before and after every
+// post, so a run that fails can be run again without posting an item twice. An
+// item whose post couldn't be recorded keeps ID 0 in the state file.
+// ctx - The context of every request
+func (bridge *Bridge) Run(ctx context.Context) (BridgeResult, error) {
+	var result BridgeResult
+	defaults, found := defaultTemplates[bridge.PostType]
+	if !found {
+		return result, fmt.Errorf("feed: can't bridge to %q posts, only to link or text posts", bridge.PostType)
+	}
+	titleTemplate, err := parseTemplate("title", bridge.Title, defaults[0])
+	if err != nil {
+		return result, err
+	}
+	bodyTemplate, err := parseTemplate("body", bridge.Body, defaults[1])
+	if err != nil {
+		return result, err
+	}
+	state, err := bridge.readState()
+	if err != nil {
+		return result, err
+	}
+	data, err := bridge.fetch(ctx)
+	if err != nil {
+		return result, err
+	}
+	items, err := Parse(data)
+	if err != nil {
+		return result, fmt.Errorf("feed: reading %s: %w", bridge.Source, err)
+	}
+
+	// Feeds list their items newest first, or sometimes in no order at all
+	slices.SortStableFunc(items, func(a, b Item) int { return a.Published.Compare(b.Published) })
+	api := bridge.api.WithContext(ctx)
+	for _, item := range items {
+		if _, posted := state.Posted[item.GUID]; posted || item.GUID == "" {
+			result.Seen++
+			continue
+		}
+		if bridge.Limit > 0 && len(result.Posted) == bridge.Limit {
+			result.Remaining++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		params, err := bridge.params(item, titleTemplate, bodyTemplate)
+		if err != nil {
+			return result, err
+		}
+		bridged := BridgedItem{GUID: item.GUID, Title: params["title"], State: params["state"]}
+		if bridge.DryRun {
+			for key, value := range params {
+				bridged.Params = append(bridged.Params, key+"="+value)
+			}
+			slices.Sort(bridged.Params)
+			result.Posted = append(result.Posted, bridged)
+			continue
+		}
+
+		// Recorded before it's posted, so that when recording the post fails a
+		// later run doesn't post the item again
+		state.Posted[item.GUID] = bridged
+		if err := state.write(bridge.State); err != nil {
+			return result, err
+		}
+		created := api.CreatePost(bridge.BlogHostname, params)
+		if created.Meta.Status < 200 || created.Meta.Status >= 300 {
+			delete(state.Posted, item.GUID)
+			err := fmt.Errorf("feed: posting %s: %d %s", item.GUID, created.Meta.Status, created.Meta.Msg)
+			return result, errors.Join(err, state.write(bridge.State))
+		}
+		bridged.ID = created.ID
+		bridged.Posted = time.Now().UTC()
+		state.Posted[item.GUID] = bridged
+		result.Posted = append(result.Posted, bridged)
+		bridge.Logger.Info("item posted", "guid", item.GUID, "post", created.ID)
+		if err := state.write(bridge.State); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// This method parses a template, or its default when it isn't set
+func parseTemplate(name string, text string, fallback string) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+	parsed, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("feed: %s template: %w", name, err)
+	}
+	return parsed, nil
+}
+
+// This method returns the parameters of the post of an item
+func (bridge *Bridge) params(item Item, titleTemplate *template.Template, bodyTemplate *template.Template) (map[string]string, error) {
+	var title, body strings.Builder
+	if err := titleTemplate.Execute(&title, item); err != nil {
+		return nil, fmt.Errorf("feed: title template: %w", err)
+	}
+	if err := bodyTemplate.Execute(&body, item); err != nil {
+		return nil, fmt.Errorf("feed: body template: %w", err)
+	}
+	params := map[string]string{"type": bridge.PostType, "state": "published"}
+	if bridge.Queue {
+		params["state"] = "queue"
+	}
+	if title := strings.TrimSpace(title.String()); title != "" {
+		params["title"] = title
+	}
+	switch bridge.PostType {
+	case "link":
+		params["url"] = item.Link
+		if description := strings.TrimSpace(body.String()); description != "" {
+			params["description"] = description
+		}
+	case "text":
+		params["body"] = strings.TrimSpace(body.String())
+	}
+
+	tags := slices.Clone(bridge.Tags)
+	if bridge.ItemTags {
+		tags = append(tags, item.Categories...)
+	}
+	var unique []string
+	for _, tag := range tags {
+		// Tumblr tags can't hold commas, which separate them
+		if tag = strings.Join(strings.Fields(strings.ReplaceAll(tag, ",", " ")), " "); tag != "" && !slices.Contains(unique, tag) {
+			unique = append(unique, tag)
+		}
+	}
+	if len(unique) > 0 {
+		params["tags"] = strings.Join(unique, ",")
+	}
+	return params, nil
+}
+
+// This method reads the feed from its file or URL
+func (bridge *Bridge) fetch(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(bridge.Source, "http://") && !strings.HasPrefix(bridge.Source, "https://") {
+		return os.ReadFile(bridge.Source)
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", bridge.Source, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := bridge.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed: fetching %s: %s", bridge.Source, response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// This method reads the state file, returning an empty state before the first run
+func (bridge *Bridge) readState() (*bridgeState, error) {
+	state := &bridgeState{Blog: bridge.BlogHostname, Posted: make(map[string]BridgedItem)}
+	data, err := os.ReadFile(bridge.State)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("feed: reading %s: %v", bridge.State, err)
+	}
+	if state.Blog != bridge.BlogHostname {
+		return nil, fmt.Errorf("feed: %s records posts to %s", bridge.State, state.Blog)
+	}
+	if state.Posted == nil {
+		state.Posted = make(map[string]BridgedItem)
+	}
+	return state, nil
+}
+
+func (state *bridgeState) write(path string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(path, bytes.NewReader(data))
+}
@@ -0,0 +1,163 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattcunningham/gumblr/internal/tumblrtest"
+)
+
+const companyRSS = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><title>Company blog</title>
+<item><title>Second</title><link>https://example.com/second</link><guid>post-2</guid><pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate>
+	<description>Summary &lt;b&gt;two&lt;/b&gt;</description><content:encoded><![CDATA[<p>Full two</p>]]></content:encoded>
+	<dc:creator>Jane</dc:creator><category>news</category><category>go, tips</category></item>
+<item><title>First</title><link>https://example.com/first</link><pubDate>Mon, 1 Jan 2024 10:00:00 GMT</pubDate><description>One</description></item>
+</channel></rss>`
+
+func TestParse(t *testing.T) {
+	items, err := Parse([]byte(companyRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Item{GUID: "post-2", Title: "Second", Link: "https://example.com/second", Summary: "Summary <b>two</b>", Content: "<p>Full two</p>",
+		Author: "Jane", Published: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), Categories: []string{"news", "go, tips"}}
+	if len(items) != 2 || !reflect.DeepEqual(items[0], want) {
+		t.Errorf("Parsed RSS items %+v", items)
+	}
+	if first := items[1]; first.GUID != "https://example.com/first" || first.Content != "One" || first.Published.IsZero() {
+		t.Errorf("Item without a GUID was parsed as %+v", first)
+	}
+
+	items, err = Parse([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>tag:example.com,2024:1</id><title>Atom &amp; co</title>
+		<link rel="enclosure" href="https://example.com/a.mp3"/><link href="https://example.com/atom"/><updated>2024-01-03T10:00:00Z</updated>
+		<summary>1 &lt; 2</summary><content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Hi</p></div></content>
+		<author><name>Joe</name></author><category term="atom"/></entry></feed>`))
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Parsed Atom items %+v with error %v", items, err)
+	}
+	if entry := items[0]; entry.GUID != "tag:example.com,2024:1" || entry.Title != "Atom & co" || entry.Link != "https://example.com/atom" ||
+		entry.Summary != "1 &lt; 2" || entry.Content != `<div xmlns="http://www.w3.org/1999/xhtml"><p>Hi</p></div>` || entry.Author != "Joe" ||
+		entry.Categories[0] != "atom" || entry.Published.Year() != 2024 {
+		t.Errorf("Parsed Atom entry %+v", entry)
+	}
+
+	if _, err := Parse([]byte(`<html></html>`)); err != ErrUnknownFormat {
+		t.Errorf("HTML was parsed with error %v", err)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	var mutex sync.Mutex
+	var created []url.Values
+	server, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch r.URL.Path {
+		case "/company.rss":
+			fmt.Fprint(w, companyRSS)
+		case "/v2/blog/staff.tumblr.com/post":
+			r.ParseForm()
+			created = append(created, r.PostForm)
+			fmt.Fprintf(w, `{"meta":{"status":201,"msg":"Created"},"response":{"id":%d}}`, 100+len(created))
+		default:
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+	}))
+	dir := t.TempDir()
+
+	bridge := NewBridge(client, server.URL+"/company.rss", "staff.tumblr.com")
+	bridge.State = filepath.Join(dir, "state.json")
+	bridge.Tags = []string{"company"}
+	bridge.ItemTags = true
+	bridge.Limit = 1
+
+	// A dry run posts nothing, oldest first
+	bridge.DryRun = true
+	result, err := bridge.Run(context.Background())
+	if err != nil || len(result.Posted) != 1 || result.Remaining != 1 || len(created) != 0 {
+		t.Fatalf("Dry run returned %+v with error %v", result, err)
+	}
+	if want := []string{"description=One", "state=published", "tags=company", "title=First", "type=link", "url=https://example.com/first"}; !reflect.DeepEqual(result.Posted[0].Params, want) {
+		t.Errorf("Dry run would post %v", result.Posted[0].Params)
+	}
+	if _, err := os.Stat(bridge.State); err == nil {
+		t.Error("Dry run wrote the state file")
+	}
+
+	bridge.DryRun = false
+	if result, err = bridge.Run(context.Background()); err != nil || len(result.Posted) != 1 || result.Posted[0].ID != 101 {
+		t.Fatalf("Run returned %+v with error %v", result, err)
+	}
+
+	// The next run posts the rest, from a file, as queued text posts
+	os.WriteFile(filepath.Join(dir, "company.rss"), []byte(companyRSS), 0644)
+	bridge.Source = filepath.Join(dir, "company.rss")
+	bridge.PostType = "text"
+	bridge.Title = "{{.Title}} by {{.Author}}"
+	bridge.Queue = true
+	result, err = bridge.Run(context.Background())
+	if err != nil || len(result.Posted) != 1 || result.Seen != 1 || result.Remaining != 0 {
+		t.Fatalf("Second run returned %+v with error %v", result, err)
+	}
+	if post := created[1]; post.Get("type") != "text" || post.Get("title") != "Second by Jane" || post.Get("state") != "queue" ||
+		post.Get("body") != `<p>Full two</p><p><a href="https://example.com/second">https://example.com/second</a></p>` || post.Get("tags") != "company,news,go tips" {
+		t.Errorf("Second item was posted with %v", post)
+	}
+	if result, err = bridge.Run(context.Background()); err != nil || len(result.Posted) != 0 || result.Seen != 2 {
+		t.Errorf("Third run returned %+v with error %v", result, err)
+	}
+
+	bridge.Title = "{{.Nope"
+	if _, err := bridge.Run(context.Background()); err == nil {
+		t.Error("Bad template was run")
+	}
+	other := NewBridge(client, bridge.Source, "other.tumblr.com")
+	other.State = bridge.State
+	if _, err := other.Run(context.Background()); err == nil {
+		t.Error("State of another blog was used")
+	}
+}
+
+func TestBridgeUnrecordedPost(t *testing.T) {
+	dir := t.TempDir()
+	state := filepath.Join(dir, "state.json")
+	var posts int
+	server, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/company.rss":
+			fmt.Fprint(w, companyRSS)
+		case "/v2/blog/staff.tumblr.com/post":
+			// The state file can't be replaced once the first item is posted
+			if posts++; posts == 1 {
+				os.Rename(state, state+".saved")
+				os.MkdirAll(filepath.Join(state, "busy"), 0755)
+			}
+			fmt.Fprintf(w, `{"meta":{"status":201,"msg":"Created"},"response":{"id":%d}}`, 100+posts)
+		default:
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+	}))
+	bridge := NewBridge(client, server.URL+"/company.rss", "staff.tumblr.com")
+	bridge.State = state
+	if _, err := bridge.Run(context.Background()); err == nil || posts != 1 {
+		t.Fatalf("Run that couldn't record its post returned %v after %d posts", err, posts)
+	}
+	os.RemoveAll(state)
+	os.Rename(state+".saved", state)
+
+	// The next run doesn't post the item again
+	result, err := bridge.Run(context.Background())
+	if err != nil || posts != 2 || result.Seen != 1 || len(result.Posted) != 1 || result.Posted[0].Title != "Second" {
+		t.Errorf("Next run returned %+v with error %v after %d posts", result, err, posts)
+	}
+}
@@ -0,0 +1,156 @@
+package feed
+
+import (
+	"encoding/json"
+	"html"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/archive"
+)
+
+// This method returns the title of a post's item: its title, else Tumblr's
+// summary of it, else its type
+func title(post tumblr.Post) string {
+	if post.Title != "" {
+		return post.Title
+	}
+	var summary string
+	json.Unmarshal(post.Extra["summary"], &summary)
+	if summary = strings.Join(strings.Fields(summary), " "); summary != "" {
+		if runes := []rune(summary); len(runes) > 80 {
+			summary = string(runes[:79]) + "…"
+		}
+		return summary
+	}
+	if post.Type == "" {
+		return "Post"
+	}
+	return strings.ToUpper(post.Type[:1]) + post.Type[1:] + " post"
+}
+
+// This method renders the content of a post as HTML, by post type
+func content(post tumblr.Post) string {
+	var out strings.Builder
+	switch post.Type {
+	case "text":
+		out.WriteString(post.Body)
+	case "photo":
+		for _, photo := range post.Photos {
+			out.WriteString(`<p><img src="` + html.EscapeString(photo.OriginalSize.URL) + `" alt="` + html.EscapeString(photo.Caption) + `"></p>`)
+		}
+		out.WriteString(post.Caption)
+	case "quote":
+		out.WriteString("<blockquote>" + post.Text + "</blockquote>")
+		if post.Source != "" {
+			out.WriteString("<p>— " + post.Source + "</p>")
+		}
+	case "link":
+		linkTitle := post.Title
+		if linkTitle == "" {
+			linkTitle = post.URL
+		}
+		out.WriteString(`<p><a href="` + html.EscapeString(post.URL) + `">` + html.EscapeString(linkTitle) + "</a></p>")
+		if post.Excerpt != "" {
+			out.WriteString("<blockquote>" + post.Excerpt + "</blockquote>")
+		}
+		out.WriteString(post.Description)
+	case "chat":
+		for _, line := range post.Dialogue {
+			out.WriteString("<p>")
+			if line.Label != "" {
+				out.WriteString("<strong>" + html.EscapeString(line.Label) + "</strong> ")
+			}
+			out.WriteString(html.EscapeString(line.Phrase) + "</p>")
+		}
+	case "answer":
+		asker := html.EscapeString(post.AskingName)
+		if asker == "" {
+			asker = "Anonymous"
+		} else if post.AskingURL != "" {
+			asker = `<a href="` + html.EscapeString(post.AskingURL) + `">` + asker + "</a>"
+		}
+		out.WriteString("<blockquote><p>" + asker + " asked:</p><p>" + post.Question + "</p></blockquote>")
+		out.WriteString(post.Answer)
+	case "audio":
+		out.WriteString(post.AudioPlayer)
+		out.WriteString(post.Caption)
+	case "video":
+		if len(post.Player) > 0 {
+			// Players are listed smallest first
+			out.WriteString(post.Player[len(post.Player)-1].EmbedCode)
+		}
+		out.WriteString(post.Caption)
+	}
+	if out.Len() == 0 {
+		out.WriteString(contentBlocks(post.Content))
+	}
+	return out.String()
+}
+
+// This method renders Neue Post Format content blocks as HTML
+func contentBlocks(blocks []tumblr.ContentBlock) string {
+	var out strings.Builder
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			text := strings.ReplaceAll(html.EscapeString(block.Text), "\n", "<br>")
+			switch block.Subtype {
+			case "heading1":
+				out.WriteString("<h1>" + text + "</h1>")
+			case "heading2":
+				out.WriteString("<h2>" + text + "</h2>")
+			case "quote", "indented":
+				out.WriteString("<blockquote>" + text + "</blockquote>")
+			default:
+				out.WriteString("<p>" + text + "</p>")
+			}
+		case "image":
+			if len(block.Media) > 0 {
+				out.WriteString(`<p><img src="` + html.EscapeString(block.Media[0].URL) + `" alt="` + html.EscapeString(block.AltText) + `"></p>`)
+			}
+		case "link":
+			linkTitle := block.Title
+			if linkTitle == "" {
+				linkTitle = block.URL
+			}
+			out.WriteString(`<p><a href="` + html.EscapeString(block.URL) + `">` + html.EscapeString(linkTitle) + "</a></p>")
+		case "audio", "video":
+			if block.EmbedHTML != "" {
+				out.WriteString(block.EmbedHTML)
+			} else if block.URL != "" {
+				out.WriteString(`<p><a href="` + html.EscapeString(block.URL) + `">` + html.EscapeString(block.URL) + "</a></p>")
+			}
+		}
+	}
+	return out.String()
+}
+
+// This method returns the enclosures of a post: its Tumblr hosted photos, audio
+// and video, as Atom links
+func enclosures(post tumblr.Post) []atomLink {
+	var links []atomLink
+	for _, mediaURL := range archive.MediaURLs(post) {
+		var mediaType string
+		if parsed, err := url.Parse(mediaURL); err == nil {
+			mediaType = mime.TypeByExtension(path.Ext(parsed.Path))
+		}
+		if mediaType == "" {
+			switch post.Type {
+			case "photo":
+				mediaType = "image/jpeg"
+			case "audio":
+				mediaType = "audio/mpeg"
+			case "video":
+				mediaType = "video/mp4"
+			default:
+				mediaType = "application/octet-stream"
+			}
+		}
+		links = append(links, atomLink{Href: mediaURL, Rel: "enclosure", Type: mediaType})
+	}
+	return links
+}
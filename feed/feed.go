@@ -0,0 +1,348 @@
+// Package feed generates RSS 2.0 and Atom 1.0 feeds of Tumblr posts: a blog's
+// posts, optionally filtered by tag and type, the posts tagged with a tag across
+// Tumblr, the user's likes, or several of these combined into one feed.
+//
+// Feeds are built on demand from the API, by Feed's methods or by the HTTP
+// handler that Handler returns. The other way around, Bridge posts the items of
+// an RSS or Atom feed to a blog.
+package feed
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+)
+
+// The number of items of a feed when Limit isn't set
+const DefaultLimit = 20
+
+// A source of the posts of a feed
+type Source struct {
+	Title string // The title of a feed of this source alone
+	Link  string // The URL of the page showing the same posts
+
+	// fetches the page of posts after the ones fetched so far, newest first
+	fetch func(api *tumblr.Tumblr, fetched []tumblr.Post, limit int) []tumblr.Post
+}
+
+// This method returns the source of a blog's published posts
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// tag - Only the posts with this tag, "" for all posts
+// postType - Only the posts of this type (text, quote, link, answer, video, audio, photo or chat), "" for all types
+func Blog(blogHostname string, tag string, postType string) Source {
+	source := Source{Title: blogHostname, Link: "https://" + blogHostname + "/"}
+	if tag != "" {
+		source.Title += " #" + tag
+		source.Link += "tagged/" + url.PathEscape(tag)
+	}
+	if postType != "" {
+		source.Title += " (" + postType + ")"
+	}
+	source.fetch = func(api *tumblr.Tumblr, fetched []tumblr.Post, limit int) []tumblr.Post {
+		params := map[string]string{"limit": strconv.Itoa(limit), "offset": strconv.Itoa(len(fetched))}
+		if tag != "" {
+			params["tag"] = tag
+		}
+		if postType != "" {
+			params["type"] = postType
+		}
+		return api.BlogPosts(blogHostname, params).Posts
+	}
+	return source
+}
+
+// This method returns the source of the posts tagged with a tag across Tumblr
+// tag - The tag
+func Tagged(tag string) Source {
+	return Source{
+		Title: "#" + tag + " on Tumblr",
+		Link:  "https://www.tumblr.com/tagged/" + url.PathEscape(tag),
+		fetch: func(api *tumblr.Tumblr, fetched []tumblr.Post, limit int) []tumblr.Post {
+			params := map[string]string{"limit": strconv.Itoa(limit)}
+			if len(fetched) > 0 {
+				params["before"] = strconv.Itoa(fetched[len(fetched)-1].Timestamp)
+			}
+			return api.TaggedPosts(tag, params)
+		},
+	}
+}
+
+// This method returns the source of the posts the user likes
+func Likes() Source {
+	return Source{
+		Title: "Likes",
+		Link:  "https://www.tumblr.com/likes",
+		fetch: func(api *tumblr.Tumblr, fetched []tumblr.Post, limit int) []tumblr.Post {
+			return api.UserLikes(map[string]string{"limit": strconv.Itoa(limit), "offset": strconv.Itoa(len(fetched))}).LikedPost
+		},
+	}
+}
+
+// A feed of the newest posts of one or more sources
+type Feed struct {
+	Title       string   // The title of the feed. Default: the titles of the sources
+	Link        string   // The URL of the page the feed is of. Default: the link of the first source
+	Description string   // The description of the feed. Default: derived from the title
+	Self        string   // The URL the feed is served at, if known
+	Sources     []Source // The sources of the posts, merged newest first
+	Limit       int      // The number of items. Default: DefaultLimit
+
+	api *tumblr.Tumblr
+}
+
+// This method creates a feed of sources
+// sources - The sources of the posts
+func New(api *tumblr.Tumblr, sources ...Source) *Feed {
+	feed := &Feed{Sources: sources, Limit: DefaultLimit, api: api}
+	var titles []string
+	for _, source := range sources {
+		titles = append(titles, source.Title)
+	}
+	feed.Title = strings.Join(titles, ", ")
+	feed.Description = "Posts from " + feed.Title
+	if len(sources) > 0 {
+		feed.Link = sources[0].Link
+	}
+	return feed
+}
+
+// This method fetches the newest posts of the sources, newest first. A post found
+// in several sources is included once.
+// ctx - The context of every request
+func (feed *Feed) Posts(ctx context.Context) ([]tumblr.Post, error) {
+	api := feed.api.WithContext(ctx)
+	api.SetPreserveRaw(true) // for the summaries of posts without a title
+
+	limit := feed.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	var posts []tumblr.Post
+	seen := make(map[int]bool)
+	for _, source := range feed.Sources {
+		var fetched []tumblr.Post
+		for len(fetched) < limit {
+			var page []tumblr.Post
+			err := api.Try(ctx, func(api *tumblr.Tumblr) {
+				page = source.fetch(api, fetched, min(limit-len(fetched), 20))
+			})
+			if err != nil {
+				return nil, fmt.Errorf("feed: %w", err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			fetched = append(fetched, page...)
+		}
+		for _, post := range fetched {
+			if !seen[post.ID] {
+				seen[post.ID] = true
+				posts = append(posts, post)
+			}
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].Timestamp > posts[j].Timestamp })
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// The elements of an RSS 2.0 document
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Categories  []string      `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"` // unknown, 0 as is customary
+	Type   string `xml:"type,attr"`
+}
+
+// The elements of an Atom 1.0 document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Links      []atomLink     `xml:"link"`
+	Author     atomPerson     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// This method generates the feed as RSS 2.0
+// ctx - The context of every request
+func (feed *Feed) RSS(ctx context.Context) ([]byte, error) {
+	posts, err := feed.Posts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return feed.marshalRSS(posts)
+}
+
+func (feed *Feed) marshalRSS(posts []tumblr.Post) ([]byte, error) {
+	document := rss{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: rssChannel{
+		Title:       feed.Title,
+		Link:        feed.Link,
+		Description: feed.Description,
+		Generator:   "gumblr",
+	}}
+	if feed.Self != "" {
+		document.Channel.Self = &atomLink{Href: feed.Self, Rel: "self", Type: "application/rss+xml"}
+	}
+	if len(posts) > 0 {
+		document.Channel.LastBuildDate = postTime(posts[0]).Format(time.RFC1123Z)
+	}
+	for _, post := range posts {
+		item := rssItem{
+			Title:       title(post),
+			Link:        post.PostURL,
+			Description: content(post),
+			GUID:        rssGUID{IsPermaLink: post.PostURL != "", Value: guid(post)},
+			PubDate:     postTime(post).Format(time.RFC1123Z),
+			Categories:  post.Tags,
+		}
+		// RSS allows one enclosure per item
+		if enclosures := enclosures(post); len(enclosures) > 0 {
+			item.Enclosure = &rssEnclosure{URL: enclosures[0].Href, Type: enclosures[0].Type}
+		}
+		document.Channel.Items = append(document.Channel.Items, item)
+	}
+	return marshal(document)
+}
+
+// This method generates the feed as Atom 1.0
+// ctx - The context of every request
+func (feed *Feed) Atom(ctx context.Context) ([]byte, error) {
+	posts, err := feed.Posts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return feed.marshalAtom(posts, time.Now())
+}
+
+func (feed *Feed) marshalAtom(posts []tumblr.Post, now time.Time) ([]byte, error) {
+	document := atomFeed{
+		Title:   feed.Title,
+		ID:      feed.Link,
+		Updated: now.UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Href: feed.Link, Rel: "alternate", Type: "text/html"}},
+		Author:  atomPerson{Name: feed.Title},
+	}
+	if feed.Self != "" {
+		document.ID = feed.Self
+		document.Links = append(document.Links, atomLink{Href: feed.Self, Rel: "self", Type: "application/atom+xml"})
+	}
+	if len(posts) > 0 {
+		document.Updated = postTime(posts[0]).Format(time.RFC3339)
+	}
+	for _, post := range posts {
+		published := postTime(post).Format(time.RFC3339)
+		entry := atomEntry{
+			Title:     title(post),
+			ID:        guid(post),
+			Updated:   published,
+			Published: published,
+			Author:    atomPerson{Name: post.BlogName, URI: "https://" + post.BlogName + ".tumblr.com/"},
+			Content:   atomContent{Type: "html", Body: content(post)},
+		}
+		if post.PostURL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: post.PostURL, Rel: "alternate", Type: "text/html"})
+		}
+		entry.Links = append(entry.Links, enclosures(post)...)
+		for _, tag := range post.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		document.Entries = append(document.Entries, entry)
+	}
+	return marshal(document)
+}
+
+// This method encodes a document with the XML declaration
+func marshal(document interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// This method returns when a post was published
+func postTime(post tumblr.Post) time.Time {
+	return time.Unix(int64(post.Timestamp), 0).UTC()
+}
+
+// This method returns the unique, permanent ID of a post: its URL, or a tag URI
+// when it has none
+func guid(post tumblr.Post) string {
+	if post.PostURL != "" {
+		return post.PostURL
+	}
+	return "tag:tumblr.com,2007:" + post.BlogName + "/" + strconv.Itoa(post.ID)
+}
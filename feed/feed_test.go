@@ -0,0 +1,186 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/tumblrtest"
+)
+
+const staffPosts = `[
+	{"id":3,"blog_name":"staff","type":"photo","timestamp":300,"post_url":"https://staff.tumblr.com/post/3","tags":["art"],"summary":"A  sunset",
+		"caption":"<p>Sunset</p>","photos":[{"original_size":{"url":"https://64.media.tumblr.com/3/photo.png"}}]},
+	{"id":1,"blog_name":"staff","type":"quote","timestamp":100,"post_url":"https://staff.tumblr.com/post/1","text":"Be <b>bold</b>","source":"Someone"}
+]`
+
+func testClient(t *testing.T) *tumblr.Tumblr {
+	_, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/v2/blog/staff.tumblr.com/posts" && query.Get("offset") == "0":
+			if query.Get("tag") != "art" || query.Get("type") != "" {
+				t.Errorf("Blog posts were requested with %v", query)
+			}
+			fmt.Fprintf(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":%s}}`, staffPosts)
+		case r.URL.Path == "/v2/blog/staff.tumblr.com/posts":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[]}}`)
+		case r.URL.Path == "/v2/tagged" && query.Get("before") == "":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":[
+				{"id":3,"blog_name":"staff","type":"photo","timestamp":300,"post_url":"https://staff.tumblr.com/post/3"},
+				{"id":2,"blog_name":"david","type":"chat","timestamp":200,"post_url":"https://david.tumblr.com/post/2","title":"Talk",
+					"dialogue":[{"label":"A:","phrase":"<hi>"}]}]}`)
+		case r.URL.Path == "/v2/tagged":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":[]}`)
+		case r.URL.Path == "/v2/user/likes":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"meta":{"status":401,"msg":"Unauthorized"},"response":[]}`)
+		default:
+			t.Errorf("Requested %s", r.URL)
+		}
+	}))
+	client.SetMaxRetries(0)
+	return client
+}
+
+func TestRSS(t *testing.T) {
+	feed := New(testClient(t), Blog("staff.tumblr.com", "art", ""), Tagged("art"))
+	if feed.Title != "staff.tumblr.com #art, #art on Tumblr" || feed.Link != "https://staff.tumblr.com/tagged/art" {
+		t.Errorf("Feed is titled %q with link %q", feed.Title, feed.Link)
+	}
+	data, err := feed.RSS(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document rss
+	if err := xml.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Invalid XML %s: %v", data, err)
+	}
+	items := document.Channel.Items
+	if document.Version != "2.0" || len(items) != 3 {
+		t.Fatalf("Feed has %d items: %s", len(items), data)
+	}
+	// Merged newest first, the post of both sources once
+	photo, chat, quote := items[0], items[1], items[2]
+	if photo.GUID.Value != "https://staff.tumblr.com/post/3" || !photo.GUID.IsPermaLink || photo.Title != "A sunset" ||
+		photo.PubDate != "Thu, 01 Jan 1970 00:05:00 +0000" || len(photo.Categories) != 1 {
+		t.Errorf("Photo item is %+v", photo)
+	}
+	if photo.Enclosure == nil || photo.Enclosure.URL != "https://64.media.tumblr.com/3/photo.png" || photo.Enclosure.Type != "image/png" ||
+		!strings.Contains(photo.Description, `<img src="https://64.media.tumblr.com/3/photo.png"`) {
+		t.Errorf("Photo item has enclosure %+v and description %q", photo.Enclosure, photo.Description)
+	}
+	if chat.Title != "Talk" || chat.Description != "<p><strong>A:</strong> &lt;hi&gt;</p>" {
+		t.Errorf("Chat item is %+v", chat)
+	}
+	if quote.Title != "Quote post" || quote.Description != "<blockquote>Be <b>bold</b></blockquote><p>— Someone</p>" {
+		t.Errorf("Quote item is %+v", quote)
+	}
+
+	feed.Limit = 1
+	if posts, err := feed.Posts(context.Background()); err != nil || len(posts) != 1 {
+		t.Errorf("Limited feed has %d posts with error %v", len(posts), err)
+	}
+	if _, err := New(feed.api, Likes()).RSS(context.Background()); err == nil {
+		t.Error("Failed source made a feed")
+	}
+}
+
+func TestContent(t *testing.T) {
+	for data, want := range map[string]string{
+		`{"id":1,"type":"audio","caption":"<p>Listen</p>","player":"<iframe src=\"https://w.soundcloud.com/player/\"></iframe>"}`: `<iframe src="https://w.soundcloud.com/player/"></iframe><p>Listen</p>`,
+		`{"id":2,"type":"video","caption":"<p>Watch</p>","player":[{"width":250,"embed_code":"<iframe width=\"250\"></iframe>"},
+			{"width":500,"embed_code":"<iframe width=\"500\"></iframe>"}]}`: `<iframe width="500"></iframe><p>Watch</p>`,
+	} {
+		var post tumblr.Post
+		if err := json.Unmarshal([]byte(data), &post); err != nil {
+			t.Fatal(err)
+		}
+		if got := content(post); got != want {
+			t.Errorf("Post %d has content %q, want %q", post.ID, got, want)
+		}
+	}
+}
+
+func TestAtom(t *testing.T) {
+	feed := New(testClient(t), Tagged("art"))
+	feed.Self = "http://localhost/tagged/art.atom"
+	data, err := feed.marshalAtom(nil, time.Unix(1000, 0))
+	if err != nil || !strings.Contains(string(data), "<updated>1970-01-01T00:16:40Z</updated>") {
+		t.Errorf("Empty feed is %s with error %v", data, err)
+	}
+	if data, err = feed.Atom(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var document atomFeed
+	if err := xml.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Invalid XML %s: %v", data, err)
+	}
+	if document.XMLName.Space != "http://www.w3.org/2005/Atom" || document.ID != feed.Self || len(document.Entries) != 2 {
+		t.Fatalf("Feed is %s", data)
+	}
+	entry := document.Entries[0]
+	if entry.ID != "https://staff.tumblr.com/post/3" || entry.Published != "1970-01-01T00:05:00Z" || entry.Author.Name != "staff" ||
+		entry.Content.Type != "html" || entry.Links[0].Href != entry.ID {
+		t.Errorf("Entry is %+v", entry)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(Handler(testClient(t)))
+	defer server.Close()
+	for path, want := range map[string]string{
+		"/blog/staff.tumblr.com?tag=art": "application/rss+xml",
+		"/tagged/art.atom?limit=1":       "application/atom+xml",
+		"/likes.rss":                     "502",
+		"/blog/,":                        "404",
+	} {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		got := response.Header.Get("Content-Type")
+		if response.StatusCode != http.StatusOK {
+			got = fmt.Sprint(response.StatusCode)
+		}
+		if !strings.HasPrefix(got, want) {
+			t.Errorf("%s answered %s: %s", path, got, body)
+		}
+		if path == "/blog/staff.tumblr.com?tag=art" && !strings.Contains(string(body), `<atom:link href="`+server.URL+`/blog/staff.tumblr.com?tag=art" rel="self"`) {
+			t.Errorf("%s answered %s", path, body)
+		}
+		if path == "/tagged/art.atom?limit=1" && (strings.Count(string(body), "<entry>") != 1 ||
+			!strings.Contains(string(body), `<link href="`+server.URL+path+`" rel="self"`)) {
+			t.Errorf("%s answered %s", path, body)
+		}
+	}
+}
+
+func TestPostsRetried(t *testing.T) {
+	attempts := 0
+	_, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts++; attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"meta":{"status":429,"msg":"Limit Exceeded"},"response":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[]}}`)
+	}))
+
+	// A request that succeeds once retried doesn't fail the feed
+	posts, err := New(client, Blog("staff.tumblr.com", "", "")).Posts(context.Background())
+	if err != nil || len(posts) != 0 || attempts != 2 {
+		t.Errorf("Posts returned %d posts with error %v after %d attempts", len(posts), err, attempts)
+	}
+}
@@ -0,0 +1,163 @@
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"html"
+	"strings"
+	"time"
+)
+
+// An item of an RSS or Atom feed
+type Item struct {
+	GUID       string    `json:"guid"`                 // The item's unique ID: its guid or id, else its link
+	Title      string    `json:"title"`                // The item's title
+	Link       string    `json:"link"`                 // The URL of the item's page
+	Summary    string    `json:"summary,omitempty"`    // The item's description or summary, as HTML
+	Content    string    `json:"content,omitempty"`    // The item's full content as HTML, else its summary
+	Author     string    `json:"author,omitempty"`     // The item's author
+	Published  time.Time `json:"published,omitzero"`   // When the item was published
+	Categories []string  `json:"categories,omitempty"` // The item's categories
+}
+
+// Returned by Parse for documents that aren't RSS or Atom
+var ErrUnknownFormat = errors.New("feed: not an RSS or Atom feed")
+
+// The date formats of RSS, whose feeds are lax about RFC 822
+var rssDateFormats = []string{time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700", time.RFC3339}
+
+// The elements of the feeds read by Parse
+type parsedRSS struct {
+	Items []struct {
+		Title       string   `xml:"title"`
+		Link        string   `xml:"link"`
+		Description string   `xml:"description"`
+		Encoded     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		GUID        string   `xml:"guid"`
+		PubDate     string   `xml:"pubDate"`
+		Author      string   `xml:"author"`
+		Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Categories  []string `xml:"category"`
+	} `xml:"channel>item"`
+}
+
+type parsedAtom struct {
+	Entries []struct {
+		Title     parsedText `xml:"title"`
+		ID        string     `xml:"id"`
+		Links     []atomLink `xml:"link"`
+		Summary   parsedText `xml:"summary"`
+		Content   parsedText `xml:"content"`
+		Published string     `xml:"published"`
+		Updated   string     `xml:"updated"`
+		Authors   []struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Categories []atomCategory `xml:"category"`
+	} `xml:"entry"`
+}
+
+// An Atom text construct, plain text, HTML or XHTML
+type parsedText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// This method returns a text construct as HTML
+func (text parsedText) html() string {
+	switch text.Type {
+	case "xhtml":
+		return strings.TrimSpace(text.Inner)
+	case "html":
+		return strings.TrimSpace(text.Text)
+	}
+	return strings.TrimSpace(html.EscapeString(text.Text))
+}
+
+// This method parses an RSS 2.0 or Atom 1.0 feed, returning its items in the
+// order of the document
+// data - The document
+func Parse(data []byte) ([]Item, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	var items []Item
+	switch root.XMLName.Local {
+	case "rss":
+		var document parsedRSS
+		if err := xml.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		for _, parsed := range document.Items {
+			item := Item{
+				GUID:       strings.TrimSpace(parsed.GUID),
+				Title:      strings.TrimSpace(parsed.Title),
+				Link:       strings.TrimSpace(parsed.Link),
+				Summary:    strings.TrimSpace(parsed.Description),
+				Content:    strings.TrimSpace(parsed.Encoded),
+				Author:     strings.TrimSpace(parsed.Creator),
+				Categories: parsed.Categories,
+			}
+			if item.Author == "" {
+				item.Author = strings.TrimSpace(parsed.Author)
+			}
+			for _, format := range rssDateFormats {
+				if published, err := time.Parse(format, strings.TrimSpace(parsed.PubDate)); err == nil {
+					item.Published = published.UTC()
+					break
+				}
+			}
+			items = append(items, item)
+		}
+	case "feed":
+		var document parsedAtom
+		if err := xml.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		for _, parsed := range document.Entries {
+			item := Item{
+				GUID:    strings.TrimSpace(parsed.ID),
+				Title:   strings.TrimSpace(parsed.Title.Text),
+				Summary: parsed.Summary.html(),
+				Content: parsed.Content.html(),
+			}
+			for _, link := range parsed.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					item.Link = link.Href
+					break
+				}
+			}
+			if len(parsed.Authors) > 0 {
+				item.Author = parsed.Authors[0].Name
+			}
+			for _, category := range parsed.Categories {
+				item.Categories = append(item.Categories, category.Term)
+			}
+			published := parsed.Published
+			if published == "" {
+				published = parsed.Updated
+			}
+			if published, err := time.Parse(time.RFC3339, strings.TrimSpace(published)); err == nil {
+				item.Published = published.UTC()
+			}
+			items = append(items, item)
+		}
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	for i := range items {
+		if items[i].GUID == "" {
+			items[i].GUID = items[i].Link
+		}
+		if items[i].Content == "" {
+			items[i].Content = items[i].Summary
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,93 @@
+package feed
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+)
+
+// The most items a feed served by Handler has
+const maxLimit = 100
+
+// This method returns an HTTP handler serving feeds, as RSS 2.0, or as Atom 1.0
+// when the path ends in .atom:
+//
+//	/blog/<blog>[,<blog>...][.rss|.atom]   the posts of one or more blogs, with optional tag and type parameters
+//	/tagged/<tag>[.rss|.atom]              the posts tagged with a tag across Tumblr
+//	/likes[.rss|.atom]                     the posts the user likes
+//
+// Every feed takes a limit parameter, the number of items, up to 100. A feed whose
+// posts can't be fetched is answered with 502 Bad Gateway.
+func Handler(api *tumblr.Tumblr) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /blog/{blogs}", func(w http.ResponseWriter, r *http.Request) {
+		blogs, format := splitFormat(r.PathValue("blogs"))
+		var sources []Source
+		for _, blog := range strings.Split(blogs, ",") {
+			if blog != "" {
+				sources = append(sources, Blog(blog, r.URL.Query().Get("tag"), r.URL.Query().Get("type")))
+			}
+		}
+		serveFeed(w, r, New(api, sources...), format)
+	})
+	mux.HandleFunc("GET /tagged/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		tag, format := splitFormat(r.PathValue("tag"))
+		serveFeed(w, r, New(api, Tagged(tag)), format)
+	})
+	for _, path := range []string{"/likes", "/likes.rss", "/likes.atom"} {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			_, format := splitFormat(r.URL.Path)
+			serveFeed(w, r, New(api, Likes()), format)
+		})
+	}
+	return mux
+}
+
+// This method splits the format extension off the last segment of a path,
+// returning the format, rss when there is none
+func splitFormat(name string) (string, string) {
+	for _, format := range []string{"rss", "atom"} {
+		if trimmed, found := strings.CutSuffix(name, "."+format); found {
+			return trimmed, format
+		}
+	}
+	return name, "rss"
+}
+
+// This method answers a request with a feed
+func serveFeed(w http.ResponseWriter, r *http.Request, feed *Feed, format string) {
+	if len(feed.Sources) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		feed.Limit = min(limit, maxLimit)
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	feed.Self = scheme + "://" + r.Host + r.URL.RequestURI()
+
+	posts, err := feed.Posts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	var data []byte
+	if format == "atom" {
+		data, err = feed.marshalAtom(posts, time.Now())
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	} else {
+		data, err = feed.marshalRSS(posts)
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
@@ -1,104 +1,416 @@
 package tumblr
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
+const (
+	defaultMaxResponseSize = 32 << 20         // default limit on the size of a response body, see SetMaxResponseSize
+	defaultMaxRetries      = 3                // default number of retries of a rate limited request, see SetMaxRetries
+	maxRetryDelay          = 30 * time.Second // longest backoff between retries without a Retry-After header
+)
+
+// Returned when a response body is larger than the client's maximum response size
+var ErrResponseTooLarge = errors.New("tumblr: response body exceeds the maximum response size")
+
+// Returned when a middleware returns neither a response nor an error
+var errNoResponse = errors.New("tumblr: middleware returned no response")
+
+// Returned when Tumblr responds with an error status
+type APIError struct {
+	Meta Meta // The meta object of the response
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("tumblr: response status %d with %s", e.Meta.Status, e.Meta.Msg)
+}
+
+// This method returns the transport shared by every request of a client. Connections
+// are kept alive and pooled per host, HTTP/2 is negotiated when the server offers it,
+// and "Accept-Encoding: gzip" is sent with responses transparently decompressed.
+func newTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// This method returns the HTTP client created by New
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: newTransport(),
+		Timeout:   60 * time.Second,
+	}
+}
+
+// A reader that fails with ErrResponseTooLarge once more than max bytes are read
+type maxBytesReader struct {
+	reader io.Reader
+	max    int64
+	read   int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.max {
+		return n - int(r.read-r.max), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// This method wraps a response body so that reading it is bounded by the
+// client's maximum response size
+// body - The response body
+func (api *Tumblr) limit(body io.Reader) io.Reader {
+	return &maxBytesReader{reader: body, max: api.maxResponseSize}
+}
+
+// This method discards what is left of a response body and closes it, so the
+// connection can go back to the pool
+// body - The response body
+func closeBody(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, 4<<10))
+	body.Close()
+}
+
+// This method names the API endpoint of a request URL, e.g. blog/info, user/dashboard
+// or blog/posts/{id}, leaving out the blog hostname and other variable parts
+// requestURL - The request URL
+func endpoint(requestURL *url.URL) string {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(requestURL.Path, "/v2"), "/"), "/")
+	switch {
+	case segments[0] == "blog" && len(segments) > 2:
+		name := "blog/" + segments[2]
+		for _, segment := range segments[3:] {
+			if _, err := strconv.Atoi(segment); err != nil {
+				name += "/" + segment
+			} else if segments[2] != "avatar" {
+				name += "/{id}"
+			}
+		}
+		return name
+	case segments[0] == "user" && len(segments) > 1:
+		return "user/" + segments[1]
+	}
+	return segments[0]
+}
+
+// This method returns the blog hostname of a request URL, or "" for non-blog requests
+// requestURL - The request URL
+func blogHostname(requestURL *url.URL) string {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(requestURL.Path, "/v2"), "/"), "/")
+	if len(segments) > 1 && segments[0] == "blog" {
+		return segments[1]
+	}
+	return ""
+}
+
+// This method opens the body of a response, serving GET requests from the cache
+// when one is set and storing cacheable responses in it
+// request - The request to send
+func (api *Tumblr) open(request *http.Request) (io.ReadCloser, error) {
+	ttl := api.cacheTTLs[endpoint(request.URL)]
+	if api.cache == nil || request.Method != "GET" || ttl <= 0 {
+		clientResponse, err := api.do(request)
+		if err != nil {
+			return nil, err
+		}
+		if request.Method != "GET" && clientResponse.StatusCode < 300 && api.cache != nil {
+			api.invalidate(request.URL)
+		}
+		return clientResponse.Body, nil
+	}
+
+	key := cacheKey(request.URL, api.identity())
+	entry, found := api.cache.Get(key)
+	if found && time.Now().Before(entry.Expires) {
+		api.metrics.ObserveCache(endpoint(request.URL), true)
+		requestSpan(request).SetAttributes(Attribute{Key: "tumblr.cache_hit", Value: true})
+		return ioutil.NopCloser(bytes.NewReader(entry.Body)), nil
+	}
+	if found && entry.ETag != "" {
+		request.Header.Set("If-None-Match", entry.ETag)
+	}
+	if found && entry.LastModified != "" {
+		request.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+
+	clientResponse, err := api.do(request)
+	if err != nil {
+		return nil, err
+	}
+	revalidated := found && clientResponse.StatusCode == http.StatusNotModified
+	api.metrics.ObserveCache(endpoint(request.URL), revalidated)
+	if revalidated {
+		closeBody(clientResponse.Body)
+		entry.Expires = time.Now().Add(ttl)
+		api.cache.Set(key, entry)
+		return ioutil.NopCloser(bytes.NewReader(entry.Body)), nil
+	}
+	if clientResponse.StatusCode != http.StatusOK {
+		return clientResponse.Body, nil
+	}
+
+	defer closeBody(clientResponse.Body)
+	body, err := io.ReadAll(api.limit(clientResponse.Body))
+	if err != nil {
+		return nil, err
+	}
+	api.cache.Set(key, CacheEntry{
+		Body:         body,
+		ETag:         clientResponse.Header.Get("ETag"),
+		LastModified: clientResponse.Header.Get("Last-Modified"),
+		Expires:      time.Now().Add(ttl),
+	})
+	return ioutil.NopCloser(bytes.NewReader(body)), nil
+}
+
+// This method removes a blog's cached posts and info, whose post count changes,
+// after a request that changes them
+// requestURL - The URL of the successful write request
+func (api *Tumblr) invalidate(requestURL *url.URL) {
+	name := endpoint(requestURL)
+	if strings.HasPrefix(name, "blog/post") {
+		blog := requestURL.Host + "/v2/blog/" + canonicalBlog(blogHostname(requestURL))
+		api.cache.DeletePrefix(blog + "/posts")
+		api.cache.DeletePrefix(blog + "/info")
+	}
+}
+
+// This method sends a request through the middleware chain, waiting for the rate
+// limiter and retrying when Tumblr responds 429 Too Many Requests
+// request - The request to send
+func (api *Tumblr) do(request *http.Request) (*http.Response, error) {
+	roundTrip := api.roundTrip()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request.Body = body
+		}
+		if err := api.limiter.wait(request.Context()); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		response, err := roundTrip(request)
+		if err == nil && response == nil {
+			err = errNoResponse
+		}
+		latency := time.Since(start)
+		api.logAttempt(request, response, err, attempt, latency)
+		if err != nil {
+			api.metrics.ObserveRequest(endpoint(request.URL), 0, latency)
+			requestSpan(request).SetAttributes(Attribute{Key: "tumblr.attempts", Value: attempt + 1})
+			return response, err
+		}
+		api.metrics.ObserveRequest(endpoint(request.URL), response.StatusCode, latency)
+		api.observeRateLimits(response.Header)
+		if response.StatusCode != http.StatusTooManyRequests || attempt >= api.maxRetries {
+			requestSpan(request).SetAttributes(
+				Attribute{Key: "http.status_code", Value: response.StatusCode},
+				Attribute{Key: "tumblr.attempts", Value: attempt + 1},
+			)
+			return response, err
+		}
+		api.metrics.IncRetries(endpoint(request.URL))
+		delay := retryDelay(response, attempt)
+		closeBody(response.Body)
+		if err := sleep(request.Context(), delay); err != nil {
+			requestSpan(request).SetAttributes(Attribute{Key: "tumblr.attempts", Value: attempt + 1})
+			return nil, err
+		}
+	}
+}
+
+// This method returns how long to wait before retrying a rate limited request,
+// honoring the Retry-After header when present
+// response - The 429 response
+// attempt - The number of the attempt that was rate limited, starting at 0
+func retryDelay(response *http.Response, attempt int) time.Duration {
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return time.Until(date)
+		}
+	}
+	delay := time.Second << uint(attempt)
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
 // This method GET requests a URL and unmarshals it based on a specified blank struct
-// url - The GET URL
+// requestURL - The GET URL
 // responseObject - A pointer to the blank struct type
-func (api Tumblr) info(url string, responseObject interface{}) {
-	response := api.get(url)
+func (api *Tumblr) info(requestURL string, responseObject interface{}) error {
+	response, err := api.get(requestURL)
+	if err != nil {
+		return err
+	}
 	if response.Meta.Status != 200 {
-		log.Println(fmt.Sprintf("http get error: response status %d with %s",
-			response.Meta.Status, response.Meta.Msg))
+		err := &APIError{Meta: response.Meta}
+		recordFailure(api.context(), err)
+		return err
 	}
 
-	err := json.Unmarshal(response.Response, &responseObject)
+	err = json.Unmarshal(response.Response, &responseObject)
+	if err == nil && api.preserveRaw {
+		fillRaw(reflect.ValueOf(responseObject), response.Response)
+	}
 	if err != nil {
-		//log.Println(string(response.Response))
 		// Looks like sometimes source_title is being returned as "false"
 		// and marshaller freaks because it should be a string.
-		//log.Println(err)
-		log.Println("Gumblr marshalling failure.")
+		parsedURL, _ := url.Parse(requestURL)
+		api.logger.Warn("tumblr response could not be unmarshalled",
+			"endpoint", endpoint(parsedURL), "blog", blogHostname(parsedURL), "error", err)
+		recordFailure(api.context(), err)
 	}
+	return err
 }
 
 // This method GET requests only returning the []byte found
-// url - The GET URL
-func (api Tumblr) rawGet(url string) []byte {
-	request, err := http.NewRequest("GET", url, nil)
-
+// requestURL - The GET URL
+func (api *Tumblr) rawGet(requestURL string) []byte {
+	request, err := api.newRequest("GET", requestURL, nil)
 	if err != nil {
-		log.Println(err)
+		return []byte{0}
 	}
+	request, span := api.startSpan(request)
+	defer func() {
+		recordFailure(request.Context(), err)
+		endSpan(request, span, err)
+	}()
 
-	api.oauthService.Sign(request, &api.config)
-	client := new(http.Client)
-	clientResponse, err := client.Do(request)
-
+	responseBody, err := api.open(request)
 	if err != nil {
-		log.Println(err)
 		return []byte{0}
 	}
-	defer clientResponse.Body.Close()
+	defer closeBody(responseBody)
 
-	body, err := ioutil.ReadAll(clientResponse.Body)
+	body, err := io.ReadAll(api.limit(responseBody))
 	if err != nil {
-		log.Println(err)
+		api.logResponseError(request, err)
 	}
 
 	return body
 }
 
 // This method GET requests a URL
-// url - The GET URL
-func (api Tumblr) get(url string) Response {
-	body := api.rawGet(url)
+// requestURL - The GET URL
+func (api *Tumblr) get(requestURL string) (Response, error) {
+	request, err := api.newRequest("GET", requestURL, nil)
+	if err != nil {
+		return Response{}, err
+	}
+	return api.send(request)
+}
 
-	var response Response
-	err := json.Unmarshal(body, &response)
+// This method POSTs to a URL
+// requestURL - The URL to post to
+// params - A string of the encoded parameters
+func (api *Tumblr) post(requestURL string, params string) Response {
+	request, err := api.newRequest("POST", requestURL, strings.NewReader(params))
 	if err != nil {
-		log.Println(err)
+		return Response{}
 	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, _ := api.send(request)
 	return response
 }
 
-// This method POSTs to a URL
-// url - The URL to post to
+// This method sends a DELETE request to a URL
+// requestURL - The URL to send the request to
 // params - A string of the encoded parameters
-func (api Tumblr) post(url string, params string) Response {
-	request, err := http.NewRequest("POST", url, strings.NewReader(params))
+func (api *Tumblr) delete(requestURL string, params string) Response {
+	if params != "" {
+		requestURL = requestURL + "?" + params
+	}
+	request, err := api.newRequest("DELETE", requestURL, nil)
+	if err != nil {
+		return Response{}
+	}
 
+	response, _ := api.send(request)
+	return response
+}
+
+// This method PUTs a JSON body to a URL
+// requestURL - The URL to send the request to
+// body - The JSON encoded request body
+func (api *Tumblr) put(requestURL string, body []byte) Response {
+	request, err := api.newRequest("PUT", requestURL, bytes.NewReader(body))
 	if err != nil {
-		log.Println(err)
+		return Response{}
 	}
+	request.Header.Set("Content-Type", "application/json")
 
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, _ := api.send(request)
+	return response
+}
 
-	api.oauthService.Sign(request, &api.config)
-	client := new(http.Client)
-	clientResponse, err := client.Do(request)
+// This method sends a request and decodes the response envelope straight from
+// the response body, or from the cache
+// request - The request to send
+func (api *Tumblr) send(request *http.Request) (response Response, err error) {
+	request, span := api.startSpan(request)
+	defer func() {
+		failure := err
+		if failure == nil && response.Meta.Status >= 400 {
+			failure = &APIError{Meta: response.Meta}
+		}
+		recordFailure(request.Context(), failure)
+		endSpan(request, span, failure)
+	}()
 
+	body, err := api.open(request)
 	if err != nil {
-		log.Println(err)
+		return response, err
 	}
-	defer clientResponse.Body.Close()
+	defer closeBody(body)
 
-	body, err := ioutil.ReadAll(clientResponse.Body)
+	err = json.NewDecoder(api.limit(body)).Decode(&response)
 	if err != nil {
-		log.Println(err)
+		api.logResponseError(request, err)
 	}
+	return response, err
+}
 
-	var response Response
-	err = json.Unmarshal(body, &response)
+// This method creates a request, logging the error if it can't be created
+// method - The HTTP method
+// requestURL - The request URL
+// body - The request body, or nil
+func (api *Tumblr) newRequest(method string, requestURL string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(api.context(), method, requestURL, body)
 	if err != nil {
-		log.Println(err)
+		api.logger.Error("tumblr request could not be created", "method", method, "error", redactError(err))
 	}
-	return response
+	return request, err
 }
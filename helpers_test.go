@@ -0,0 +1,197 @@
+package tumblr
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testBlogInfo = `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff","title":"Tumblr Staff"}}}`
+
+// A transport that sends every request to a local test server
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.URL.Scheme = t.target.Scheme
+	request.URL.Host = t.target.Host
+	return t.next.RoundTrip(request)
+}
+
+// This method returns a transport trusting the test server's certificate
+func testTransport(server *httptest.Server, transport *http.Transport) http.RoundTripper {
+	target, _ := url.Parse(server.URL)
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	return redirectTransport{target: target, next: transport}
+}
+
+// This method returns a client whose requests are sent to the test server
+func newTestClient(server *httptest.Server) *Tumblr {
+	client := New("consumer-key", "consumer-secret", "oauth-key", "oauth-secret")
+	client.SetHTTPClient(&http.Client{Transport: testTransport(server, newTransport())})
+	return client
+}
+
+// This method starts a TLS test server, with HTTP/2 enabled when http2 is set
+func newTestServer(http2 bool, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = http2
+	server.StartTLS()
+	return server
+}
+
+func TestSharedTransport(t *testing.T) {
+	var mutex sync.Mutex
+	remoteAddrs := make(map[string]bool)
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		remoteAddrs[r.RemoteAddr] = true
+		mutex.Unlock()
+		if r.ProtoMajor != 2 {
+			t.Errorf("Request was sent over %s instead of HTTP/2", r.Proto)
+		}
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Error("Request did not accept gzip encoding")
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "OAuth ") {
+			t.Error("Request was not signed")
+		}
+		fmt.Fprint(w, testBlogInfo)
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	for i := 0; i < 10; i++ {
+		if blogInfo := client.BlogInfo("staff.tumblr.com"); blogInfo.Blog.Name != "staff" {
+			t.Fatalf("Incorrect blog name %q returned", blogInfo.Blog.Name)
+		}
+	}
+	if len(remoteAddrs) != 1 {
+		t.Errorf("Requests used %d connections instead of 1", len(remoteAddrs))
+	}
+}
+
+func TestGzipResponse(t *testing.T) {
+	server := newTestServer(false, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		fmt.Fprint(writer, testBlogInfo)
+		writer.Close()
+	})
+	defer server.Close()
+
+	blogInfo := newTestClient(server).BlogInfo("staff.tumblr.com")
+	if blogInfo.Blog.Title != "Tumblr Staff" {
+		t.Errorf("Gzip encoded response was not decoded, title returned %q", blogInfo.Blog.Title)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	server := newTestServer(false, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testBlogInfo)
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetMaxResponseSize(int64(len(testBlogInfo)))
+	if _, err := client.get(apiBlogUrl + "staff.tumblr.com/info"); err != nil {
+		t.Errorf("Response at the size limit failed: %s", err)
+	}
+
+	client.SetMaxResponseSize(int64(len(testBlogInfo) - 1))
+	if _, err := client.get(apiBlogUrl + "staff.tumblr.com/info"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Oversized response returned %v instead of ErrResponseTooLarge", err)
+	}
+	if avatar := client.rawGet(apiBlogUrl + "staff.tumblr.com/avatar/64"); len(avatar) > len(testBlogInfo)-1 {
+		t.Errorf("Oversized raw response returned %d bytes", len(avatar))
+	}
+}
+
+// A dashboard sized response for the benchmarks
+func benchmarkResponse() []byte {
+	var posts []Post
+	for i := 0; i < 20; i++ {
+		posts = append(posts, Post{
+			BlogName: "staff",
+			ID:       i,
+			Type:     "text",
+			Tags:     []string{"tumblr", "staff", "benchmark"},
+			Title:    "Benchmark post",
+			Body:     strings.Repeat("<p>Lorem ipsum dolor sit amet.</p>", 50),
+		})
+	}
+	response, _ := json.Marshal(map[string]interface{}{
+		"meta":     Meta{Status: 200, Msg: "OK"},
+		"response": BlogList{Posts: posts},
+	})
+	return response
+}
+
+func benchmarkServer(http2 bool) *httptest.Server {
+	response := benchmarkResponse()
+	return newTestServer(http2, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(response)
+	})
+}
+
+// The request path before the shared transport: a new client per request, whose
+// connection isn't reused, so every request pays for a new connection and TLS
+// handshake, reading the whole body before unmarshalling.
+func benchmarkPerRequestClient(b *testing.B, http2 bool) {
+	server := benchmarkServer(http2)
+	defer server.Close()
+	api := New("consumer-key", "consumer-secret", "oauth-key", "oauth-secret")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			request, _ := http.NewRequest("GET", apiUserUrl+"dashboard", nil)
+			api.oauthService.Sign(request, &api.config)
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.DisableKeepAlives = true
+			client := &http.Client{Transport: testTransport(server, transport)}
+			clientResponse, err := client.Do(request)
+			if err != nil {
+				b.Fatal(err)
+			}
+			body, _ := ioutil.ReadAll(clientResponse.Body)
+			clientResponse.Body.Close()
+			transport.CloseIdleConnections()
+			var response Response
+			json.Unmarshal(body, &response)
+			var dashboard BlogList
+			json.Unmarshal(response.Response, &dashboard)
+		}
+	})
+}
+
+func benchmarkSharedTransport(b *testing.B, http2 bool) {
+	server := benchmarkServer(http2)
+	defer server.Close()
+	api := newTestClient(server)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			api.UserDashboard(nil)
+		}
+	})
+}
+
+func BenchmarkPerRequestClientHTTP1(b *testing.B) { benchmarkPerRequestClient(b, false) }
+func BenchmarkSharedTransportHTTP1(b *testing.B)  { benchmarkSharedTransport(b, false) }
+func BenchmarkPerRequestClientHTTP2(b *testing.B) { benchmarkPerRequestClient(b, true) }
+func BenchmarkSharedTransportHTTP2(b *testing.B)  { benchmarkSharedTransport(b, true) }
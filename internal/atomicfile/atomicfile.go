@@ -0,0 +1,32 @@
+// Package atomicfile writes files through a temporary file renamed into place, so
+// an interrupted write never leaves a truncated file behind.
+package atomicfile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// This method writes a file, creating its directory if needed. The file is
+// replaced only once the whole content was written.
+// path - The file
+// content - What the file holds
+func Write(path string, content io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := io.Copy(temp, content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,46 @@
+// Package tumblrtest serves the Tumblr API from a local test server, for the tests
+// of the packages built on the client.
+package tumblrtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	tumblr "github.com/mattcunningham/gumblr"
+)
+
+// A transport sending every request to a test server, whatever its URL, so the
+// requests the client makes to api.tumblr.com and media hosts reach the server
+type Transport struct {
+	Server *url.URL          // The URL of the test server
+	Next   http.RoundTripper // Sends the redirected requests
+}
+
+func (t Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.URL.Scheme = t.Server.Scheme
+	request.URL.Host = t.Server.Host
+	return t.Next.RoundTrip(request)
+}
+
+// This method returns an HTTP client sending every request to a test server,
+// trusting its certificate when it serves TLS
+// server - The test server
+func HTTPClient(server *httptest.Server) *http.Client {
+	serverURL, _ := url.Parse(server.URL)
+	return &http.Client{Transport: Transport{Server: serverURL, Next: server.Client().Transport}}
+}
+
+// This method starts a test server, closed when the test ends, and returns it
+// with a client authorized by an api key whose requests are sent to it
+// t - The test
+// handler - Answers the requests
+func NewServer(t testing.TB, handler http.Handler) (*httptest.Server, *tumblr.Tumblr) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := tumblr.New("consumer-key", "", "", "")
+	client.SetHTTPClient(HTTPClient(server))
+	return server, client
+}
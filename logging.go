@@ -0,0 +1,149 @@
+package tumblr
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The value logged in place of a secret
+const redacted = "REDACTED"
+
+// Matches the values of the OAuth parameters of an Authorization header
+var oauthParam = regexp.MustCompile(`(oauth_[a-z_]+)="[^"]*"`)
+
+// OAuth parameters that aren't secret and are kept in logs
+var publicOAuthParams = map[string]bool{
+	"oauth_signature_method": true,
+	"oauth_timestamp":        true,
+	"oauth_nonce":            true,
+	"oauth_version":          true,
+}
+
+// This method sets the logger requests and failures are logged to. Every request
+// attempt is logged with its method, endpoint, blog, redacted URL, status, latency
+// and attempt number: at debug level when it succeeds, at warn level for an error
+// status and at error level when no response was received. The api key, OAuth
+// tokens and signatures are never logged. By default nothing is logged.
+// logger - The logger to use, nil disables logging
+func (api *Tumblr) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	api.logger = logger
+}
+
+// This method logs one attempt of a request
+// request - The request sent
+// response - The response received, nil when err is set
+// err - The error that prevented a response
+// attempt - The number of the attempt, starting at 0
+// latency - How long the attempt took
+func (api *Tumblr) logAttempt(request *http.Request, response *http.Response, err error, attempt int, latency time.Duration) {
+	attrs := []slog.Attr{
+		slog.String("method", request.Method),
+		slog.String("endpoint", endpoint(request.URL)),
+		slog.String("blog", blogHostname(request.URL)),
+		slog.String("url", redactURL(request.URL)),
+		slog.Int("attempt", attempt+1),
+		slog.Duration("latency", latency),
+	}
+	switch {
+	case err != nil:
+		attrs = append(attrs, slog.String("error", redactError(err)))
+		api.logger.LogAttrs(request.Context(), slog.LevelError, "tumblr request failed", attrs...)
+	case response.StatusCode >= 400:
+		attrs = append(attrs, slog.Int("status", response.StatusCode))
+		api.logger.LogAttrs(request.Context(), slog.LevelWarn, "tumblr request returned an error status", attrs...)
+	default:
+		attrs = append(attrs, slog.Int("status", response.StatusCode))
+		api.logger.LogAttrs(request.Context(), slog.LevelDebug, "tumblr request", attrs...)
+	}
+}
+
+// This method logs a response body that couldn't be read or decoded
+// request - The request sent
+// err - The read or decode error
+func (api *Tumblr) logResponseError(request *http.Request, err error) {
+	api.logger.WarnContext(request.Context(), "tumblr response could not be read",
+		"method", request.Method,
+		"endpoint", endpoint(request.URL),
+		"blog", blogHostname(request.URL),
+		"url", redactURL(request.URL),
+		"error", redactError(err))
+}
+
+// This method returns a URL with the values of its api key, OAuth and signature
+// query parameters replaced
+// requestURL - The URL to redact
+func redactURL(requestURL *url.URL) string {
+	redactedURL := *requestURL
+	redactedURL.User = nil
+	query := requestURL.Query()
+	for key := range query {
+		if isSecretParam(key) {
+			query.Set(key, redacted)
+		}
+	}
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.String()
+}
+
+// This method returns a copy of headers with the credentials in Authorization,
+// Proxy-Authorization and Cookie headers replaced
+// header - The headers to redact
+func redactHeader(header http.Header) http.Header {
+	redactedHeader := header.Clone()
+	for _, key := range []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"} {
+		for i, value := range redactedHeader[key] {
+			redactedHeader[key][i] = redactCredentials(value)
+		}
+	}
+	return redactedHeader
+}
+
+// This method redacts the credentials of an Authorization header value, keeping
+// the scheme and the non-secret OAuth parameters
+// value - The header value
+func redactCredentials(value string) string {
+	if strings.HasPrefix(value, "OAuth ") {
+		return oauthParam.ReplaceAllStringFunc(value, func(param string) string {
+			name := param[:strings.Index(param, "=")]
+			if publicOAuthParams[name] {
+				return param
+			}
+			return name + `="` + redacted + `"`
+		})
+	}
+	if scheme, _, found := strings.Cut(value, " "); found {
+		return scheme + " " + redacted
+	}
+	return redacted
+}
+
+// This method returns the message of an error with any URL it contains redacted
+// err - The error to redact
+func redactError(err error) string {
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		if parsedURL, parseErr := url.Parse(urlError.URL); parseErr == nil {
+			redactedError := *urlError
+			redactedError.URL = redactURL(parsedURL)
+			return strings.Replace(err.Error(), urlError.Error(), redactedError.Error(), 1)
+		}
+		return redacted
+	}
+	return err.Error()
+}
+
+// This method reports whether a query or form parameter holds a secret
+// key - The parameter name
+func isSecretParam(key string) bool {
+	key = strings.ToLower(key)
+	return key == "api_key" || (strings.HasPrefix(key, "oauth_") && !publicOAuthParams[key]) ||
+		strings.Contains(key, "signature") || strings.Contains(key, "token") || strings.Contains(key, "secret")
+}
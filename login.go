@@ -0,0 +1,88 @@
+package tumblr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kurrik/oauth1a"
+)
+
+// Returned when the user denied the application access or the callback carries no verifier
+var ErrLoginDenied = errors.New("tumblr: authorization was denied")
+
+// An OAuth 1.0a login in progress. The user visits URL, authorizes the application
+// and is redirected to the callback URL with a verifier, which Finish exchanges for
+// the user's OAuth token and secret.
+type Login struct {
+	URL     string             // The authorize URL the user visits
+	api     *Tumblr            // the client whose consumer key started the login
+	service oauth1a.Service    // the client's OAuth service, with the callback URL set
+	user    oauth1a.UserConfig // holds the request token, then the access token
+}
+
+// This method starts an OAuth 1.0a login with the consumer key and secret given to
+// New, getting a request token from the request-token URL
+// callbackURL - Where Tumblr redirects the user after authorizing, e.g. a loopback
+// listener, "" for the callback URL registered with the application
+func (api *Tumblr) StartLogin(callbackURL string) (*Login, error) {
+	clientConfig := *api.oauthService.ClientConfig
+	clientConfig.CallbackURL = callbackURL
+	login := &Login{api: api, service: api.oauthService}
+	login.service.ClientConfig = &clientConfig
+
+	if err := login.user.GetRequestToken(&login.service, api.client); err != nil {
+		return nil, fmt.Errorf("tumblr: getting a request token: %w", err)
+	}
+	authorizeURL, err := login.user.GetAuthorizeURL(&login.service)
+	if err != nil {
+		return nil, err
+	}
+	login.URL = authorizeURL
+	return login, nil
+}
+
+// This method reads the verifier from the request Tumblr redirected the user's
+// browser to
+// request - The callback request
+func (login *Login) Verifier(request *http.Request) (string, error) {
+	token, verifier, err := login.user.ParseAuthorize(request, &login.service)
+	if err != nil || verifier == "" {
+		return "", ErrLoginDenied
+	}
+	if token != login.user.RequestTokenKey {
+		return "", errors.New("tumblr: callback is for another login")
+	}
+	return verifier, nil
+}
+
+// This method reads the verifier from text the user pasted, either the verifier
+// itself or the URL they were redirected to
+// text - The pasted text
+func (login *Login) ParseVerifier(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if !strings.Contains(text, "oauth_token=") {
+		if text == "" {
+			return "", ErrLoginDenied
+		}
+		return text, nil
+	}
+	callbackURL, err := url.Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return login.Verifier(&http.Request{URL: callbackURL})
+}
+
+// This method exchanges the verifier for the user's OAuth token and secret
+// verifier - The verifier from the callback
+func (login *Login) Finish(verifier string) (token, secret string, err error) {
+	err = login.user.GetAccessToken(login.user.RequestTokenKey, verifier, &login.service, login.api.client)
+	if err != nil {
+		return "", "", fmt.Errorf("tumblr: getting an access token: %w", err)
+	}
+	token, secret = login.user.GetToken()
+	return token, secret, nil
+}
@@ -0,0 +1,416 @@
+// Package markdown exports Tumblr posts to a tree of Markdown files with YAML
+// front matter, as static site generators like Hugo and Jekyll read them.
+//
+// An export directory is laid out as:
+//
+//	posts/<date>-<slug>.md   one file per post, e.g. posts/2015-01-02-hello-world.md
+//	assets/<id>/<file>       the images the posts reference, by post ID
+//
+// Post HTML is converted to Markdown, see HTMLToMarkdown. Embedded audio and video
+// players are kept as HTML, which some generators only render when allowed to.
+//
+// The other way around, Sync posts a directory of Markdown files to a blog and
+// keeps the posts up to date as the files change.
+package markdown
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/archive"
+	"github.com/mattcunningham/gumblr/internal/atomicfile"
+)
+
+// An export of posts to a Markdown tree. Exporting again overwrites the posts'
+// files and downloads only the images that are missing.
+type Exporter struct {
+	Dir        string       // The export directory
+	PostsDir   string       // The directory of the Markdown files, relative to Dir. Default: posts
+	AssetsDir  string       // The directory of the images, relative to Dir. Default: assets
+	AssetsURL  string       // The URL the site serves AssetsDir from. Default: /assets
+	Assets     bool         // Whether to copy referenced images into AssetsDir. Default: true
+	Archive    string       // An archive directory (see the archive package) whose media are copied instead of downloaded
+	HTTPClient *http.Client // The HTTP client images are downloaded with. Default: http.DefaultClient
+	Logger     *slog.Logger // Receives the progress of an export. Default: silent
+}
+
+// The numbers of files an export wrote
+type ExportResult struct {
+	Posts       int `json:"posts"`        // Markdown files
+	Assets      int `json:"assets"`       // Copied images
+	AssetErrors int `json:"asset_errors"` // Images that failed to download, referenced by their URL instead
+}
+
+// This method creates an export to a directory
+// dir - The export directory, created if needed
+func NewExporter(dir string) *Exporter {
+	return &Exporter{
+		Dir:        dir,
+		PostsDir:   "posts",
+		AssetsDir:  "assets",
+		AssetsURL:  "/assets",
+		Assets:     true,
+		HTTPClient: http.DefaultClient,
+		Logger:     slog.New(slog.DiscardHandler),
+	}
+}
+
+// The state of one export
+type export struct {
+	*Exporter
+	ctx    context.Context
+	media  map[string]string // the archive's media files by URL
+	names  map[string]bool   // the Markdown files written
+	result ExportResult
+}
+
+// This method exports posts, e.g. the Posts of BlogPosts or of archive.ReadPosts
+// ctx - The context of image downloads
+// posts - The posts to export
+func (exporter *Exporter) Export(ctx context.Context, posts []tumblr.Post) (ExportResult, error) {
+	e := &export{Exporter: exporter, ctx: ctx, names: make(map[string]bool)}
+	if exporter.Archive != "" {
+		manifest, err := archive.ReadManifest(exporter.Archive)
+		if err != nil {
+			return ExportResult{}, err
+		}
+		e.media = manifest.Media
+	}
+	if err := os.MkdirAll(filepath.Join(exporter.Dir, exporter.PostsDir), 0755); err != nil {
+		return ExportResult{}, err
+	}
+
+	for _, post := range posts {
+		if err := ctx.Err(); err != nil {
+			return e.result, err
+		}
+		front, body := e.render(post)
+		name := e.fileName(front, post)
+		data := append(front.Marshal(), '\n')
+		data = append(data, body...)
+		data = append(data, '\n')
+		if err := os.WriteFile(filepath.Join(exporter.Dir, exporter.PostsDir, name), data, 0644); err != nil {
+			return e.result, err
+		}
+		e.result.Posts++
+		exporter.Logger.Info("post exported", "post", post.ID, "file", name)
+	}
+	return e.result, nil
+}
+
+// This method returns the file name of a post, unique within the export
+func (e *export) fileName(front FrontMatter, post tumblr.Post) string {
+	name := front.Date.Format("2006-01-02") + "-" + front.Slug
+	if e.names[name] {
+		name += "-" + strconv.Itoa(post.ID)
+	}
+	e.names[name] = true
+	return name + ".md"
+}
+
+// This method returns the front matter and Markdown body of a post
+func (e *export) render(post tumblr.Post) (FrontMatter, string) {
+	front := FrontMatter{
+		Title:     post.Title,
+		Date:      time.Unix(int64(post.Timestamp), 0).UTC(),
+		Tags:      post.Tags,
+		ID:        post.ID,
+		URL:       post.PostURL,
+		Type:      post.Type,
+		SourceURL: post.SourceURL,
+		Draft:     post.State != "" && post.State != "published",
+	}
+	front.Slug = post.Slug
+	if front.Slug == "" {
+		front.Slug = strconv.Itoa(post.ID)
+	}
+
+	c := converter{image: func(src string) string { return e.localize(post.ID, src) }}
+	var blocks []string
+	add := func(block string) {
+		if block = strings.TrimSpace(block); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	switch post.Type {
+	case "text":
+		add(c.convert(post.Body))
+	case "photo":
+		for _, photo := range post.Photos {
+			if photo.OriginalSize.URL != "" {
+				add("![" + escape(photo.Caption) + "](" + destination(e.localize(post.ID, photo.OriginalSize.URL)) + ")")
+			}
+		}
+		add(c.convert(post.Caption))
+	case "quote":
+		add(quote(c.convert(post.Text)))
+		if source := c.convert(post.Source); source != "" {
+			add("— " + source)
+		}
+	case "link":
+		if front.Title == "" {
+			front.Title = post.URL
+		}
+		add("[" + escape(front.Title) + "](" + destination(post.URL) + ")")
+		add(quote(c.convert(post.Excerpt)))
+		add(c.convert(post.Description))
+	case "chat":
+		var lines []string
+		for _, line := range post.Dialogue {
+			text := escape(line.Phrase)
+			if line.Label != "" {
+				text = "**" + escape(strings.TrimSpace(line.Label)) + "** " + text
+			}
+			lines = append(lines, text)
+		}
+		add(strings.Join(lines, "\\\n"))
+	case "answer":
+		asker := escape(post.AskingName)
+		if asker == "" {
+			asker = "Anonymous"
+		} else if post.AskingURL != "" {
+			asker = "[" + asker + "](" + destination(post.AskingURL) + ")"
+		}
+		add(quote(asker + " asked:\n\n" + c.convert(post.Question)))
+		add(c.convert(post.Answer))
+	case "audio":
+		add(post.AudioPlayer)
+		add(c.convert(post.Caption))
+	case "video":
+		if len(post.Player) > 0 {
+			// Players are listed smallest first
+			add(post.Player[len(post.Player)-1].EmbedCode)
+		}
+		add(c.convert(post.Caption))
+	}
+	if len(blocks) == 0 && len(post.Content) > 0 {
+		add(c.contentBlocks(post.Content))
+	}
+	return front, strings.Join(blocks, "\n\n")
+}
+
+// This method renders Neue Post Format content blocks
+func (c converter) contentBlocks(content []tumblr.ContentBlock) string {
+	var out strings.Builder
+	number := 0
+	for i, block := range content {
+		var text string
+		list := block.Type == "text" && strings.HasSuffix(block.Subtype, "list-item")
+		switch block.Type {
+		case "text":
+			text = formatText(block.Text, block.Formatting)
+			switch block.Subtype {
+			case "heading1":
+				text = "# " + text
+			case "heading2":
+				text = "## " + text
+			case "quote", "indented":
+				text = quote(text)
+			case "ordered-list-item":
+				number++
+				text = strconv.Itoa(number) + ". " + text
+			case "unordered-list-item":
+				text = "- " + text
+			}
+		case "image":
+			if len(block.Media) > 0 {
+				src := block.Media[0].URL
+				if c.image != nil {
+					src = c.image(src)
+				}
+				text = "![" + escape(block.AltText) + "](" + destination(src) + ")"
+				if block.Caption != "" {
+					text += "\n\n" + escape(block.Caption)
+				}
+			}
+		case "link":
+			title := block.Title
+			if title == "" {
+				title = block.URL
+			}
+			text = "[" + escape(title) + "](" + destination(block.URL) + ")"
+			if block.Description != "" {
+				text += "\n\n" + quote(escape(block.Description))
+			}
+		case "audio", "video":
+			switch {
+			case block.EmbedHTML != "":
+				text = block.EmbedHTML
+			case block.URL != "":
+				text = "[" + escape(block.Title) + "](" + destination(block.URL) + ")"
+			case len(block.Media) > 0:
+				text = "[" + escape(block.Title) + "](" + destination(block.Media[0].URL) + ")"
+			}
+		}
+		if !list || block.Subtype != "ordered-list-item" {
+			number = 0
+		}
+		if text == "" {
+			continue
+		}
+		if i > 0 && out.Len() > 0 {
+			// Items of the same list are on consecutive lines
+			if list && content[i-1].Type == "text" && content[i-1].Subtype == block.Subtype {
+				out.WriteString("\n")
+			} else {
+				out.WriteString("\n\n")
+			}
+		}
+		out.WriteString(text)
+	}
+	return out.String()
+}
+
+// This method renders the text of a text block with its inline formatting. Ranges
+// ending at the same place are closed in the reverse order they were opened, and
+// a range ending inside another is closed with the ones opened after it, which
+// are then reopened, so the markers always nest.
+func formatText(text string, formatting []tumblr.Formatting) string {
+	runes := []rune(text)
+	var open []tumblr.Formatting
+	var out strings.Builder
+	for i := 0; i <= len(runes); i++ {
+		for j := 0; j < len(open); j++ {
+			if open[j].End > i {
+				continue
+			}
+			var reopen []tumblr.Formatting
+			for k := len(open) - 1; k >= j; k-- {
+				out.WriteString(closingMarker(open[k]))
+				if open[k].End > i {
+					reopen = append([]tumblr.Formatting{open[k]}, reopen...)
+				}
+			}
+			for _, f := range reopen {
+				out.WriteString(openingMarker(f))
+			}
+			open = append(open[:j], reopen...)
+			j--
+		}
+		for _, f := range formatting {
+			if f.Start == i && f.End > f.Start && f.End <= len(runes) && openingMarker(f) != "" {
+				out.WriteString(openingMarker(f))
+				open = append(open, f)
+			}
+		}
+		if i == len(runes) {
+			break
+		}
+		if runes[i] == '\n' {
+			out.WriteString("\\\n")
+		} else {
+			out.WriteString(escaper.Replace(string(runes[i])))
+		}
+	}
+	return escapeLineStart(out.String())
+}
+
+// This method returns the Markdown starting a range of inline formatting, "" for
+// formatting Markdown has no syntax for
+func openingMarker(f tumblr.Formatting) string {
+	switch f.Type {
+	case "bold":
+		return "**"
+	case "italic":
+		return "*"
+	case "strikethrough":
+		return "~~"
+	case "link", "mention":
+		return "["
+	}
+	return ""
+}
+
+// This method returns the Markdown ending a range of inline formatting
+func closingMarker(f tumblr.Formatting) string {
+	switch f.Type {
+	case "link":
+		return "](" + destination(f.URL) + ")"
+	case "mention":
+		return "](" + destination(f.Blog.URL) + ")"
+	}
+	return openingMarker(f)
+}
+
+// This method quotes Markdown, prefixing every line with >
+func quote(text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// This method copies an image into the assets directory, returning the URL to
+// reference it by, or its original URL when it can't be copied
+func (e *export) localize(postID int, src string) string {
+	if !e.Assets || !(strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "http://")) {
+		return src
+	}
+	parsed, err := url.Parse(src)
+	if err != nil {
+		return src
+	}
+	name := path.Base(parsed.Path)
+	if name == "/" || name == "." || strings.HasPrefix(name, ".") {
+		name = "image"
+	}
+	name = strconv.Itoa(postID) + "/" + name
+	file := filepath.Join(e.Dir, e.AssetsDir, filepath.FromSlash(name))
+	local := strings.TrimSuffix(e.AssetsURL, "/") + "/" + name
+
+	if _, err := os.Stat(file); err == nil {
+		return local
+	}
+	if err := e.copy(file, src); err != nil {
+		e.result.AssetErrors++
+		e.Logger.Warn("image copy failed", "post", postID, "url", src, "error", err)
+		return src
+	}
+	e.result.Assets++
+	return local
+}
+
+// This method writes an image to a file, from the archive when it was downloaded
+// there, or else from its URL
+func (e *export) copy(file string, src string) error {
+	var body io.Reader
+	if archived, found := e.media[src]; found {
+		f, err := os.Open(filepath.Join(e.Archive, filepath.FromSlash(archived)))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		body = f
+	} else {
+		request, err := http.NewRequestWithContext(e.ctx, "GET", src, nil)
+		if err != nil {
+			return err
+		}
+		response, err := e.HTTPClient.Do(request)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("markdown: downloading %s: %s", src, response.Status)
+		}
+		body = response.Body
+	}
+
+	return atomicfile.Write(file, body)
+}
@@ -0,0 +1,90 @@
+package markdown
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tumblr "github.com/mattcunningham/gumblr"
+)
+
+func TestExport(t *testing.T) {
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.png" {
+			http.NotFound(w, r)
+			return
+		}
+		downloads++
+		fmt.Fprint(w, "image data")
+	}))
+	defer server.Close()
+
+	var posts []tumblr.Post
+	for _, post := range []string{
+		`{"id":1,"type":"text","timestamp":1420106400,"title":"Hello \"world\"","slug":"hello-world","tags":["go","tumblr"],"state":"published",
+			"post_url":"https://staff.tumblr.com/post/1/hello-world",
+			"body":"<p>Hi <img src=\"` + server.URL + `/inline.png\"></p>"}`,
+		`{"id":2,"type":"photo","timestamp":1420192800,"caption":"<p>Sunset</p>","source_url":"https://example.com/",
+			"photos":[{"caption":"First","original_size":{"url":"` + server.URL + `/photo.jpg"}},{"original_size":{"url":"` + server.URL + `/missing.png"}}]}`,
+		`{"id":3,"type":"quote","timestamp":1420192800,"text":"Be yourself","source":"<a href=\"https://example.com/oscar\">Oscar</a>","state":"draft"}`,
+		`{"id":4,"type":"chat","timestamp":1420192800,"dialogue":[{"label":"A:","phrase":"Hi"},{"label":"B:","phrase":"*waves*"}]}`,
+		`{"id":5,"type":"answer","timestamp":1420192800,"asking_name":"david","asking_url":"https://david.tumblr.com/","question":"Why?","answer":"<p>Because</p>"}`,
+		`{"id":6,"type":"link","timestamp":1420192800,"url":"https://example.com/article","excerpt":"Excerpt","description":"<p>Worth reading</p>"}`,
+		`{"id":7,"type":"blocks","timestamp":1420192800,"content":[{"type":"text","subtype":"heading1","text":"Neue"},
+			{"type":"text","text":"bold and link","formatting":[{"start":0,"end":8,"type":"bold"},{"start":4,"end":13,"type":"link","url":"https://example.com/"}]},
+			{"type":"text","subtype":"ordered-list-item","text":"one"},{"type":"text","subtype":"ordered-list-item","text":"two"}]}`,
+		`{"id":8,"type":"audio","timestamp":1420192800,"caption":"<p>Listen</p>","player":"<iframe src=\"https://w.soundcloud.com/player/\"></iframe>"}`,
+		`{"id":9,"type":"video","timestamp":1420192800,"caption":"<p>Watch</p>","player":[
+			{"width":250,"embed_code":"<iframe width=\"250\"></iframe>"},{"width":500,"embed_code":"<iframe width=\"500\"></iframe>"}]}`,
+	} {
+		// Posts decoded without their raw JSON, which export doesn't need
+		var decoded tumblr.Post
+		if err := json.Unmarshal([]byte(post), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, decoded)
+	}
+
+	dir := t.TempDir()
+	exporter := NewExporter(dir)
+	result, err := exporter.Export(context.Background(), posts)
+	if err != nil || result.Posts != 9 || result.Assets != 2 || result.AssetErrors != 1 {
+		t.Fatalf("Export returned %+v with error %v", result, err)
+	}
+
+	for name, want := range map[string]string{
+		"2015-01-01-hello-world.md": "---\ntitle: \"Hello \\\"world\\\"\"\ndate: 2015-01-01T10:00:00Z\nslug: \"hello-world\"\ntumblr_type: \"text\"\ntumblr_id: 1\n" +
+			"tumblr_url: \"https://staff.tumblr.com/post/1/hello-world\"\n" +
+			"tags:\n  - \"go\"\n  - \"tumblr\"\n---\n\nHi ![](/assets/1/inline.png)\n",
+		"2015-01-02-2.md": "---\ndate: 2015-01-02T10:00:00Z\nslug: \"2\"\ntumblr_type: \"photo\"\ntumblr_id: 2\nsource_url: \"https://example.com/\"\n---\n\n" +
+			"![First](/assets/2/photo.jpg)\n\n![](" + server.URL + "/missing.png)\n\nSunset\n",
+		"2015-01-02-3.md": "draft: true\n---\n\n> Be yourself\n\n— [Oscar](https://example.com/oscar)\n",
+		"2015-01-02-4.md": "---\n\n**A:** Hi\\\n**B:** \\*waves\\*\n",
+		"2015-01-02-5.md": "---\n\n> [david](https://david.tumblr.com/) asked:\n>\n> Why?\n\nBecause\n",
+		"2015-01-02-6.md": "title: \"https://example.com/article\"\n",
+		"2015-01-02-7.md": "---\n\n# Neue\n\n**bold[ and](https://example.com/)**[ link](https://example.com/)\n\n1. one\n2. two\n",
+		"2015-01-02-8.md": "---\n\n<iframe src=\"https://w.soundcloud.com/player/\"></iframe>\n\nListen\n",
+		"2015-01-02-9.md": "---\n\n<iframe width=\"500\"></iframe>\n\nWatch\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, "posts", name))
+		if err != nil || !strings.Contains(string(data), want) {
+			t.Errorf("%s holds %q with error %v, want %q", name, data, err, want)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "assets", "2", "photo.jpg")); err != nil || string(data) != "image data" {
+		t.Errorf("Asset holds %q with error %v", data, err)
+	}
+
+	// Exporting again downloads only what is missing
+	result, err = exporter.Export(context.Background(), posts)
+	if err != nil || result.Assets != 0 || downloads != 2 {
+		t.Errorf("Second export returned %+v with error %v after %d downloads", result, err, downloads)
+	}
+}
@@ -0,0 +1,204 @@
+package markdown
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The front matter of a post's Markdown file
+type FrontMatter struct {
+	Title     string    // The post's title, if it has one
+	Date      time.Time // When the post was published
+	Tags      []string  // The post's tags
+	Slug      string    // The post's slug, or its ID when it has none
+	Type      string    // The Tumblr post type, written as tumblr_type as type means a layout to Hugo
+	SourceURL string    // The URL of the content's source, e.g. for quotes and reblogs
+	Draft     bool      // Whether the post isn't published, e.g. drafts and queued posts
+	ID        int       // The ID of the Tumblr post, written as tumblr_id. 0 for a file that wasn't posted yet
+	URL       string    // The URL of the Tumblr post, written as tumblr_url
+	Hash      string    // The hash of the content as of the last sync, written as tumblr_hash
+}
+
+// Returned for a file whose front matter has no closing --- line
+var errUnterminated = errors.New("markdown: front matter isn't terminated by a --- line")
+
+// The date formats accepted in front matter, besides RFC 3339
+var dateFormats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04:05 MST", "2006-01-02"}
+
+// This method writes the front matter as YAML between --- lines
+func (front FrontMatter) Marshal() []byte {
+	var out strings.Builder
+	out.WriteString("---\n")
+	if front.Title != "" {
+		out.WriteString("title: " + strconv.Quote(front.Title) + "\n")
+	}
+	out.WriteString("date: " + front.Date.Format(time.RFC3339) + "\n")
+	out.WriteString("slug: " + strconv.Quote(front.Slug) + "\n")
+	out.WriteString("tumblr_type: " + strconv.Quote(front.Type) + "\n")
+	if front.ID != 0 {
+		out.WriteString("tumblr_id: " + strconv.Itoa(front.ID) + "\n")
+	}
+	if front.URL != "" {
+		out.WriteString("tumblr_url: " + strconv.Quote(front.URL) + "\n")
+	}
+	if front.Hash != "" {
+		out.WriteString("tumblr_hash: " + strconv.Quote(front.Hash) + "\n")
+	}
+	if len(front.Tags) > 0 {
+		out.WriteString("tags:\n")
+		for _, tag := range front.Tags {
+			out.WriteString("  - " + strconv.Quote(tag) + "\n")
+		}
+	}
+	if front.SourceURL != "" {
+		out.WriteString("source_url: " + strconv.Quote(front.SourceURL) + "\n")
+	}
+	if front.Draft {
+		out.WriteString("draft: true\n")
+	}
+	out.WriteString("---\n")
+	return []byte(out.String())
+}
+
+// A Markdown file: its front matter and body. The lines of the front matter are
+// kept as they were, so writing the file back changes only the keys that were set
+// and keeps those this package doesn't know, e.g. the ones of a site generator.
+type document struct {
+	front FrontMatter
+	lines []string // the lines between the --- lines
+	body  string   // everything after the closing --- line
+	bare  bool     // whether the file had no front matter
+}
+
+// This method parses a Markdown file. Only the subset of YAML that front matter
+// is written in is understood: top-level keys with plain or quoted values, and
+// lists written in a block or inline.
+// data - The contents of the file
+func parseDocument(data []byte) (*document, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return &document{body: text, bare: true}, nil
+	}
+	lines := strings.Split(text[len("---\n"):], "\n")
+	end := -1
+	for i, line := range lines {
+		if line == "---" || line == "..." {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, errUnterminated
+	}
+	doc := &document{lines: lines[:end:end], body: strings.Join(lines[end+1:], "\n")}
+
+	front := &doc.front
+	for i, line := range doc.lines {
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '-' || line[0] == '#' {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "title":
+			front.Title = scalar(value)
+		case "date":
+			for _, format := range dateFormats {
+				if date, err := time.Parse(format, scalar(value)); err == nil {
+					front.Date = date
+					break
+				}
+			}
+		case "slug":
+			front.Slug = scalar(value)
+		case "tumblr_type":
+			front.Type = scalar(value)
+		case "source_url":
+			front.SourceURL = scalar(value)
+		case "draft":
+			front.Draft = scalar(value) == "true"
+		case "tumblr_id":
+			front.ID, _ = strconv.Atoi(scalar(value))
+		case "tumblr_url":
+			front.URL = scalar(value)
+		case "tumblr_hash":
+			front.Hash = scalar(value)
+		case "tags":
+			front.Tags = list(value, doc.lines[i+1:])
+		}
+	}
+	return doc, nil
+}
+
+// This method returns the value of a scalar, unquoted
+func scalar(value string) string {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+		return strings.Trim(value, `"`)
+	case strings.HasPrefix(value, "'"):
+		return strings.ReplaceAll(strings.Trim(value, "'"), "''", "'")
+	}
+	if comment := strings.Index(value, " #"); comment >= 0 {
+		value = value[:comment]
+	}
+	return strings.TrimSpace(value)
+}
+
+// This method returns the items of a list, written inline as [a, b] or as the
+// indented - lines following its key
+// value - What follows the list's key
+// following - The lines after the list's key
+func list(value string, following []string) []string {
+	var items []string
+	if strings.HasPrefix(value, "[") {
+		for _, item := range strings.Split(strings.Trim(value, "[]"), ",") {
+			if item = scalar(strings.TrimSpace(item)); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	if value != "" {
+		return []string{scalar(value)}
+	}
+	for _, line := range following {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "-" && !strings.HasPrefix(trimmed, "- ") {
+			break
+		}
+		items = append(items, scalar(strings.TrimSpace(trimmed[1:])))
+	}
+	return items
+}
+
+// This method sets a key of the front matter to a value, replacing its line or
+// adding one
+// key - The top-level key
+// value - The value, as written in YAML
+func (doc *document) set(key string, value string) {
+	line := key + ": " + value
+	for i, existing := range doc.lines {
+		if strings.HasPrefix(existing, key+":") {
+			doc.lines[i] = line
+			return
+		}
+	}
+	doc.lines = append(doc.lines, line)
+}
+
+// This method returns the contents of the file
+func (doc *document) bytes() []byte {
+	body := doc.body
+	if doc.bare {
+		body = "\n" + body
+	}
+	return []byte("---\n" + strings.Join(doc.lines, "\n") + "\n---\n" + body)
+}
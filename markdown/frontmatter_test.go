@@ -0,0 +1,38 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDocument(t *testing.T) {
+	source := "---\ntitle: \"Hello \\\"there\\\"\"\ndate: 2024-05-01\nauthor: Jane # kept\ntags:\n  - go\n  - 'it''s'\nslug: hello\ndraft: true\ntumblr_id: 12\ntumblr_url: https://staff.tumblr.com/post/12\n---\n\nBody\n"
+	doc, err := parseDocument([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FrontMatter{Title: `Hello "there"`, Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"go", "it's"},
+		Slug: "hello", Draft: true, ID: 12, URL: "https://staff.tumblr.com/post/12"}
+	if !reflect.DeepEqual(doc.front, want) || doc.body != "\nBody\n" {
+		t.Errorf("Parsed %+v with body %q", doc.front, doc.body)
+	}
+	if string(doc.bytes()) != source {
+		t.Errorf("Document was written back as %q", doc.bytes())
+	}
+	doc.set("tumblr_id", "13")
+	doc.set("tumblr_hash", `"abc"`)
+	if written := string(doc.bytes()); written != "---\ntitle: \"Hello \\\"there\\\"\"\ndate: 2024-05-01\nauthor: Jane # kept\ntags:\n  - go\n  - 'it''s'\nslug: hello\ndraft: true\ntumblr_id: 13\ntumblr_url: https://staff.tumblr.com/post/12\ntumblr_hash: \"abc\"\n---\n\nBody\n" {
+		t.Errorf("Document with new keys was written as %q", written)
+	}
+
+	if doc, err := parseDocument([]byte("---\ntags: [a, \"b\"]\n---\n")); err != nil || !reflect.DeepEqual(doc.front.Tags, []string{"a", "b"}) {
+		t.Errorf("Inline list was parsed as %q with error %v", doc.front.Tags, err)
+	}
+	if doc, err := parseDocument([]byte("Just text\n")); err != nil || !doc.bare || string(doc.bytes()) != "---\n\n---\n\nJust text\n" {
+		t.Errorf("File without front matter was parsed as %+v with error %v", doc, err)
+	}
+	if _, err := parseDocument([]byte("---\ntitle: x\n")); err != errUnterminated {
+		t.Errorf("Unterminated front matter returned %v", err)
+	}
+}
@@ -0,0 +1,453 @@
+package markdown
+
+import (
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A node of a parsed HTML fragment: an element, or text when tag is ""
+type node struct {
+	tag      string
+	attrs    map[string]string
+	text     string
+	children []*node
+}
+
+// Elements that never have children
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// Elements whose content is dropped
+var droppedElements = map[string]bool{"script": true, "style": true, "template": true, "noscript": true}
+
+// Elements rendered as blocks, separated by blank lines
+var blockElements = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "blockquote": true, "pre": true, "hr": true, "figure": true,
+	"figcaption": true, "section": true, "article": true, "header": true, "footer": true, "table": true,
+	"tr": true, "iframe": true, "video": true, "audio": true,
+}
+
+// This method parses an HTML fragment into a tree. It is forgiving like browsers
+// are: unknown end tags are ignored and unclosed elements are closed by their
+// parent's end tag.
+// source - The HTML
+func parseHTML(source string) *node {
+	root := &node{tag: "#root"}
+	stack := []*node{root}
+	appendNode := func(n *node) {
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, n)
+	}
+
+	for len(source) > 0 {
+		start := strings.IndexByte(source, '<')
+		if start < 0 {
+			appendNode(&node{text: html.UnescapeString(source)})
+			break
+		}
+		if start > 0 {
+			appendNode(&node{text: html.UnescapeString(source[:start])})
+			source = source[start:]
+		}
+		if strings.HasPrefix(source, "<!--") {
+			end := strings.Index(source, "-->")
+			if end < 0 {
+				break
+			}
+			source = source[end+3:]
+			continue
+		}
+		end := tagEnd(source)
+		if end < 0 || len(source) < 2 || !(isLetter(source[1]) || source[1] == '/' || source[1] == '!') {
+			// A < that doesn't start a tag is text
+			appendNode(&node{text: "<"})
+			source = source[1:]
+			continue
+		}
+		tag := source[1:end]
+		source = source[end+1:]
+		if strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToLower(strings.TrimSpace(tag[1:]))
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		element := parseTag(strings.TrimSuffix(tag, "/"))
+		if droppedElements[element.tag] {
+			closing := strings.Index(strings.ToLower(source), "</"+element.tag)
+			if closing < 0 {
+				break
+			}
+			source = source[closing:]
+			continue
+		}
+		if element.tag == "li" || element.tag == "p" {
+			// An open li or p is closed by the next one
+			for i := len(stack) - 1; i > 0 && (stack[i].tag != "ul" && stack[i].tag != "ol"); i-- {
+				if stack[i].tag == element.tag {
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+		appendNode(element)
+		if element.tag == "pre" {
+			// The content of pre is kept as text
+			closing := strings.Index(strings.ToLower(source), "</pre")
+			if closing < 0 {
+				closing = len(source)
+			}
+			element.children = []*node{{text: html.UnescapeString(stripTags(source[:closing]))}}
+			source = source[closing:]
+			continue
+		}
+		if !voidElements[element.tag] && !strings.HasSuffix(tag, "/") {
+			stack = append(stack, element)
+		}
+	}
+	return root
+}
+
+// This method returns the index of the > ending the tag at the start of source,
+// skipping quoted attribute values
+func tagEnd(source string) int {
+	var quote byte
+	for i := 1; i < len(source); i++ {
+		switch c := source[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// This method parses the name and attributes of a start tag, e.g. a href="/"
+func parseTag(tag string) *node {
+	name, rest, _ := strings.Cut(strings.TrimSpace(tag), " ")
+	if i := strings.IndexAny(name, "\t\n\r"); i >= 0 {
+		name, rest = name[:i], name[i:]+" "+rest
+	}
+	element := &node{tag: strings.ToLower(name), attrs: make(map[string]string)}
+	for {
+		rest = strings.TrimLeft(rest, " \t\n\r")
+		if rest == "" {
+			return element
+		}
+		end := strings.IndexAny(rest, "= \t\n\r")
+		if end < 0 {
+			element.attrs[strings.ToLower(rest)] = ""
+			return element
+		}
+		key := strings.ToLower(rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t\n\r")
+		if !strings.HasPrefix(rest, "=") {
+			element.attrs[key] = ""
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\n\r")
+		var value string
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			closing := strings.IndexByte(rest[1:], rest[0])
+			if closing < 0 {
+				closing = len(rest) - 1
+			}
+			value, rest = rest[1:closing+1], rest[min(closing+2, len(rest)):]
+		} else {
+			end := strings.IndexAny(rest, " \t\n\r")
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		element.attrs[key] = html.UnescapeString(value)
+	}
+}
+
+// This method removes the tags of an HTML fragment, keeping its text
+func stripTags(source string) string {
+	var text strings.Builder
+	for {
+		start := strings.IndexByte(source, '<')
+		if start < 0 {
+			text.WriteString(source)
+			return text.String()
+		}
+		text.WriteString(source[:start])
+		source = source[start:]
+		end := tagEnd(source)
+		if end < 0 || len(source) < 2 || !(isLetter(source[1]) || source[1] == '/' || source[1] == '!') {
+			text.WriteByte('<')
+			source = source[1:]
+			continue
+		}
+		source = source[end+1:]
+	}
+}
+
+// Converts HTML to Markdown
+type converter struct {
+	image func(src string) string // returns where an image is referenced from, e.g. a local copy
+}
+
+// This method converts HTML to CommonMark Markdown. Paragraphs, headings, lists,
+// quotes, code, links, images and emphasis are converted; other elements are
+// reduced to their text, except for embedded media, which is kept as HTML.
+// source - The HTML
+func HTMLToMarkdown(source string) string {
+	return converter{}.convert(source)
+}
+
+func (c converter) convert(source string) string {
+	return strings.TrimSpace(c.blocks(parseHTML(source).children))
+}
+
+// This method renders a sequence of nodes as blocks separated by blank lines,
+// gathering inline nodes into paragraphs
+func (c converter) blocks(nodes []*node) string {
+	var blocks []string
+	var inline []*node
+	flush := func() {
+		if text := strings.TrimSpace(c.inline(inline)); text != "" {
+			blocks = append(blocks, text)
+		}
+		inline = nil
+	}
+	for _, n := range nodes {
+		if !blockElements[n.tag] {
+			inline = append(inline, n)
+			continue
+		}
+		flush()
+		if block := c.block(n); strings.TrimSpace(block) != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	flush()
+	return strings.Join(blocks, "\n\n")
+}
+
+// This method renders a block element
+func (c converter) block(n *node) string {
+	switch n.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(n.tag[1:])
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(strings.ReplaceAll(c.inline(n.children), "\n", " "))
+	case "ul", "ol":
+		var items []string
+		number := 1
+		if start, err := strconv.Atoi(n.attrs["start"]); err == nil {
+			number = start
+		}
+		for _, child := range n.children {
+			if child.tag != "li" {
+				continue
+			}
+			marker := "- "
+			if n.tag == "ol" {
+				marker = strconv.Itoa(number) + ". "
+				number++
+			}
+			items = append(items, marker+indent(c.blocks(child.children), strings.Repeat(" ", len(marker))))
+		}
+		return strings.Join(items, "\n")
+	case "li":
+		return "- " + indent(c.blocks(n.children), "  ")
+	case "blockquote":
+		lines := strings.Split(c.blocks(n.children), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return strings.Join(lines, "\n")
+	case "pre":
+		code := strings.Trim(textContent(n), "\n")
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return fence + "\n" + code + "\n" + fence
+	case "hr":
+		return "---"
+	case "iframe", "video", "audio":
+		return renderHTML(n)
+	}
+	return c.blocks(n.children)
+}
+
+// This method renders inline nodes, collapsing whitespace like browsers do
+func (c converter) inline(nodes []*node) string {
+	var out strings.Builder
+	for _, n := range nodes {
+		switch n.tag {
+		case "":
+			out.WriteString(escape(collapseSpace(n.text)))
+		case "br":
+			out.WriteString("\\\n")
+		case "b", "strong":
+			out.WriteString(wrap(c.inline(n.children), "**"))
+		case "i", "em":
+			out.WriteString(wrap(c.inline(n.children), "*"))
+		case "s", "strike", "del":
+			out.WriteString(wrap(c.inline(n.children), "~~"))
+		case "code":
+			code := textContent(n)
+			fence := "`"
+			for strings.Contains(code, fence) {
+				fence += "`"
+			}
+			out.WriteString(fence + code + fence)
+		case "a":
+			text := strings.TrimSpace(c.inline(n.children))
+			href := n.attrs["href"]
+			if href == "" {
+				out.WriteString(text)
+				continue
+			}
+			if text == "" {
+				text = escape(href)
+			}
+			out.WriteString("[" + text + "](" + destination(href) + ")")
+		case "img":
+			src := n.attrs["src"]
+			if src == "" {
+				continue
+			}
+			if c.image != nil {
+				src = c.image(src)
+			}
+			out.WriteString("![" + escape(n.attrs["alt"]) + "](" + destination(src) + ")")
+		default:
+			if blockElements[n.tag] {
+				out.WriteString(" " + c.block(n) + " ")
+			} else {
+				out.WriteString(c.inline(n.children))
+			}
+		}
+	}
+	return out.String()
+}
+
+// This method returns the text of a node and its descendants
+func textContent(n *node) string {
+	if n.tag == "" {
+		return n.text
+	}
+	var text strings.Builder
+	for _, child := range n.children {
+		text.WriteString(textContent(child))
+	}
+	return text.String()
+}
+
+// This method renders a node back to HTML
+func renderHTML(n *node) string {
+	if n.tag == "" {
+		return html.EscapeString(n.text)
+	}
+	var out strings.Builder
+	out.WriteString("<" + n.tag)
+	names := make([]string, 0, len(n.attrs))
+	for name := range n.attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.WriteString(" " + name + `="` + html.EscapeString(n.attrs[name]) + `"`)
+	}
+	out.WriteString(">")
+	if voidElements[n.tag] {
+		return out.String()
+	}
+	for _, child := range n.children {
+		out.WriteString(renderHTML(child))
+	}
+	out.WriteString("</" + n.tag + ">")
+	return out.String()
+}
+
+// This method wraps text in emphasis markers, keeping surrounding spaces outside
+func wrap(text string, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+// This method collapses runs of whitespace into single spaces
+func collapseSpace(text string) string {
+	var out strings.Builder
+	space := false
+	for _, r := range text {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			if !space {
+				out.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`)
+
+// This method escapes the characters of text that Markdown would interpret
+func escape(text string) string {
+	return escapeLineStart(escaper.Replace(text))
+}
+
+// This method escapes text that would start a heading, quote or list at the start of a line
+func escapeLineStart(text string) string {
+	if trimmed := strings.TrimLeft(text, " "); strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "> ") ||
+		strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "+ ") {
+		text = text[:len(text)-len(trimmed)] + `\` + trimmed
+	}
+	return text
+}
+
+// This method writes a link destination, in angle brackets when it has spaces or parentheses
+func destination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+// This method indents every line but the first
+func indent(text string, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
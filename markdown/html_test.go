@@ -0,0 +1,24 @@
+package markdown
+
+import "testing"
+
+func TestHTMLToMarkdown(t *testing.T) {
+	for _, test := range []struct{ html, markdown string }{
+		{"<p>Hello <b>world</b>, <i>again</i></p><p>Second &amp; last</p>", "Hello **world**, *again*\n\nSecond & last"},
+		{"Loose text<br>next line", "Loose text\\\nnext line"},
+		{`<p><a href="https://example.com/a b">a link</a> and <img src="https://example.com/x.png" alt="X"></p>`,
+			"[a link](<https://example.com/a b>) and ![X](https://example.com/x.png)"},
+		{"<h2>Title</h2><ul><li>one<li>two <strong>bold</strong></ul><ol start=3><li><p>three</p><p>more</p></li></ol>",
+			"## Title\n\n- one\n- two **bold**\n\n3. three\n\n   more"},
+		{"<blockquote><p>quoted</p><p>twice</p></blockquote>", "> quoted\n>\n> twice"},
+		{"<pre><code>if a < b {\n\treturn\n}</code></pre>", "```\nif a < b {\n\treturn\n}\n```"},
+		{"<p>Use <code>go vet</code> * not _this_</p>", "Use `go vet` \\* not \\_this\\_"},
+		{"<p># not a heading</p><!-- comment --><script>alert(1)</script><hr>", "\\# not a heading\n\n---"},
+		{`<iframe src="https://www.youtube.com/embed/x" width=500></iframe>`, `<iframe src="https://www.youtube.com/embed/x" width="500"></iframe>`},
+		{"<p><s>gone</s> 1 < 2</p>", "~~gone~~ 1 \\< 2"},
+	} {
+		if markdown := HTMLToMarkdown(test.html); markdown != test.markdown {
+			t.Errorf("HTMLToMarkdown(%q) = %q, want %q", test.html, markdown, test.markdown)
+		}
+	}
+}
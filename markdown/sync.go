@@ -0,0 +1,342 @@
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/atomicfile"
+)
+
+// The file a sync records the posts it made in, in the synced directory
+const stateFile = ".gumblr-sync.json"
+
+// A sync of a directory of Markdown files to a blog, each file a text post in
+// Markdown format. New files are posted, files that changed since the last sync
+// update their post, and files that were removed have their post deleted when
+// Delete is set. The ID of a file's post and the hash of what was posted are kept
+// in its front matter, as tumblr_id and tumblr_hash, so the files can be committed
+// and synced from anywhere; the state file only records which posts came from
+// files, to notice removed ones and files whose ID failed to be written.
+//
+// Files whose tumblr_type isn't text, e.g. exported photo posts, are skipped, as
+// are files and directories whose name starts with . or _.
+type Sync struct {
+	BlogHostname string       // The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+	Dir          string       // The directory of Markdown files, searched recursively
+	State        string       // The state file. Default: .gumblr-sync.json in Dir
+	Delete       bool         // Whether to delete the posts of removed files
+	Logger       *slog.Logger // Receives the progress of a sync. Default: silent
+
+	api *tumblr.Tumblr
+}
+
+// A change a sync makes to the blog
+type SyncAction struct {
+	Op    string `json:"op"`              // create, update or delete
+	File  string `json:"file"`            // The file, relative to Dir
+	ID    int    `json:"id,omitempty"`    // The ID of the post, 0 for a post to create
+	Title string `json:"title,omitempty"` // The title of the post
+
+	params map[string]string
+	hash   string
+}
+
+// The changes a sync would make, to review before applying them
+type SyncPlan struct {
+	Actions   []SyncAction `json:"actions"`           // The changes, in file order, deletions last
+	Unchanged int          `json:"unchanged"`         // The number of files that didn't change
+	Skipped   []string     `json:"skipped,omitempty"` // The files that aren't text posts
+
+	files map[int]string // the file of every post, by ID
+}
+
+// The numbers of posts a sync changed
+type SyncResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+}
+
+// The record of the posts made from files
+type syncState struct {
+	Blog  string         `json:"blog"`  // the hostname of the synced blog
+	Posts map[int]string `json:"posts"` // the file of every post, by ID
+}
+
+// This method creates a sync of a directory to a blog
+// dir - The directory of Markdown files
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+func NewSync(api *tumblr.Tumblr, dir string, blogHostname string) *Sync {
+	return &Sync{
+		BlogHostname: blogHostname,
+		Dir:          dir,
+		State:        filepath.Join(dir, stateFile),
+		Logger:       slog.New(slog.DiscardHandler),
+		api:          api,
+	}
+}
+
+// This method reads the state file, returning an empty state for a directory that
+// wasn't synced yet
+func (sync *Sync) readState() (*syncState, error) {
+	state := &syncState{Blog: sync.BlogHostname, Posts: make(map[int]string)}
+	data, err := os.ReadFile(sync.State)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("markdown: reading %s: %v", sync.State, err)
+	}
+	if state.Blog != sync.BlogHostname {
+		return nil, fmt.Errorf("markdown: %s was synced to %s", sync.Dir, state.Blog)
+	}
+	if state.Posts == nil {
+		state.Posts = make(map[int]string)
+	}
+	return state, nil
+}
+
+func (state *syncState) write(path string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(path, bytes.NewReader(data))
+}
+
+// This method compares the directory with what was synced, returning the changes
+// a sync would make without making them
+func (sync *Sync) Plan() (SyncPlan, error) {
+	state, err := sync.readState()
+	if err != nil {
+		return SyncPlan{}, err
+	}
+	plan := SyncPlan{files: make(map[int]string)}
+	// The posts of files a sync created but failed to write the ID into
+	created := make(map[string]int)
+	for id, file := range state.Posts {
+		created[file] = id
+	}
+	err = filepath.WalkDir(sync.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if path != sync.Dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || filepath.Ext(name) != ".md" {
+			return nil
+		}
+		file, err := filepath.Rel(sync.Dir, path)
+		if err != nil {
+			return err
+		}
+		file = filepath.ToSlash(file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		doc, err := parseDocument(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		front := doc.front
+		if front.Type != "" && front.Type != "text" {
+			plan.Skipped = append(plan.Skipped, file)
+			return nil
+		}
+
+		action := SyncAction{File: file, ID: front.ID, Title: front.Title, params: postParams(doc)}
+		if action.ID == 0 {
+			action.ID = created[file]
+		}
+		action.hash = hashParams(action.params)
+		switch {
+		case action.ID == 0:
+			action.Op = "create"
+		case plan.files[action.ID] != "":
+			return fmt.Errorf("markdown: %s and %s are both post %d", plan.files[action.ID], file, action.ID)
+		case action.hash != front.Hash || front.ID == 0:
+			action.Op = "update"
+		default:
+			plan.Unchanged++
+		}
+		if action.ID != 0 {
+			plan.files[action.ID] = file
+		}
+		if action.Op != "" {
+			plan.Actions = append(plan.Actions, action)
+		}
+		return nil
+	})
+	if err != nil {
+		return SyncPlan{}, err
+	}
+
+	if sync.Delete {
+		var removed []int
+		for id := range state.Posts {
+			if plan.files[id] == "" {
+				removed = append(removed, id)
+			}
+		}
+		sort.Ints(removed)
+		for _, id := range removed {
+			plan.Actions = append(plan.Actions, SyncAction{Op: "delete", File: state.Posts[id], ID: id})
+		}
+	}
+	return plan, nil
+}
+
+// This method makes the changes of a plan, writing the ID and hash of every
+// created or updated post into its file's front matter. The state file is written
+// after every change, so a sync that fails can be planned and applied again.
+// ctx - The context of every request
+// plan - The changes, as returned by Plan
+func (sync *Sync) Apply(ctx context.Context, plan SyncPlan) (SyncResult, error) {
+	var result SyncResult
+	state, err := sync.readState()
+	if err != nil {
+		return result, err
+	}
+	for id, file := range plan.files {
+		state.Posts[id] = file
+	}
+	api := sync.api.WithContext(ctx)
+
+	for _, action := range plan.Actions {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		switch action.Op {
+		case "create":
+			created := api.CreatePost(sync.BlogHostname, action.params)
+			if err := failed(created.Meta, action); err != nil {
+				return result, err
+			}
+			action.ID = created.ID
+			result.Created++
+			// Recorded before anything else can fail, so a later sync updates the
+			// post rather than creating it again
+			state.Posts[action.ID] = action.File
+			if err := state.write(sync.State); err != nil {
+				return result, err
+			}
+		case "update":
+			if err := failed(api.PostEdit(sync.BlogHostname, action.ID, action.params), action); err != nil {
+				return result, err
+			}
+			result.Updated++
+		case "delete":
+			// A post deleted on Tumblr already is gone all the same
+			if meta := api.PostDelete(sync.BlogHostname, action.ID); meta.Status != http.StatusNotFound {
+				if err := failed(meta, action); err != nil {
+					return result, err
+				}
+			}
+			delete(state.Posts, action.ID)
+			result.Deleted++
+			sync.Logger.Info("post deleted", "post", action.ID, "file", action.File)
+			if err := state.write(sync.State); err != nil {
+				return result, err
+			}
+			continue
+		}
+
+		if err := sync.record(action); err != nil {
+			return result, err
+		}
+		state.Posts[action.ID] = action.File
+		sync.Logger.Info("post "+action.Op+"d", "post", action.ID, "file", action.File)
+		if err := state.write(sync.State); err != nil {
+			return result, err
+		}
+	}
+	return result, state.write(sync.State)
+}
+
+// This method returns an error when a request of an action failed
+func failed(meta tumblr.Meta, action SyncAction) error {
+	if meta.Status >= 200 && meta.Status < 300 {
+		return nil
+	}
+	return fmt.Errorf("markdown: %s of %s failed: %d %s", action.Op, action.File, meta.Status, meta.Msg)
+}
+
+// This method writes the ID and hash of a file's post into its front matter
+func (sync *Sync) record(action SyncAction) error {
+	path := filepath.Join(sync.Dir, filepath.FromSlash(action.File))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	doc, err := parseDocument(data)
+	if err != nil {
+		return err
+	}
+	doc.set("tumblr_id", strconv.Itoa(action.ID))
+	doc.set("tumblr_hash", strconv.Quote(action.hash))
+	return atomicfile.Write(path, bytes.NewReader(doc.bytes()))
+}
+
+// This method returns the parameters posting a file as a text post
+func postParams(doc *document) map[string]string {
+	front := doc.front
+	params := map[string]string{
+		"type":   "text",
+		"format": "markdown",
+		"body":   strings.TrimSpace(doc.body),
+		"state":  "published",
+	}
+	if front.Draft {
+		params["state"] = "draft"
+	}
+	if front.Title != "" {
+		params["title"] = front.Title
+	}
+	if len(front.Tags) > 0 {
+		params["tags"] = strings.Join(front.Tags, ",")
+	}
+	if front.Slug != "" {
+		params["slug"] = front.Slug
+	}
+	if !front.Date.IsZero() {
+		params["date"] = front.Date.UTC().Format("2006-01-02 15:04:05 GMT")
+	}
+	return params
+}
+
+// This method returns a hash of the parameters of a post, to tell whether its file
+// changed since it was posted
+func hashParams(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%s\x00", key, params[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
@@ -0,0 +1,147 @@
+package markdown
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattcunningham/gumblr/internal/tumblrtest"
+)
+
+func TestSync(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []string
+	var forms []url.Values
+	_, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		r.ParseForm()
+		requests = append(requests, strings.TrimPrefix(r.URL.Path, "/v2/blog/staff.tumblr.com")+" "+r.PostForm.Get("id"))
+		forms = append(forms, r.PostForm)
+		switch r.URL.Path {
+		case "/v2/blog/staff.tumblr.com/post":
+			fmt.Fprintf(w, `{"meta":{"status":201,"msg":"Created"},"response":{"id":%d}}`, 100+len(requests))
+		case "/v2/blog/staff.tumblr.com/post/edit", "/v2/blog/staff.tumblr.com/post/delete":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{}}`)
+		default:
+			t.Errorf("Requested %s", r.URL.Path)
+		}
+	}))
+
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("posts/hello.md", "---\ntitle: Hello\nauthor: jane\ntags: [go, tumblr]\ndate: 2024-05-01T10:00:00Z\n---\n\nHello *world*\n")
+	write("notes.md", "Just a note\n")
+	write("posts/photo.md", "---\ntumblr_type: \"photo\"\ntumblr_id: 5\n---\n")
+	write("_index.md", "Site index\n")
+	write(".git/README.md", "Not a post\n")
+
+	sync := NewSync(client, dir, "staff.tumblr.com")
+	plan, err := sync.Plan()
+	if err != nil || len(plan.Actions) != 2 || len(plan.Skipped) != 1 || plan.Actions[0].File != "notes.md" || plan.Actions[1].Op != "create" {
+		t.Fatalf("Plan returned %+v with error %v", plan, err)
+	}
+	if len(requests) != 0 {
+		t.Fatalf("Planning sent %v", requests)
+	}
+	result, err := sync.Apply(context.Background(), plan)
+	if err != nil || result.Created != 2 {
+		t.Fatalf("Apply returned %+v with error %v", result, err)
+	}
+	hello := forms[1]
+	if hello.Get("format") != "markdown" || hello.Get("title") != "Hello" || hello.Get("tags") != "go,tumblr" ||
+		hello.Get("body") != "Hello *world*" || hello.Get("date") != "2024-05-01 10:00:00 GMT" || hello.Get("state") != "published" {
+		t.Errorf("Post was created with %v", hello)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "posts", "hello.md"))
+	if !strings.Contains(string(data), "author: jane\ntags: [go, tumblr]\ndate: 2024-05-01T10:00:00Z\ntumblr_id: 102\ntumblr_hash: \"") {
+		t.Errorf("Post ID wasn't written back: %q", data)
+	}
+
+	// Nothing changed, nothing to do
+	if plan, err := sync.Plan(); err != nil || len(plan.Actions) != 0 || plan.Unchanged != 2 {
+		t.Errorf("Second plan returned %+v with error %v", plan, err)
+	}
+
+	// Edits update their post, and removed files delete theirs
+	os.WriteFile(filepath.Join(dir, "posts", "hello.md"), []byte(strings.Replace(string(data), "Hello *world*", "Hello again", 1)), 0644)
+	os.Remove(filepath.Join(dir, "notes.md"))
+	requests = nil
+	sync.Delete = true
+	plan, err = sync.Plan()
+	if err != nil || len(plan.Actions) != 2 {
+		t.Fatalf("Plan after edits returned %+v with error %v", plan, err)
+	}
+	if _, err := sync.Apply(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(requests, ",") != "/post/edit 102,/post/delete 101" {
+		t.Errorf("Applying edits sent %v", requests)
+	}
+	if plan, err := sync.Plan(); err != nil || len(plan.Actions) != 0 {
+		t.Errorf("Plan after applying edits returned %+v with error %v", plan, err)
+	}
+
+	if _, err := NewSync(client, dir, "other.tumblr.com").Plan(); err == nil {
+		t.Error("Directory synced to another blog was planned")
+	}
+}
+
+func TestSyncAfterFailedRecord(t *testing.T) {
+	var requests []string
+	_, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests = append(requests, strings.TrimPrefix(r.URL.Path, "/v2/blog/staff.tumblr.com")+" "+r.PostForm.Get("id"))
+		if r.URL.Path == "/v2/blog/staff.tumblr.com/post" {
+			fmt.Fprint(w, `{"meta":{"status":201,"msg":"Created"},"response":{"id":42}}`)
+			return
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{}}`)
+	}))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.md")
+	content := []byte("---\ntitle: Hello\n---\n\nHello world\n")
+	os.WriteFile(path, content, 0644)
+	sync := NewSync(client, dir, "staff.tumblr.com")
+	plan, err := sync.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The post is created, but its ID can't be written into the broken file
+	os.WriteFile(path, []byte("---\ntitle: Hello\n"), 0644)
+	if _, err := sync.Apply(context.Background(), plan); err == nil {
+		t.Fatal("Apply wrote the ID into a broken file")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Directory holds %v", entries)
+	}
+
+	// The next sync updates the created post instead of creating another
+	os.WriteFile(path, content, 0644)
+	plan, err = sync.Plan()
+	if err != nil || len(plan.Actions) != 1 || plan.Actions[0].Op != "update" || plan.Actions[0].ID != 42 {
+		t.Fatalf("Plan after the failure returned %+v with error %v", plan, err)
+	}
+	if _, err := sync.Apply(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(requests, ",") != "/post ,/post/edit 42" {
+		t.Errorf("Syncs sent %v", requests)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "tumblr_id: 42\n") {
+		t.Errorf("Post ID wasn't written back: %q", data)
+	}
+}
@@ -0,0 +1,237 @@
+package tumblr
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A MetricsCollector receives measurements of a client's API usage. Implementations
+// must be safe for concurrent use.
+type MetricsCollector interface {
+	// ObserveRequest records one attempt of a request, with status 0 when no response was received
+	ObserveRequest(endpoint string, status int, latency time.Duration)
+	// IncRetries records that a request to endpoint is being retried
+	IncRetries(endpoint string)
+	// ObserveCache records whether a GET request to a cached endpoint was served from the cache
+	ObserveCache(endpoint string, hit bool)
+	// SetRateLimitRemaining records the requests left in one of Tumblr's rate limits, e.g. perday or perhour
+	SetRateLimitRemaining(limit string, remaining int)
+}
+
+// The collector used when no metrics are set
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(string, int, time.Duration) {}
+func (nopMetrics) IncRetries(string)                         {}
+func (nopMetrics) ObserveCache(string, bool)                 {}
+func (nopMetrics) SetRateLimitRemaining(string, int)         {}
+
+// This method sets the collector that receives measurements of every request:
+// counts and latencies by endpoint and status, retries, cache hits and misses,
+// and the remaining requests reported in Tumblr's rate limit headers.
+// collector - The collector to use, e.g. a PrometheusCollector, nil disables metrics
+func (api *Tumblr) SetMetrics(collector MetricsCollector) {
+	if collector == nil {
+		collector = nopMetrics{}
+	}
+	api.metrics = collector
+}
+
+// This method records the rate limits reported by Tumblr in headers such as
+// X-Ratelimit-Perday-Remaining
+// header - The response headers
+func (api *Tumblr) observeRateLimits(header http.Header) {
+	for key, values := range header {
+		if !strings.HasPrefix(key, "X-Ratelimit-") || !strings.HasSuffix(key, "-Remaining") || len(values) == 0 {
+			continue
+		}
+		remaining, err := strconv.Atoi(values[0])
+		if err != nil {
+			continue
+		}
+		limit := strings.TrimSuffix(strings.TrimPrefix(key, "X-Ratelimit-"), "-Remaining")
+		api.metrics.SetRateLimitRemaining(strings.ToLower(limit), remaining)
+	}
+}
+
+// The upper bounds, in seconds, of the request latency histogram buckets
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// A MetricsCollector that exports its measurements in the Prometheus text format.
+// It is an http.Handler, so it can be served on a local metrics endpoint:
+//
+//	collector := tumblr.NewPrometheusCollector()
+//	client.SetMetrics(collector)
+//	http.Handle("/metrics", collector)
+type PrometheusCollector struct {
+	mutex     sync.Mutex
+	buckets   []float64
+	requests  map[[2]string]*histogram // by endpoint and status
+	retries   map[string]float64       // by endpoint
+	cacheHits map[[2]string]float64    // by endpoint and result
+	rateLimit map[string]float64       // by limit
+}
+
+type histogram struct {
+	counts []float64 // cumulative count per bucket
+	sum    float64
+	count  float64
+}
+
+// This method creates a collector with the DefaultLatencyBuckets
+func NewPrometheusCollector() *PrometheusCollector {
+	return &PrometheusCollector{
+		buckets:   DefaultLatencyBuckets,
+		requests:  make(map[[2]string]*histogram),
+		retries:   make(map[string]float64),
+		cacheHits: make(map[[2]string]float64),
+		rateLimit: make(map[string]float64),
+	}
+}
+
+func (collector *PrometheusCollector) ObserveRequest(endpoint string, status int, latency time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	if status == 0 {
+		statusLabel = "error"
+	}
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	key := [2]string{endpoint, statusLabel}
+	requests, found := collector.requests[key]
+	if !found {
+		requests = &histogram{counts: make([]float64, len(collector.buckets))}
+		collector.requests[key] = requests
+	}
+	seconds := latency.Seconds()
+	for i, bound := range collector.buckets {
+		if seconds <= bound {
+			requests.counts[i]++
+		}
+	}
+	requests.sum += seconds
+	requests.count++
+}
+
+func (collector *PrometheusCollector) IncRetries(endpoint string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.retries[endpoint]++
+}
+
+func (collector *PrometheusCollector) ObserveCache(endpoint string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.cacheHits[[2]string{endpoint, result}]++
+}
+
+func (collector *PrometheusCollector) SetRateLimitRemaining(limit string, remaining int) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.rateLimit[limit] = float64(remaining)
+}
+
+// This method writes the measurements in the Prometheus text exposition format
+// w - The writer to write to
+func (collector *PrometheusCollector) WriteTo(w io.Writer) (int64, error) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	var out strings.Builder
+
+	out.WriteString("# HELP gumblr_requests_total Tumblr API requests by endpoint and status.\n")
+	out.WriteString("# TYPE gumblr_requests_total counter\n")
+	var requestKeys [][2]string
+	for key := range collector.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sortPairs(requestKeys)
+	for _, key := range requestKeys {
+		fmt.Fprintf(&out, "gumblr_requests_total{endpoint=%s,status=%s} %s\n",
+			quoteLabel(key[0]), quoteLabel(key[1]), formatValue(collector.requests[key].count))
+	}
+
+	out.WriteString("# HELP gumblr_request_duration_seconds Tumblr API request latency by endpoint and status.\n")
+	out.WriteString("# TYPE gumblr_request_duration_seconds histogram\n")
+	for _, key := range requestKeys {
+		requests := collector.requests[key]
+		labels := "endpoint=" + quoteLabel(key[0]) + ",status=" + quoteLabel(key[1])
+		for i, bound := range collector.buckets {
+			fmt.Fprintf(&out, "gumblr_request_duration_seconds_bucket{%s,le=%s} %s\n",
+				labels, quoteLabel(formatValue(bound)), formatValue(requests.counts[i]))
+		}
+		fmt.Fprintf(&out, "gumblr_request_duration_seconds_bucket{%s,le=\"+Inf\"} %s\n", labels, formatValue(requests.count))
+		fmt.Fprintf(&out, "gumblr_request_duration_seconds_sum{%s} %s\n", labels, formatValue(requests.sum))
+		fmt.Fprintf(&out, "gumblr_request_duration_seconds_count{%s} %s\n", labels, formatValue(requests.count))
+	}
+
+	out.WriteString("# HELP gumblr_retries_total Tumblr API requests retried after being rate limited.\n")
+	out.WriteString("# TYPE gumblr_retries_total counter\n")
+	for _, endpoint := range sortedLabels(collector.retries) {
+		fmt.Fprintf(&out, "gumblr_retries_total{endpoint=%s} %s\n", quoteLabel(endpoint), formatValue(collector.retries[endpoint]))
+	}
+
+	out.WriteString("# HELP gumblr_cache_requests_total Cacheable Tumblr API requests by endpoint and cache result.\n")
+	out.WriteString("# TYPE gumblr_cache_requests_total counter\n")
+	var cacheKeys [][2]string
+	for key := range collector.cacheHits {
+		cacheKeys = append(cacheKeys, key)
+	}
+	for _, key := range sortPairs(cacheKeys) {
+		fmt.Fprintf(&out, "gumblr_cache_requests_total{endpoint=%s,result=%s} %s\n",
+			quoteLabel(key[0]), quoteLabel(key[1]), formatValue(collector.cacheHits[key]))
+	}
+
+	out.WriteString("# HELP gumblr_rate_limit_remaining Requests remaining in a Tumblr rate limit.\n")
+	out.WriteString("# TYPE gumblr_rate_limit_remaining gauge\n")
+	for _, limit := range sortedLabels(collector.rateLimit) {
+		fmt.Fprintf(&out, "gumblr_rate_limit_remaining{limit=%s} %s\n", quoteLabel(limit), formatValue(collector.rateLimit[limit]))
+	}
+
+	n, err := io.WriteString(w, out.String())
+	return int64(n), err
+}
+
+func (collector *PrometheusCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	collector.WriteTo(w)
+}
+
+// This method sorts label pairs by their first, then second value
+func sortPairs(pairs [][2]string) [][2]string {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+// This method returns the labels of a gauge or counter in order
+func sortedLabels(values map[string]float64) []string {
+	labels := make([]string, 0, len(values))
+	for label := range values {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// This method quotes a label value, escaping it as the text format requires
+func quoteLabel(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,83 @@
+package tumblr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrometheusCollector(t *testing.T) {
+	var posts int32
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Perday-Remaining", "4999")
+		w.Header().Set("X-Ratelimit-Perhour-Remaining", "999")
+		if strings.HasSuffix(r.URL.Path, "/posts") && atomic.AddInt32(&posts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"meta":{"status":429,"msg":"Limit Exceeded"},"response":[]}`)
+			return
+		}
+		fmt.Fprint(w, testBlogInfo)
+	})
+	defer server.Close()
+
+	collector := NewPrometheusCollector()
+	client := newTestClient(server)
+	client.SetMetrics(collector)
+	client.SetCache(NewMemoryCache(10), map[string]time.Duration{"blog/info": time.Hour})
+	client.BlogInfo("staff.tumblr.com")
+	client.BlogInfo("staff.tumblr.com")
+	client.BlogPosts("staff.tumblr.com", nil)
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Metrics were served as %s", recorder.Header().Get("Content-Type"))
+	}
+	output := recorder.Body.String()
+	for _, line := range []string{
+		`gumblr_requests_total{endpoint="blog/info",status="200"} 1`,
+		`gumblr_requests_total{endpoint="blog/posts",status="200"} 1`,
+		`gumblr_requests_total{endpoint="blog/posts",status="429"} 1`,
+		`gumblr_request_duration_seconds_bucket{endpoint="blog/info",status="200",le="+Inf"} 1`,
+		`gumblr_request_duration_seconds_count{endpoint="blog/posts",status="429"} 1`,
+		`gumblr_retries_total{endpoint="blog/posts"} 1`,
+		`gumblr_cache_requests_total{endpoint="blog/info",result="hit"} 1`,
+		`gumblr_cache_requests_total{endpoint="blog/info",result="miss"} 1`,
+		`gumblr_rate_limit_remaining{limit="perday"} 4999`,
+		`gumblr_rate_limit_remaining{limit="perhour"} 999`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Metrics are missing %s", line)
+		}
+	}
+}
+
+func TestPrometheusHistogram(t *testing.T) {
+	collector := NewPrometheusCollector()
+	collector.ObserveRequest("user/info", 0, 200*time.Millisecond)
+	collector.ObserveRequest("user/info", 0, 3*time.Second)
+	var output strings.Builder
+	collector.WriteTo(&output)
+	for _, line := range []string{
+		`gumblr_request_duration_seconds_bucket{endpoint="user/info",status="error",le="0.1"} 0`,
+		`gumblr_request_duration_seconds_bucket{endpoint="user/info",status="error",le="0.25"} 1`,
+		`gumblr_request_duration_seconds_bucket{endpoint="user/info",status="error",le="5"} 2`,
+		`gumblr_request_duration_seconds_sum{endpoint="user/info",status="error"} 3.2`,
+		`gumblr_request_duration_seconds_count{endpoint="user/info",status="error"} 2`,
+	} {
+		if !strings.Contains(output.String(), line+"\n") {
+			t.Errorf("Histogram is missing %s", line)
+		}
+	}
+}
+
+func TestQuoteLabel(t *testing.T) {
+	if quoted := quoteLabel("a\"b\\c\nd"); quoted != `"a\"b\\c\nd"` {
+		t.Errorf("Label was quoted as %s", quoted)
+	}
+}
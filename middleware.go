@@ -0,0 +1,47 @@
+package tumblr
+
+import "net/http"
+
+// A RoundTrip sends a request and returns its response
+type RoundTrip func(request *http.Request) (*http.Response, error)
+
+// A Middleware wraps the RoundTrip of every request a client sends. It may inspect
+// or change the request, return its own response or error instead of calling next,
+// or inspect the response, e.g. for logging, metrics, tracing, header injection or
+// fault injection.
+type Middleware func(next RoundTrip) RoundTrip
+
+// This method adds middleware to the chain every request is sent through. The first
+// middleware added is the outermost. Signing the request is the innermost step, so
+// changes made by middleware are covered by the OAuth signature. Every attempt of a
+// retried request goes through the chain; responses served from the cache don't.
+// middleware - The middleware to add
+func (api *Tumblr) Use(middleware ...Middleware) {
+	api.middleware = append(api.middleware[:len(api.middleware):len(api.middleware)], middleware...)
+}
+
+// This method returns the middleware chain ending with the circuit breaker, signing
+// the request, dumping it when debugging and sending it with the shared HTTP client
+func (api *Tumblr) roundTrip() RoundTrip {
+	roundTrip := api.breaker.guard(api.sign(api.dump(api.client.Do)))
+	for i := len(api.middleware) - 1; i >= 0; i-- {
+		roundTrip = api.middleware[i](roundTrip)
+	}
+	return roundTrip
+}
+
+// This middleware signs requests with the client's OAuth credentials, or sets
+// its OAuth 2.0 access token
+// next - The rest of the chain
+func (api *Tumblr) sign(next RoundTrip) RoundTrip {
+	return func(request *http.Request) (*http.Response, error) {
+		if api.oauth2Token != "" {
+			request.Header.Set("Authorization", "Bearer "+api.oauth2Token)
+			return next(request)
+		}
+		if err := api.oauthService.Sign(request, &api.config); err != nil {
+			return nil, err
+		}
+		return next(request)
+	}
+}
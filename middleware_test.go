@@ -0,0 +1,86 @@
+package tumblr
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Team") != "crawler" {
+			t.Error("Injected header was not sent")
+		}
+		if r.URL.Query().Get("limit") != "5" {
+			t.Error("Mutated query was not sent")
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[]}}`)
+	})
+	defer server.Close()
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next RoundTrip) RoundTrip {
+			return func(request *http.Request) (*http.Response, error) {
+				if request.Header.Get("Authorization") != "" {
+					t.Errorf("Middleware %s saw a signed request", name)
+				}
+				calls = append(calls, name+" request")
+				response, err := next(request)
+				calls = append(calls, fmt.Sprintf("%s response %d", name, response.StatusCode))
+				return response, err
+			}
+		}
+	}
+	injectHeader := func(next RoundTrip) RoundTrip {
+		return func(request *http.Request) (*http.Response, error) {
+			request.Header.Set("X-Team", "crawler")
+			query := request.URL.Query()
+			query.Set("limit", "5")
+			request.URL.RawQuery = query.Encode()
+			return next(request)
+		}
+	}
+
+	client := newTestClient(server)
+	client.Use(trace("outer"), injectHeader)
+	client.Use(trace("inner"))
+	client.UserDashboard(map[string]string{"limit": "20"})
+
+	expected := "outer request,inner request,inner response 200,outer response 200"
+	if strings.Join(calls, ",") != expected {
+		t.Errorf("Middleware was called in order %v", calls)
+	}
+}
+
+func TestMiddlewareFaultInjection(t *testing.T) {
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request reached the server")
+	})
+	defer server.Close()
+
+	fault := errors.New("injected fault")
+	client := newTestClient(server)
+	client.Use(func(next RoundTrip) RoundTrip {
+		return func(request *http.Request) (*http.Response, error) {
+			return nil, fault
+		}
+	})
+	if _, err := client.blogInfo("staff.tumblr.com"); !errors.Is(err, fault) {
+		t.Errorf("Request returned %v instead of the injected fault", err)
+	}
+
+	stubbed := newTestClient(server)
+	stubbed.Use(func(next RoundTrip) RoundTrip {
+		return func(request *http.Request) (*http.Response, error) {
+			body := `{"meta":{"status":201,"msg":"Created"},"response":{}}`
+			return &http.Response{StatusCode: 201, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+		}
+	})
+	if response := stubbed.Post("staff.tumblr.com", nil); response.Status != 201 {
+		t.Errorf("Stubbed response returned %d instead of 201", response.Status)
+	}
+}
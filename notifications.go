@@ -0,0 +1,121 @@
+package tumblr
+
+import (
+	"strconv"
+	"time"
+)
+
+// NotificationPoller fetches a blog's notifications and hands back only the ones
+// that arrived after the last notification it has seen.
+type NotificationPoller struct {
+	BlogHostname string            // The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+	Params       map[string]string // Extra BlogNotifications params, e.g. types
+	LastSeen     int               // The timestamp of the newest notification seen, in seconds since the epoch
+
+	api  *Tumblr
+	seen map[string]bool // IDs of the notifications seen at the LastSeen timestamp
+}
+
+// This method creates a poller for a blog's notifications.
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// params - BlogNotifications params applied to every poll (before is managed by the poller)
+// lastSeen - The timestamp of the newest notification already handled, or 0 to start with the most recent page
+func (api *Tumblr) NewNotificationPoller(blogHostname string, params map[string]string, lastSeen int) *NotificationPoller {
+	return &NotificationPoller{
+		BlogHostname: blogHostname,
+		Params:       params,
+		LastSeen:     lastSeen,
+		api:          api,
+		seen:         make(map[string]bool),
+	}
+}
+
+// This method returns the notifications that arrived since the last poll, oldest first.
+// It pages back with before until it reaches LastSeen. With a zero LastSeen only the
+// most recent page is returned, so that a new poller doesn't walk the blog's entire history.
+func (poller *NotificationPoller) Poll() []Notification {
+	var fresh []Notification
+	found := make(map[string]bool) // IDs of the notifications on the pages read so far
+	before := 0
+	for {
+		params := make(map[string]string)
+		for key, value := range poller.Params {
+			params[key] = value
+		}
+		if before > 0 {
+			params["before"] = strconv.Itoa(before)
+		}
+		page := poller.api.BlogNotifications(poller.BlogHostname, params).Notifications
+		reachedLastSeen, added := false, 0
+		for _, notification := range page {
+			if found[notification.ID] {
+				continue
+			}
+			found[notification.ID] = true
+			added++
+			if notification.Timestamp < poller.LastSeen ||
+				(notification.Timestamp == poller.LastSeen && poller.seen[notification.ID]) {
+				reachedLastSeen = true
+				continue
+			}
+			fresh = append(fresh, notification)
+		}
+		if len(page) == 0 || reachedLastSeen || poller.LastSeen == 0 {
+			break
+		}
+		// Notifications sharing the oldest timestamp may continue on the next page,
+		// so it starts one second later, unless that would fetch the same page again
+		oldest := page[len(page)-1].Timestamp
+		next := oldest + 1
+		if added == 0 || next == before {
+			next = oldest
+		}
+		if before > 0 && next >= before {
+			break
+		}
+		before = next
+	}
+
+	// Notifications come newest first; hand them back in the order they happened.
+	for i, j := 0, len(fresh)-1; i < j; i, j = i+1, j-1 {
+		fresh[i], fresh[j] = fresh[j], fresh[i]
+	}
+	for _, notification := range fresh {
+		if notification.Timestamp > poller.LastSeen {
+			poller.LastSeen = notification.Timestamp
+			poller.seen = make(map[string]bool)
+		}
+		if notification.Timestamp == poller.LastSeen {
+			poller.seen[notification.ID] = true
+		}
+	}
+	return fresh
+}
+
+// This method polls every interval and sends new notifications on the returned channel,
+// which is closed once stop is closed.
+// interval - The time to wait between polls
+// stop - Closing this channel stops the poller
+func (poller *NotificationPoller) Run(interval time.Duration, stop <-chan struct{}) <-chan Notification {
+	notifications := make(chan Notification)
+	go func() {
+		defer close(notifications)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for _, notification := range poller.Poll() {
+				select {
+				case notifications <- notification:
+				case <-stop:
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return notifications
+}
@@ -0,0 +1,86 @@
+package tumblr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A blog's notifications served newest first, two per page
+type testNotifications struct {
+	sync.Mutex
+	notifications []Notification
+}
+
+func (n *testNotifications) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.Lock()
+	defer n.Unlock()
+	before, _ := strconv.Atoi(r.URL.Query().Get("before"))
+	page := []Notification{}
+	for _, notification := range n.notifications {
+		if (before == 0 || notification.Timestamp < before) && len(page) < 2 {
+			page = append(page, notification)
+		}
+	}
+	data, _ := json.Marshal(map[string]interface{}{"meta": Meta{Status: 200, Msg: "OK"}, "response": map[string]interface{}{"notifications": page}})
+	w.Write(data)
+}
+
+func (n *testNotifications) add(notifications ...Notification) {
+	n.Lock()
+	defer n.Unlock()
+	n.notifications = append(notifications, n.notifications...)
+}
+
+// This method returns the IDs of notifications
+func notificationIDs(notifications []Notification) string {
+	var ids []string
+	for _, notification := range notifications {
+		ids = append(ids, notification.ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestNotificationPollerTies(t *testing.T) {
+	// b and c share a timestamp across the boundary of the first two pages
+	blog := &testNotifications{notifications: []Notification{
+		{ID: "a", Timestamp: 500}, {ID: "b", Timestamp: 400}, {ID: "c", Timestamp: 400}, {ID: "e", Timestamp: 300}, {ID: "f", Timestamp: 200},
+	}}
+	server := newTestServer(false, blog.ServeHTTP)
+	defer server.Close()
+	client := newTestClient(server)
+
+	poller := client.NewNotificationPoller("staff.tumblr.com", nil, 250)
+	if fresh := notificationIDs(poller.Poll()); fresh != "e c b a" || poller.LastSeen != 500 {
+		t.Errorf("Poll returned %q, leaving LastSeen %d", fresh, poller.LastSeen)
+	}
+
+	// A notification sharing the timestamp of the newest one seen is new all the same
+	stop := make(chan struct{})
+	notifications := client.NewNotificationPoller("staff.tumblr.com", nil, 250).Run(time.Millisecond, stop)
+	var received []Notification
+	receive := func(count int) {
+		for len(received) < count {
+			select {
+			case notification := <-notifications:
+				received = append(received, notification)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Run sent only %q", notificationIDs(received))
+			}
+		}
+	}
+	receive(4)
+	blog.add(Notification{ID: "h", Timestamp: 600}, Notification{ID: "g", Timestamp: 500})
+	receive(6)
+	close(stop)
+	for notification := range notifications {
+		received = append(received, notification)
+	}
+	if ids := notificationIDs(received); ids != "e c b a g h" {
+		t.Errorf("Run sent %q", ids)
+	}
+}
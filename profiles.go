@@ -0,0 +1,363 @@
+package tumblr
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultProfileName = "default"            // the profile used when none is named
+	encryptedPrefix    = "enc:"               // prefix of an encrypted secret in a config file
+	pbkdf2Iterations   = 600000               // PBKDF2 iterations used when encrypting a config file
+	pbkdf2KDF          = "pbkdf2-sha256"      // the key derivation function of encrypted config files
+	configFileName     = "gumblr/config.json" // the config file, relative to the user config directory
+)
+
+// Returned when a config file with encrypted secrets is loaded without a passphrase
+var ErrPassphraseRequired = errors.New("tumblr: config file is encrypted, a passphrase is required")
+
+// Returned when the secrets of a config file can't be decrypted with the passphrase given
+var ErrWrongPassphrase = errors.New("tumblr: wrong passphrase for config file")
+
+// The credentials and settings of one Tumblr account
+type Profile struct {
+	Name               string `json:"-"`                              // The name of the profile
+	ConsumerKey        string `json:"consumer_key,omitempty"`         // The application's consumer key
+	ConsumerSecret     string `json:"consumer_secret,omitempty"`      // The application's consumer secret
+	OAuthToken         string `json:"oauth_token,omitempty"`          // The user's OAuth 1.0a token
+	OAuthTokenSecret   string `json:"oauth_token_secret,omitempty"`   // The user's OAuth 1.0a token secret
+	OAuth2AccessToken  string `json:"oauth2_access_token,omitempty"`  // An OAuth 2.0 access token, used instead of the OAuth 1.0a token
+	OAuth2RefreshToken string `json:"oauth2_refresh_token,omitempty"` // The OAuth 2.0 refresh token
+	OAuth2ExpiresAt    int    `json:"oauth2_expires_at,omitempty"`    // When the access token expires, in seconds since the epoch
+	DefaultBlog        string `json:"default_blog,omitempty"`         // The blog hostname commands write to by default
+}
+
+// The secret fields of a profile, encrypted when the config file has a passphrase
+func (profile *Profile) secrets() map[string]*string {
+	return map[string]*string{
+		"consumer_key":         &profile.ConsumerKey,
+		"consumer_secret":      &profile.ConsumerSecret,
+		"oauth_token":          &profile.OAuthToken,
+		"oauth_token_secret":   &profile.OAuthTokenSecret,
+		"oauth2_access_token":  &profile.OAuth2AccessToken,
+		"oauth2_refresh_token": &profile.OAuth2RefreshToken,
+	}
+}
+
+// A config file holding named profiles:
+//
+//	{
+//	  "default_profile": "work",
+//	  "profiles": {
+//	    "work": {
+//	      "consumer_key": "...",
+//	      "consumer_secret": "...",
+//	      "oauth_token": "...",
+//	      "oauth_token_secret": "...",
+//	      "default_blog": "staff.tumblr.com"
+//	    }
+//	  }
+//	}
+//
+// When the config has a passphrase its secrets are stored encrypted with AES-GCM,
+// under a key derived from the passphrase with PBKDF2.
+type Config struct {
+	DefaultProfile string              `json:"default_profile,omitempty"` // The profile used when none is named
+	Profiles       map[string]*Profile `json:"profiles"`                  // The profiles by name
+	Encryption     *ConfigEncryption   `json:"encryption,omitempty"`      // How the secrets are encrypted, nil when they aren't
+	passphrase     string
+}
+
+// The key derivation parameters of an encrypted config file
+type ConfigEncryption struct {
+	KDF        string `json:"kdf"`        // The key derivation function, pbkdf2-sha256
+	Iterations int    `json:"iterations"` // The number of PBKDF2 iterations
+	Salt       []byte `json:"salt"`       // The PBKDF2 salt
+}
+
+// This method returns the path of the config file: $GUMBLR_CONFIG, or gumblr/config.json
+// in the user's config directory (e.g. ~/.config on Linux)
+// getenv - Looks up environment variables, e.g. os.Getenv
+func DefaultConfigPath(getenv func(string) string) (string, error) {
+	if path := getenv("GUMBLR_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(configFileName)), nil
+}
+
+// This method reads a config file, decrypting its secrets
+// path - The path of the config file
+// passphrase - The passphrase of the config file, "" if its secrets aren't encrypted
+func LoadConfig(path string, passphrase string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("tumblr: config file %s: %w", path, err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]*Profile)
+	}
+	for name, profile := range config.Profiles {
+		if profile == nil {
+			profile = &Profile{}
+			config.Profiles[name] = profile
+		}
+		profile.Name = name
+	}
+	if config.Encryption == nil {
+		return config, nil
+	}
+
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	gcm, err := config.Encryption.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	for name, profile := range config.Profiles {
+		for field, value := range profile.secrets() {
+			if *value, err = decryptSecret(gcm, name+"/"+field, *value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	config.Encryption = nil
+	config.passphrase = passphrase
+	return config, nil
+}
+
+// This method sets the passphrase the secrets are encrypted with when the config is saved
+// passphrase - The new passphrase, "" to store the secrets unencrypted
+func (config *Config) SetPassphrase(passphrase string) {
+	config.passphrase = passphrase
+}
+
+// This method reports whether the secrets are encrypted when the config is saved
+func (config *Config) Encrypted() bool {
+	return config.passphrase != ""
+}
+
+// This method writes the config file, readable only by the user, encrypting the
+// secrets when the config has a passphrase
+// path - The path of the config file
+func (config *Config) Save(path string) error {
+	saved := Config{DefaultProfile: config.DefaultProfile, Profiles: make(map[string]*Profile)}
+	for name, profile := range config.Profiles {
+		copied := *profile
+		saved.Profiles[name] = &copied
+	}
+	if config.passphrase != "" {
+		saved.Encryption = &ConfigEncryption{KDF: pbkdf2KDF, Iterations: pbkdf2Iterations, Salt: make([]byte, 16)}
+		if _, err := rand.Read(saved.Encryption.Salt); err != nil {
+			return err
+		}
+		gcm, err := saved.Encryption.cipher(config.passphrase)
+		if err != nil {
+			return err
+		}
+		for name, profile := range saved.Profiles {
+			for field, value := range profile.secrets() {
+				if *value, err = encryptSecret(gcm, name+"/"+field, *value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so the config is never left half written.
+	temp := path + ".tmp"
+	if err := ioutil.WriteFile(temp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// This method returns a profile by name
+// name - The profile name, "" for the default profile
+func (config *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = config.DefaultProfile
+	}
+	if name == "" {
+		name = defaultProfileName
+	}
+	profile, found := config.Profiles[name]
+	if !found {
+		return nil, fmt.Errorf("tumblr: no profile named %q", name)
+	}
+	return profile, nil
+}
+
+// This method adds or replaces a profile
+// profile - The profile, stored under its name
+func (config *Config) SetProfile(profile Profile) {
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]*Profile)
+	}
+	if profile.Name == "" {
+		profile.Name = defaultProfileName
+	}
+	config.Profiles[profile.Name] = &profile
+}
+
+// This method returns the names of the profiles in order
+func (config *Config) ProfileNames() []string {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// This method loads a profile from the default config file, decrypted with
+// $GUMBLR_PASSPHRASE, and applies the environment overrides (see LoadProfileEnv)
+// name - The profile name, "" for $GUMBLR_PROFILE or the config's default profile
+func LoadProfile(name string) (*Profile, error) {
+	return LoadProfileEnv(name, os.Getenv)
+}
+
+// This method loads a profile like LoadProfile, looking up environment variables
+// with getenv. These variables override the profile's fields:
+//
+//	TUMBLR_CONSUMER_KEY, TUMBLR_CONSUMER_SECRET, TUMBLR_OAUTH_TOKEN,
+//	TUMBLR_OAUTH_TOKEN_SECRET, TUMBLR_OAUTH2_ACCESS_TOKEN, TUMBLR_BLOG
+//
+// When there is no config file, the profile is made from the environment alone.
+// name - The profile name, "" for $GUMBLR_PROFILE or the config's default profile
+// getenv - Looks up environment variables, e.g. os.Getenv
+func LoadProfileEnv(name string, getenv func(string) string) (*Profile, error) {
+	if name == "" {
+		name = getenv("GUMBLR_PROFILE")
+	}
+	path, err := DefaultConfigPath(getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{Name: name}
+	config, err := LoadConfig(path, getenv("GUMBLR_PASSPHRASE"))
+	switch {
+	case err == nil:
+		stored, err := config.Profile(name)
+		if err != nil && (name != "" || config.DefaultProfile != "") {
+			return nil, err
+		}
+		if stored != nil {
+			copied := *stored
+			profile = &copied
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	case name != "":
+		return nil, fmt.Errorf("tumblr: no profile named %q, %s does not exist", name, path)
+	}
+
+	overrides := map[string]*string{
+		"TUMBLR_CONSUMER_KEY":        &profile.ConsumerKey,
+		"TUMBLR_CONSUMER_SECRET":     &profile.ConsumerSecret,
+		"TUMBLR_OAUTH_TOKEN":         &profile.OAuthToken,
+		"TUMBLR_OAUTH_TOKEN_SECRET":  &profile.OAuthTokenSecret,
+		"TUMBLR_OAUTH2_ACCESS_TOKEN": &profile.OAuth2AccessToken,
+		"TUMBLR_BLOG":                &profile.DefaultBlog,
+	}
+	for variable, field := range overrides {
+		if value := getenv(variable); value != "" {
+			*field = value
+		}
+	}
+	return profile, nil
+}
+
+// This method creates a client with a profile's credentials, authorized with its
+// OAuth 2.0 access token when it has one
+// profile - The profile
+func NewFromProfile(profile *Profile) *Tumblr {
+	client := New(profile.ConsumerKey, profile.ConsumerSecret, profile.OAuthToken, profile.OAuthTokenSecret)
+	if profile.OAuth2AccessToken != "" {
+		client.SetOAuth2Token(profile.OAuth2AccessToken)
+	}
+	return client
+}
+
+// This method derives the cipher of an encrypted config file from its passphrase
+// passphrase - The passphrase
+func (encryption *ConfigEncryption) cipher(passphrase string) (cipher.AEAD, error) {
+	if encryption.KDF != pbkdf2KDF {
+		return nil, fmt.Errorf("tumblr: unsupported config key derivation function %q", encryption.KDF)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, encryption.Salt, encryption.Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// This method encrypts a secret, bound to where it is stored so it can't be moved
+// gcm - The cipher
+// location - The profile and field the secret is stored in
+// secret - The secret
+func encryptSecret(gcm cipher.AEAD, location string, secret string) (string, error) {
+	if secret == "" {
+		return "", nil
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), []byte(location))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// This method decrypts a secret encrypted by encryptSecret
+// gcm - The cipher
+// location - The profile and field the secret is stored in
+// value - The stored value
+func decryptSecret(gcm cipher.AEAD, location string, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return "", fmt.Errorf("tumblr: %s is not encrypted in an encrypted config file", location)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("tumblr: %s is malformed", location)
+	}
+	secret, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(location))
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(secret), nil
+}
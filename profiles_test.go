@@ -0,0 +1,135 @@
+package tumblr
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testConfig() *Config {
+	config := &Config{DefaultProfile: "work"}
+	config.SetProfile(Profile{
+		Name:             "work",
+		ConsumerKey:      "work-consumer-key",
+		ConsumerSecret:   "work-consumer-secret",
+		OAuthToken:       "work-token",
+		OAuthTokenSecret: "work-token-secret",
+		DefaultBlog:      "staff.tumblr.com",
+	})
+	config.SetProfile(Profile{Name: "bot", ConsumerKey: "bot-consumer-key", OAuth2AccessToken: "bot-access-token"})
+	return config
+}
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gumblr", "config.json")
+	if err := testConfig().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Config file was saved with mode %v and error %v", info.Mode(), err)
+	}
+
+	config, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(config.ProfileNames(), ","); names != "bot,work" {
+		t.Errorf("Loaded profiles %s", names)
+	}
+	profile, err := config.Profile("")
+	if err != nil || profile.Name != "work" || profile.OAuthTokenSecret != "work-token-secret" || profile.DefaultBlog != "staff.tumblr.com" {
+		t.Errorf("Default profile loaded as %+v with error %v", profile, err)
+	}
+	if _, err := config.Profile("missing"); err == nil {
+		t.Error("Missing profile was found")
+	}
+}
+
+func TestEncryptedConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := testConfig()
+	config.SetPassphrase("correct horse")
+	if err := config.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	for _, secret := range []string{"work-consumer-secret", "work-token", "bot-access-token"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Encrypted config file contains %s", secret)
+		}
+	}
+	if !strings.Contains(string(data), "staff.tumblr.com") {
+		t.Error("Default blog was encrypted")
+	}
+
+	if _, err := LoadConfig(path, ""); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("Loading without a passphrase returned %v", err)
+	}
+	if _, err := LoadConfig(path, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Loading with a wrong passphrase returned %v", err)
+	}
+	loaded, err := LoadConfig(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile, _ := loaded.Profile("bot"); profile.OAuth2AccessToken != "bot-access-token" || !loaded.Encrypted() {
+		t.Errorf("Decrypted bot profile is %+v", profile)
+	}
+
+	// Secrets can't be moved to another profile or field
+	swapped := strings.Replace(string(data), `"bot": {`, `"tob": {`, 1)
+	ioutil.WriteFile(path, []byte(swapped), 0600)
+	if _, err := LoadConfig(path, "correct horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Loading a moved secret returned %v", err)
+	}
+}
+
+func TestLoadProfileEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	testConfig().Save(path)
+	env := map[string]string{"GUMBLR_CONFIG": path, "TUMBLR_BLOG": "other.tumblr.com"}
+	getenv := func(name string) string { return env[name] }
+
+	profile, err := LoadProfileEnv("", getenv)
+	if err != nil || profile.ConsumerKey != "work-consumer-key" || profile.DefaultBlog != "other.tumblr.com" {
+		t.Errorf("Default profile loaded as %+v with error %v", profile, err)
+	}
+	env["GUMBLR_PROFILE"] = "bot"
+	if profile, err := LoadProfileEnv("", getenv); err != nil || profile.OAuth2AccessToken != "bot-access-token" {
+		t.Errorf("GUMBLR_PROFILE loaded %+v with error %v", profile, err)
+	}
+	if _, err := LoadProfileEnv("missing", getenv); err == nil {
+		t.Error("Missing profile was loaded")
+	}
+	if stored, _ := LoadConfig(path, ""); stored.Profiles["work"].DefaultBlog != "staff.tumblr.com" {
+		t.Error("Environment override changed the stored profile")
+	}
+
+	env = map[string]string{"GUMBLR_CONFIG": path + ".missing", "TUMBLR_CONSUMER_KEY": "env-key"}
+	if profile, err := LoadProfileEnv("", getenv); err != nil || profile.ConsumerKey != "env-key" {
+		t.Errorf("Profile without a config file loaded as %+v with error %v", profile, err)
+	}
+}
+
+func TestOAuth2Token(t *testing.T) {
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer bot-access-token" {
+			t.Errorf("Request was authorized with %q", r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"user":{"name":"bot"}}}`)
+	})
+	defer server.Close()
+
+	profile, _ := testConfig().Profile("bot")
+	client := NewFromProfile(profile)
+	client.SetHTTPClient(newTestClient(server).client)
+	if userInfo := client.UserInfo(); userInfo.User.Name != "bot" {
+		t.Errorf("OAuth 2.0 request returned user %q", userInfo.User.Name)
+	}
+}
@@ -0,0 +1,65 @@
+package tumblr
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A token bucket shared by every request of a client. A nil limiter doesn't limit.
+type rateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64   // tokens added per second
+	burst  float64   // the most tokens the bucket holds
+	tokens float64   // available tokens, negative when requests have reserved future tokens
+	last   time.Time // when tokens was last updated
+}
+
+func newRateLimiter(requestsPerSecond float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// This method blocks until the request may be sent or ctx is done
+// ctx - The context of the request
+func (limiter *rateLimiter) wait(ctx context.Context) error {
+	if limiter == nil {
+		return ctx.Err()
+	}
+	limiter.mutex.Lock()
+	now := time.Now()
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+	if limiter.tokens > limiter.burst {
+		limiter.tokens = limiter.burst
+	}
+	limiter.last = now
+	limiter.tokens--
+	delay := time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+	limiter.mutex.Unlock()
+
+	return sleep(ctx, delay)
+}
+
+// This method sleeps for delay, returning early with the context's error when ctx is done
+// ctx - The context of the request
+// delay - How long to sleep
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
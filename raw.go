@@ -0,0 +1,335 @@
+package tumblr
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The JSON a response object was decoded from, and the fields of that JSON which
+// its type doesn't model. It is embedded in every response type and is only filled
+// when the client preserves raw responses (see SetPreserveRaw) or the object was
+// decoded with UnmarshalRaw. Marshalling an object writes its unmodeled fields back,
+// so objects can be re-serialized without losing data Tumblr added after this
+// package was written.
+type RawFields struct {
+	Raw   json.RawMessage            `json:"-"` // The JSON object the object was decoded from
+	Extra map[string]json.RawMessage `json:"-"` // The fields of Raw not modeled by the object's type, by name
+}
+
+// This method makes the objects returned by the client carry their raw JSON and
+// the fields their types don't model, in their RawFields, at every level: a BlogPosts
+// response, each of its posts and each of their content blocks. Decoding takes
+// longer and uses more memory, so this is off by default.
+// preserve - Whether to preserve raw responses
+func (api *Tumblr) SetPreserveRaw(preserve bool) {
+	api.preserveRaw = preserve
+}
+
+// This method decodes JSON into v like json.Unmarshal, and fills the RawFields of
+// every object within it, e.g. to read back posts archived with json.Marshal.
+// data - The JSON to decode
+// v - A pointer to the value to decode into
+func UnmarshalRaw(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	fillRaw(reflect.ValueOf(v), data)
+	return nil
+}
+
+var rawFieldsType = reflect.TypeOf(RawFields{})
+
+// This method fills the RawFields of a decoded value and of everything within it
+// v - The decoded value
+// data - The JSON it was decoded from
+func fillRaw(v reflect.Value, data json.RawMessage) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			fillRaw(v.Elem(), data)
+		}
+	case reflect.Slice, reflect.Array:
+		if data[0] == '{' && v.Len() == 1 {
+			// A single object decoded into a list, see MediaList
+			fillRaw(v.Index(0), data)
+			return
+		}
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return
+		}
+		for i := 0; i < v.Len() && i < len(items); i++ {
+			fillRaw(v.Index(i), items[i])
+		}
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if data[0] != '{' || json.Unmarshal(data, &object) != nil {
+			return
+		}
+		fields := jsonFields(v.Type())
+		var extra map[string]json.RawMessage
+		for name, value := range object {
+			index, found := fields[strings.ToLower(name)]
+			if !found {
+				if extra == nil {
+					extra = make(map[string]json.RawMessage)
+				}
+				extra[name] = value
+				continue
+			}
+			fillRaw(v.FieldByIndex(index), value)
+		}
+		if field, found := v.Type().FieldByName("RawFields"); found && field.Type == rawFieldsType && len(field.Index) == 1 && v.CanSet() {
+			v.Field(field.Index[0]).Set(reflect.ValueOf(RawFields{
+				Raw:   append(json.RawMessage(nil), data...),
+				Extra: extra,
+			}))
+		}
+	}
+}
+
+// The JSON fields of struct types, by lower case name
+var jsonFieldCache sync.Map
+
+// This method returns the index of each JSON field of a struct type by lower case
+// name, following encoding/json: untagged embedded structs are flattened, the
+// shallowest field of a name wins and fields tagged with the same name at the same
+// depth cancel out.
+// t - The struct type
+func jsonFields(t reflect.Type) map[string][]int {
+	if fields, found := jsonFieldCache.Load(t); found {
+		return fields.(map[string][]int)
+	}
+	type candidate struct {
+		index  []int
+		tagged bool
+	}
+	candidates := make(map[string][]candidate)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" || (field.PkgPath != "" && !field.Anonymous) {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			fieldIndex := append(append([]int(nil), index...), i)
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				walk(field.Type, fieldIndex)
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			name = strings.ToLower(name)
+			candidates[name] = append(candidates[name], candidate{index: fieldIndex, tagged: tag != "" && !strings.HasPrefix(tag, ",")})
+		}
+	}
+	walk(t, nil)
+
+	fields := make(map[string][]int)
+	for name, named := range candidates {
+		sort.SliceStable(named, func(i, j int) bool { return len(named[i].index) < len(named[j].index) })
+		dominant := named[:1]
+		for _, c := range named[1:] {
+			if len(c.index) == len(named[0].index) {
+				dominant = append(dominant, c)
+			}
+		}
+		if len(dominant) > 1 {
+			var tagged []candidate
+			for _, c := range dominant {
+				if c.tagged {
+					tagged = append(tagged, c)
+				}
+			}
+			if len(tagged) != 1 {
+				continue
+			}
+			dominant = tagged
+		}
+		fields[name] = dominant[0].index
+	}
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
+
+// This method marshals an object with its unmodeled fields added back
+// object - The object without MarshalJSON methods, see the MarshalJSON methods below
+// extra - The unmodeled fields of the object
+func marshalRaw(object interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(object)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var modeled map[string]json.RawMessage
+	if err := json.Unmarshal(data, &modeled); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		if _, found := modeled[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	out := bytes.NewBuffer(data[:len(data)-1])
+	for i, name := range names {
+		if i > 0 || len(modeled) > 0 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(extra[name])
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// The MarshalJSON methods of the response types convert to a type without methods
+// so that json.Marshal doesn't recurse, then add the unmodeled fields back.
+
+func (v BlogInfo) MarshalJSON() ([]byte, error) {
+	type plain BlogInfo
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v Blog) MarshalJSON() ([]byte, error) {
+	type plain Blog
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v BlogAvatar) MarshalJSON() ([]byte, error) {
+	type plain BlogAvatar
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v Likes) MarshalJSON() ([]byte, error) {
+	type plain Likes
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v BlogFollowers) MarshalJSON() ([]byte, error) {
+	type plain BlogFollowers
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v BlogFollowing) MarshalJSON() ([]byte, error) {
+	type plain BlogFollowing
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v BlogFollowedBy) MarshalJSON() ([]byte, error) {
+	type plain BlogFollowedBy
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v BlogNotifications) MarshalJSON() ([]byte, error) {
+	type plain BlogNotifications
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v Notification) MarshalJSON() ([]byte, error) {
+	type plain Notification
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v BlogList) MarshalJSON() ([]byte, error) {
+	type plain BlogList
+	return marshalRaw(plain(v), v.Extra)
+}
+
+// The embedded BlogInfo's MarshalJSON would be promoted to a converted BlogPosts,
+// so its fields are listed instead
+func (v BlogPosts) MarshalJSON() ([]byte, error) {
+	return marshalRaw(struct {
+		Blog       Blog   `json:"blog"`
+		Posts      []Post `json:"posts"`
+		TotalPosts int    `json:"total_posts"`
+	}{v.Blog, v.Posts, v.TotalPosts}, v.Extra)
+}
+
+// The player isn't a field of a converted Post, see Post.UnmarshalJSON, so it is
+// added back beside its fields
+func (v Post) MarshalJSON() ([]byte, error) {
+	type plain Post
+	var player interface{}
+	if v.AudioPlayer != "" {
+		player = v.AudioPlayer
+	} else if len(v.Player) > 0 {
+		player = v.Player
+	}
+	return marshalRaw(struct {
+		plain
+		Player interface{} `json:"player,omitempty"`
+	}{plain(v), player}, v.Extra)
+}
+
+func (v CreatedPost) MarshalJSON() ([]byte, error) {
+	type plain CreatedPost
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v ContentBlock) MarshalJSON() ([]byte, error) {
+	type plain ContentBlock
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v Formatting) MarshalJSON() ([]byte, error) {
+	type plain Formatting
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v Media) MarshalJSON() ([]byte, error) {
+	type plain Media
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v LayoutBlock) MarshalJSON() ([]byte, error) {
+	type plain LayoutBlock
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v UserInfo) MarshalJSON() ([]byte, error) {
+	type plain UserInfo
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v UserLimits) MarshalJSON() ([]byte, error) {
+	type plain UserLimits
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v Limit) MarshalJSON() ([]byte, error) {
+	type plain Limit
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v UserFilteredTags) MarshalJSON() ([]byte, error) {
+	type plain UserFilteredTags
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v UserFilteredContent) MarshalJSON() ([]byte, error) {
+	type plain UserFilteredContent
+	return marshalRaw(plain(v), v.Extra)
+}
+
+func (v UserFollowing) MarshalJSON() ([]byte, error) {
+	type plain UserFollowing
+	return marshalRaw(plain(v), v.Extra)
+}
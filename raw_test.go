@@ -0,0 +1,112 @@
+package tumblr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const rawPost = `{"blog_name":"staff","id":123,"type":"text","is_nsfw":false,"summary":"Hello",` +
+	`"player":[{"width":250,"embed_code":"<iframe>"}],` +
+	`"content":[{"type":"text","text":"Hello","subtype_v2":"big"}],` +
+	`"blog":{"name":"staff","uuid":"t:abc"}}`
+
+func TestPreserveRaw(t *testing.T) {
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff","uuid":"t:abc"},"posts":[%s],"total_posts":1,"next":"x"}}`, rawPost)
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	if blogPosts := client.BlogPosts("staff.tumblr.com", nil); blogPosts.Raw != nil || blogPosts.Posts[0].Extra != nil {
+		t.Error("Raw JSON was preserved by default")
+	}
+
+	client.SetPreserveRaw(true)
+	blogPosts := client.BlogPosts("staff.tumblr.com", nil)
+	if len(blogPosts.Posts) != 1 {
+		t.Fatalf("%d posts were decoded", len(blogPosts.Posts))
+	}
+	post := blogPosts.Posts[0]
+	checks := map[string]json.RawMessage{
+		"response next": blogPosts.Extra["next"],
+		"blog uuid":     blogPosts.Blog.Extra["uuid"],
+		"post summary":  post.Extra["summary"],
+		"post is_nsfw":  post.Extra["is_nsfw"],
+		"post player":   post.Extra["player"],
+		"block subtype": post.Content[0].Extra["subtype_v2"],
+	}
+	for name, value := range checks {
+		if value == nil {
+			t.Errorf("Unmodeled %s was not preserved", name)
+		}
+	}
+	if _, found := post.Extra["blog_name"]; found {
+		t.Error("Modeled blog_name is in Extra")
+	}
+	if string(post.Raw) != rawPost {
+		t.Errorf("Raw post is %s", post.Raw)
+	}
+}
+
+func TestMarshalRaw(t *testing.T) {
+	var post Post
+	if err := UnmarshalRaw([]byte(rawPost), &post); err != nil {
+		t.Fatal(err)
+	}
+	post.Title = "Edited"
+	data, err := json.Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var original, reserialized map[string]interface{}
+	json.Unmarshal([]byte(rawPost), &original)
+	json.Unmarshal(data, &reserialized)
+	original["title"] = "Edited"
+	for key, value := range original {
+		if !reflect.DeepEqual(reserialized[key], value) {
+			t.Errorf("Field %s was re-serialized as %v instead of %v", key, reserialized[key], value)
+		}
+	}
+
+	var decoded Post
+	if err := UnmarshalRaw(data, &decoded); err != nil || string(decoded.Extra["summary"]) != `"Hello"` {
+		t.Errorf("Re-serialized post decoded with summary %s and error %v", decoded.Extra["summary"], err)
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	fields := jsonFields(reflect.TypeOf(BlogPosts{}))
+	for _, name := range []string{"blog", "posts", "total_posts"} {
+		if _, found := fields[name]; !found {
+			t.Errorf("BlogPosts field %s was not found", name)
+		}
+	}
+	if _, found := jsonFields(reflect.TypeOf(Post{}))["player"]; found {
+		t.Error("Post's player, decoded by postPlayer, is a JSON field")
+	}
+}
+
+func TestPostPlayer(t *testing.T) {
+	var audio, video Post
+	json.Unmarshal([]byte(`{"id":1,"type":"audio","player":"<embed src=\"audio\">"}`), &audio)
+	json.Unmarshal([]byte(`{"id":2,"type":"video","player":[{"width":250,"embed_code":"small"},{"width":500,"embed_code":"large"}]}`), &video)
+	if audio.AudioPlayer != `<embed src="audio">` || len(audio.Player) != 0 {
+		t.Errorf("Audio player was decoded as %q and %v", audio.AudioPlayer, audio.Player)
+	}
+	if video.AudioPlayer != "" || len(video.Player) != 2 || video.Player[1].Width != 500 || video.Player[1].EmbedCode != "large" {
+		t.Errorf("Video player was decoded as %q and %v", video.AudioPlayer, video.Player)
+	}
+
+	// Posts decoded without their raw JSON keep their player when re-serialized
+	for _, post := range []Post{audio, video} {
+		data, err := json.Marshal(post)
+		var decoded Post
+		if err != nil || json.Unmarshal(data, &decoded) != nil || !reflect.DeepEqual(decoded, post) {
+			t.Errorf("Post %d was re-serialized as %s with error %v", post.ID, data, err)
+		}
+	}
+}
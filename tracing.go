@@ -0,0 +1,250 @@
+package tumblr
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Tracer starts a span for every API call and for every request it sends. It is shaped so that an OpenTelemetry
+// tracer can be adapted in a few lines:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, tumblr.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+//
+// where otelSpan converts each Attribute to an attribute.KeyValue.
+type Tracer interface {
+	// Start starts a span as a child of any span in ctx, returning a context holding the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A Span records one API call or request
+type Span interface {
+	// SetAttributes adds attributes to the span
+	SetAttributes(attributes ...Attribute)
+	// RecordError marks the span as failed
+	RecordError(err error)
+	// End ends the span
+	End()
+}
+
+// An attribute of a span, e.g. tumblr.blog or http.status_code
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// The tracer used when no tracer is set
+type nopTracer struct{}
+
+type nopSpan struct{}
+
+func (nopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
+
+// The context key of the span of the request in progress
+type spanKey struct{}
+
+// The context key of the span of the API call in progress
+type callKey struct{}
+
+// This method sets the tracer that starts a span for every API call, named after
+// the method (e.g. "tumblr.BlogPosts"), as a child of the span in the context
+// passed to WithContext. Each request the call sends has a span of its own as a
+// child of the call's span, named after its endpoint (e.g. "tumblr blog/posts")
+// with the endpoint, blog hostname, post ID, HTTP method and status and number of
+// attempts as attributes. A call whose request fails records the error too, so
+// the requests of a call made of several, e.g. PostMutePopular or BatchGetPost,
+// are traced together.
+// tracer - The tracer to use, nil disables tracing
+func (api *Tumblr) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = nopTracer{}
+	}
+	api.tracer = tracer
+}
+
+// This method returns a copy of the client whose requests use ctx, for cancellation,
+// deadlines and trace propagation. The copy is shallow: it starts with the
+// original's configuration, and shares its connections, cache, rate limiter,
+// circuit breaker and metrics, and the middleware added before the copy was made.
+// Settings changed on the copy, e.g. with Use or SetMaxRetries, only apply to it.
+// ctx - The context of the requests
+func (api *Tumblr) WithContext(ctx context.Context) *Tumblr {
+	client := *api
+	client.ctx = ctx
+	return &client
+}
+
+// This method returns the context requests are made with
+func (api *Tumblr) context() context.Context {
+	if api.ctx == nil {
+		return context.Background()
+	}
+	return api.ctx
+}
+
+// This method starts the span of an API call, returning a copy of the client whose
+// requests are traced as children of it. Without a tracer the client itself is
+// returned, so untraced calls don't copy it.
+// method - The name of the exported method, e.g. BlogPosts
+func (api *Tumblr) startCall(method string) (*Tumblr, Span) {
+	if _, untraced := api.tracer.(nopTracer); untraced {
+		return api, nopSpan{}
+	}
+	ctx, span := api.tracer.Start(api.context(), "tumblr."+method)
+	return api.WithContext(context.WithValue(ctx, callKey{}, span)), span
+}
+
+// This method starts the span of a request, returning the request with the span's context
+// request - The request
+func (api *Tumblr) startSpan(request *http.Request) (*http.Request, Span) {
+	ctx, span := api.tracer.Start(request.Context(), "tumblr "+endpoint(request.URL))
+	span.SetAttributes(
+		Attribute{Key: "tumblr.endpoint", Value: endpoint(request.URL)},
+		Attribute{Key: "http.method", Value: request.Method},
+	)
+	if blog := blogHostname(request.URL); blog != "" {
+		span.SetAttributes(Attribute{Key: "tumblr.blog", Value: blog})
+	}
+	if id := requestPostID(request); id != "" {
+		span.SetAttributes(Attribute{Key: "tumblr.post_id", Value: id})
+	}
+	return request.WithContext(context.WithValue(ctx, spanKey{}, span)), span
+}
+
+// This method ends the span of a request, recording its error on the span of the
+// API call it belongs to as well
+// request - The request, with the span's context
+// span - The span to end
+// err - The error the request failed with, if any
+func endSpan(request *http.Request, span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if call, ok := request.Context().Value(callKey{}).(Span); ok {
+			call.RecordError(err)
+		}
+	}
+	span.End()
+}
+
+// This method returns the span of the API call a request belongs to
+// request - The request
+func requestSpan(request *http.Request) Span {
+	if span, ok := request.Context().Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return nopSpan{}
+}
+
+// This method returns the ID of the post a request is about, from its path, its
+// query or its form body
+// request - The request
+func requestPostID(request *http.Request) string {
+	if id := request.URL.Query().Get("id"); id != "" {
+		return id
+	}
+	segments := strings.Split(request.URL.Path, "/")
+	for i, segment := range segments[:len(segments)-1] {
+		if segment == "posts" {
+			if _, err := strconv.Atoi(segments[i+1]); err == nil {
+				return segments[i+1]
+			}
+		}
+	}
+	if request.GetBody != nil && request.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		body, err := request.GetBody()
+		if err != nil {
+			return ""
+		}
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		form, _ := url.ParseQuery(string(data))
+		return form.Get("id")
+	}
+	return ""
+}
+
+// A Tracer keeping finished spans in memory, for tests
+type RecordingTracer struct {
+	mutex sync.Mutex
+	spans []*RecordedSpan
+}
+
+// A span recorded by a RecordingTracer
+type RecordedSpan struct {
+	Name       string                 // The span name
+	Parent     *RecordedSpan          // The parent span, if it was started by the same tracer
+	Attributes map[string]interface{} // The span attributes
+	Err        error                  // The recorded error
+	Start      time.Time              // When the span started
+	End        time.Time              // When the span ended
+	tracer     *RecordingTracer
+}
+
+// This method creates a tracer recording spans in memory
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+func (tracer *RecordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		Start:      time.Now(),
+		tracer:     tracer,
+	}
+	span.Parent, _ = ctx.Value(recordedSpanKey{}).(*RecordedSpan)
+	return context.WithValue(ctx, recordedSpanKey{}, span), recordedSpan{span}
+}
+
+// This method returns the spans that have ended, in the order they ended
+func (tracer *RecordingTracer) Spans() []*RecordedSpan {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	return append([]*RecordedSpan(nil), tracer.spans...)
+}
+
+// The context key of a RecordingTracer's current span
+type recordedSpanKey struct{}
+
+// The Span interface of a RecordedSpan, kept off RecordedSpan so that its fields
+// can share the method names
+type recordedSpan struct {
+	span *RecordedSpan
+}
+
+func (s recordedSpan) SetAttributes(attributes ...Attribute) {
+	s.span.tracer.mutex.Lock()
+	defer s.span.tracer.mutex.Unlock()
+	for _, attribute := range attributes {
+		s.span.Attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s recordedSpan) RecordError(err error) {
+	s.span.tracer.mutex.Lock()
+	defer s.span.tracer.mutex.Unlock()
+	s.span.Err = err
+}
+
+func (s recordedSpan) End() {
+	s.span.tracer.mutex.Lock()
+	defer s.span.tracer.mutex.Unlock()
+	s.span.End = time.Now()
+	s.span.tracer.spans = append(s.span.tracer.spans, s.span)
+}
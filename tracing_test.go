@@ -0,0 +1,148 @@
+package tumblr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTracing(t *testing.T) {
+	var attempts int32
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/blog/staff.tumblr.com/post/delete":
+			if atomic.AddInt32(&attempts, 1) < 2 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"meta":{"status":429,"msg":"Limit Exceeded"},"response":[]}`)
+				return
+			}
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":[]}`)
+		case "/v2/blog/missing.tumblr.com/info":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"meta":{"status":404,"msg":"Not Found"},"response":[]}`)
+		default:
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"posts":[]}}`)
+		}
+	})
+	defer server.Close()
+
+	tracer := NewRecordingTracer()
+	client := newTestClient(server)
+	client.SetTracer(tracer)
+	ctx, parent := tracer.Start(context.Background(), "handler")
+	traced := client.WithContext(ctx)
+	traced.PostDelete("staff.tumblr.com", 123)
+	traced.GetPost("staff.tumblr.com", 456, nil)
+	traced.BlogInfo("missing.tumblr.com")
+	parent.End()
+
+	// Every call has a span, with a span for its request below it
+	spans := tracer.Spans()
+	if len(spans) != 7 {
+		t.Fatalf("%d spans were recorded instead of 7", len(spans))
+	}
+	expected := []struct {
+		name       string
+		call       string
+		attributes map[string]interface{}
+	}{
+		{"tumblr blog/post/delete", "tumblr.PostDelete", map[string]interface{}{"tumblr.blog": "staff.tumblr.com", "tumblr.post_id": "123", "http.method": "POST", "http.status_code": 200, "tumblr.attempts": 2}},
+		{"tumblr blog/posts/{id}", "tumblr.GetPost", map[string]interface{}{"tumblr.endpoint": "blog/posts/{id}", "tumblr.post_id": "456", "http.status_code": 200, "tumblr.attempts": 1}},
+		{"tumblr blog/info", "tumblr.BlogInfo", map[string]interface{}{"tumblr.blog": "missing.tumblr.com", "http.status_code": 404}},
+	}
+	for i, span := range expected {
+		request, call := spans[2*i], spans[2*i+1]
+		if request.Name != span.name || call.Name != span.call {
+			t.Errorf("Spans %d are named %s and %s instead of %s and %s", i, request.Name, call.Name, span.name, span.call)
+		}
+		if request.Parent != call || call.Parent != spans[6] {
+			t.Errorf("Span %s is not a child of %s, a child of the context's span", request.Name, call.Name)
+		}
+		for key, value := range span.attributes {
+			if request.Attributes[key] != value {
+				t.Errorf("Span %s has %s %v instead of %v", request.Name, key, request.Attributes[key], value)
+			}
+		}
+	}
+	var apiError *APIError
+	for _, span := range spans[4:6] {
+		if !errors.As(span.Err, &apiError) || apiError.Meta.Status != 404 {
+			t.Errorf("Span %s of a 404 recorded error %v", span.Name, span.Err)
+		}
+	}
+	if spans[0].Err != nil || spans[1].Err != nil {
+		t.Errorf("Spans of a retried request recorded errors %v and %v", spans[0].Err, spans[1].Err)
+	}
+}
+
+func TestTracingCompositeCalls(t *testing.T) {
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"id":1,"content":[{"type":"text","text":"Why?"}]}}`)
+	})
+	defer server.Close()
+
+	tracer := NewRecordingTracer()
+	client := newTestClient(server)
+	client.SetTracer(tracer)
+	client.BatchGetPost("staff.tumblr.com", []int{1, 2}, nil)
+	client.AskAnswerNPF("staff.tumblr.com", 1, []ContentBlock{{Type: "text", Text: "Because"}}, "published")
+
+	// The requests of a call are children of its span, which has no parent
+	children := make(map[string][]string)
+	for _, span := range tracer.Spans() {
+		if span.Parent == nil {
+			children[span.Name] = children[span.Name]
+		} else {
+			children[span.Parent.Name] = append(children[span.Parent.Name], span.Name)
+		}
+	}
+	expected := map[string][]string{
+		"tumblr.BatchGetPost": {"tumblr blog/posts/{id}", "tumblr blog/posts/{id}"},
+		"tumblr.AskAnswerNPF": {"tumblr blog/posts/{id}", "tumblr blog/posts/{id}"},
+	}
+	if !reflect.DeepEqual(children, expected) {
+		t.Errorf("Spans are %v instead of %v", children, expected)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	server := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"meta":{"status":429,"msg":"Limit Exceeded"},"response":[]}`)
+	})
+	defer server.Close()
+
+	tracer := NewRecordingTracer()
+	client := newTestClient(server)
+	client.SetTracer(tracer)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.WithContext(ctx).getPost("staff.tumblr.com", 123, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Cancelled request waited %s for its retry", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Cancelled request returned %v", err)
+	}
+	if spans := tracer.Spans(); len(spans) != 1 || !errors.Is(spans[0].Err, context.DeadlineExceeded) {
+		t.Errorf("Cancelled request recorded spans %v", spans)
+	}
+}
+
+func TestNoTracer(t *testing.T) {
+	client, closeServer := blogInfoServer()
+	defer closeServer()
+	client.SetTracer(nil)
+	if _, err := client.WithContext(context.Background()).blogInfo("staff.tumblr.com"); err != nil {
+		t.Errorf("Untraced request failed with %v", err)
+	}
+}
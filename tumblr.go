@@ -1,9 +1,19 @@
 package tumblr
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/kurrik/oauth1a"
+	"io"
+	"log/slog"
+	"net/http"
 	"net/url"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -15,10 +25,28 @@ const (
 	accessTokenUrl  = "http://www.tumblr.com/oauth/access_token"  // oauth access-token URL
 )
 
+// A Tumblr client. A *Tumblr is safe for concurrent use by multiple goroutines;
+// configure it with the Set methods before sharing it.
 type Tumblr struct {
-	oauthService oauth1a.Service    // oauth service used to sign HTTP requests
-	config       oauth1a.UserConfig // used within the oauth HTTP signing
-	apiKey       string             // consumer key used for certain API requests
+	oauthService     oauth1a.Service          // oauth service used to sign HTTP requests
+	config           oauth1a.UserConfig       // used within the oauth HTTP signing
+	apiKey           string                   // consumer key used for certain API requests
+	oauth2Token      string                   // OAuth 2.0 access token, used instead of OAuth 1.0a signing when set
+	client           *http.Client             // HTTP client shared by every request
+	maxResponseSize  int64                    // limit on the size of a response body, in bytes
+	maxRetries       int                      // retries of a request rate limited by Tumblr
+	limiter          *rateLimiter             // client side rate limit, nil when unlimited
+	breaker          *circuitBreaker          // circuit breaker, nil when disabled
+	batchConcurrency int                      // number of requests a batch method sends at once
+	cache            Cache                    // cache consulted by GET requests, nil when disabled
+	cacheTTLs        map[string]time.Duration // lifetime of cached responses by endpoint
+	middleware       []Middleware             // middleware wrapped around every request, outermost first
+	logger           *slog.Logger             // logger for requests and failures, silent by default
+	debug            io.Writer                // writer requests and responses are dumped to, nil when disabled
+	preserveRaw      bool                     // whether decoded objects carry their raw JSON and unmodeled fields
+	metrics          MetricsCollector         // receives measurements of every request
+	tracer           Tracer                   // starts a span for every API call
+	ctx              context.Context          // context of every request, see WithContext
 }
 
 // This is the initialization method.
@@ -37,25 +65,89 @@ func New(consumerKey, consumerSecret, oauthKey, oauthSecret string) *Tumblr {
 	}
 	config := oauth1a.NewAuthorizedConfig(oauthKey, oauthSecret)
 	return &Tumblr{
-		oauthService: *service,
-		config:       *config,
-		apiKey:       consumerKey,
+		oauthService:     *service,
+		config:           *config,
+		apiKey:           consumerKey,
+		client:           newHTTPClient(),
+		maxResponseSize:  defaultMaxResponseSize,
+		maxRetries:       defaultMaxRetries,
+		batchConcurrency: defaultBatchConcurrency,
+		logger:           slog.New(slog.DiscardHandler),
+		metrics:          nopMetrics{},
+		tracer:           nopTracer{},
 	}
 }
 
+// This method replaces the HTTP client used to send requests. By default a client
+// is created with a transport that keeps connections alive, pools them per host,
+// negotiates HTTP/2 and accepts gzip encoded responses.
+// client - The HTTP client to use for every request
+func (api *Tumblr) SetHTTPClient(client *http.Client) {
+	api.client = client
+}
+
+// This method authorizes requests with an OAuth 2.0 access token instead of signing
+// them with the OAuth 1.0a credentials given to New
+// token - The access token, "" to go back to OAuth 1.0a
+func (api *Tumblr) SetOAuth2Token(token string) {
+	api.oauth2Token = token
+}
+
+// This method sets the maximum size of a response body. Larger responses fail with
+// ErrResponseTooLarge. Default: 32 MiB
+// size - The maximum size in bytes
+func (api *Tumblr) SetMaxResponseSize(size int64) {
+	api.maxResponseSize = size
+}
+
+// This method sets how many times a request is retried when Tumblr responds with
+// 429 Too Many Requests. Retries wait for the Retry-After header or back off
+// exponentially. Default: 3
+// retries - The maximum number of retries, 0 disables retrying
+func (api *Tumblr) SetMaxRetries(retries int) {
+	api.maxRetries = retries
+}
+
+// This method limits the rate at which the client sends requests, across all
+// goroutines sharing it. By default requests aren't limited.
+// requestsPerSecond - The sustained number of requests per second, 0 removes the limit
+// burst - The number of requests that may be sent at once before the rate applies
+func (api *Tumblr) SetRateLimit(requestsPerSecond float64, burst int) {
+	if requestsPerSecond <= 0 {
+		api.limiter = nil
+		return
+	}
+	api.limiter = newRateLimiter(requestsPerSecond, burst)
+}
+
+// This method sets how many requests a batch method sends at once. Default: 8
+// concurrency - The number of workers
+func (api *Tumblr) SetBatchConcurrency(concurrency int) {
+	api.batchConcurrency = concurrency
+}
+
 // This method returns general information about the blog, such as the title,
 // number of posts, and other high-level data.
 // blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
-func (api Tumblr) BlogInfo(blogHostname string) BlogInfo {
+func (api *Tumblr) BlogInfo(blogHostname string) BlogInfo {
+	api, span := api.startCall("BlogInfo")
+	defer span.End()
+	blogInfo, _ := api.blogInfo(blogHostname)
+	return blogInfo
+}
+
+func (api *Tumblr) blogInfo(blogHostname string) (BlogInfo, error) {
 	var blogInfo BlogInfo
 	requestURL := apiBlogUrl + blogHostname + "/info"
-	api.info(requestURL, &blogInfo)
-	return blogInfo
+	err := api.info(requestURL, &blogInfo)
+	return blogInfo, err
 }
 
 // This method returns a URL to a blog's avatar with default size 64
 // blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
-func (api Tumblr) BlogAvatar(blogHostname string) []byte {
+func (api *Tumblr) BlogAvatar(blogHostname string) []byte {
+	api, span := api.startCall("BlogAvatar")
+	defer span.End()
 	return api.BlogAvatarAndSize(blogHostname, 64)
 }
 
@@ -63,7 +155,9 @@ func (api Tumblr) BlogAvatar(blogHostname string) []byte {
 // blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
 // size - The size of the avatar (square, one value for both length and width).
 //        Must be one of the values: 16, 24, 30, 40, 48, 64, 96, 128, 512
-func (api Tumblr) BlogAvatarAndSize(blogHostname string, size int) []byte {
+func (api *Tumblr) BlogAvatarAndSize(blogHostname string, size int) []byte {
+	api, span := api.startCall("BlogAvatarAndSize")
+	defer span.End()
 	requestURL := apiBlogUrl + blogHostname + "/avatar/" + strconv.Itoa(size)
 	return api.rawGet(requestURL)
 }
@@ -75,7 +169,9 @@ func (api Tumblr) BlogAvatarAndSize(blogHostname string, size int) []byte {
 //          * offset - Liked post number to start at.  Default: 0 (First post)
 //          * before - Retrieve posts liked before the specified timestamp. Default: None
 //          * after - Retrieve posts liked after the specified timestamp. Default: None
-func (api Tumblr) BlogLikes(blogHostname string, params map[string]string) Likes {
+func (api *Tumblr) BlogLikes(blogHostname string, params map[string]string) Likes {
+	api, span := api.startCall("BlogLikes")
+	defer span.End()
 	var blogLikes Likes
 	requestURL := apiBlogUrl + blogHostname + "/likes?"
 	urlParams := url.Values{}
@@ -93,7 +189,9 @@ func (api Tumblr) BlogLikes(blogHostname string, params map[string]string) Likes
 // params - A map of the params that are included in this request. Possible parameters:
 //          * limit - The number of results to return.  Default: 20 (1–20, inclusive)
 //          * offset - Liked post number to start at.  Default: 0 (First follower)
-func (api Tumblr) BlogFollowers(blogHostname string, params map[string]string) BlogFollowers {
+func (api *Tumblr) BlogFollowers(blogHostname string, params map[string]string) BlogFollowers {
+	api, span := api.startCall("BlogFollowers")
+	defer span.End()
 	var blogFollowers BlogFollowers
 	requestURL := apiBlogUrl + blogHostname + "/followers?"
 	urlParams := url.Values{}
@@ -105,6 +203,68 @@ func (api Tumblr) BlogFollowers(blogHostname string, params map[string]string) B
 	return blogFollowers
 }
 
+// This method retrieves the blogs that a blog is following
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// params - A map of the params that are included in this request. Possible parameters:
+//          * limit - The number of results to return.  Default: 20 (1–20, inclusive)
+//          * offset - Followed blog number to start at.  Default: 0 (First blog)
+func (api *Tumblr) BlogFollowing(blogHostname string, params map[string]string) BlogFollowing {
+	api, span := api.startCall("BlogFollowing")
+	defer span.End()
+	var blogFollowing BlogFollowing
+	requestURL := apiBlogUrl + blogHostname + "/following?"
+	urlParams := url.Values{}
+	for key, value := range params {
+		urlParams.Set(key, value)
+	}
+	requestURL = requestURL + urlParams.Encode()
+	api.info(requestURL, &blogFollowing)
+	return blogFollowing
+}
+
+// This method checks whether a blog is followed by another blog, e.g. to find out
+// if blog A follows blog B call BlogFollowedBy(B, A).
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// query - The name of the blog that may be following blogHostname
+func (api *Tumblr) BlogFollowedBy(blogHostname string, query string) BlogFollowedBy {
+	api, span := api.startCall("BlogFollowedBy")
+	defer span.End()
+	var blogFollowedBy BlogFollowedBy
+	requestURL := apiBlogUrl + blogHostname + "/followed_by?"
+	urlParams := url.Values{}
+	urlParams.Set("query", query)
+	requestURL = requestURL + urlParams.Encode()
+	api.info(requestURL, &blogFollowedBy)
+	return blogFollowedBy
+}
+
+// This method retrieves the activity on a blog, such as likes, reblogs, follows, replies and asks.
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// params - A map of the params that are included in this request. Possible parameters:
+//          * before - Retrieve notifications before the specified timestamp. Default: None
+//          * types - Comma-separated notification types to return, e.g. like,reblog_naked,follow
+//                    (like, reblog_naked, reblog_with_content, reply, ask, answered_ask, follow,
+//                    mention_in_reply, mention_in_post, conversational_note). Default: all
+func (api *Tumblr) BlogNotifications(blogHostname string, params map[string]string) BlogNotifications {
+	api, span := api.startCall("BlogNotifications")
+	defer span.End()
+	var blogNotifications BlogNotifications
+	requestURL := apiBlogUrl + blogHostname + "/notifications?"
+	urlParams := url.Values{}
+	for key, value := range params {
+		if key == "types" {
+			for _, notificationType := range strings.Split(value, ",") {
+				urlParams.Add("types[]", strings.TrimSpace(notificationType))
+			}
+			continue
+		}
+		urlParams.Set(key, value)
+	}
+	requestURL = requestURL + urlParams.Encode()
+	api.info(requestURL, &blogNotifications)
+	return blogNotifications
+}
+
 // This method retrieves a list of a blog's published posts
 // blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
 // params - A map of the params that are included in this request. Possible parameters:
@@ -116,7 +276,14 @@ func (api Tumblr) BlogFollowers(blogHostname string, params map[string]string) B
 //          * reblog_info - Indicates whether to return reblog information (specify true or false)
 //          * notes_info - Indicates whether to return notes information (specify true or false).
 //          * filter - Specifies the post format to return, other than HTML (text or raw)
-func (api Tumblr) BlogPosts(blogHostname string, params map[string]string) BlogPosts {
+func (api *Tumblr) BlogPosts(blogHostname string, params map[string]string) BlogPosts {
+	api, span := api.startCall("BlogPosts")
+	defer span.End()
+	blogPosts, _ := api.blogPosts(blogHostname, params)
+	return blogPosts
+}
+
+func (api *Tumblr) blogPosts(blogHostname string, params map[string]string) (BlogPosts, error) {
 	var blogPosts BlogPosts
 	requestURL := apiBlogUrl + blogHostname + "/posts?"
 	urlParams := url.Values{}
@@ -125,8 +292,33 @@ func (api Tumblr) BlogPosts(blogHostname string, params map[string]string) BlogP
 		urlParams.Set(key, value)
 	}
 	requestURL = requestURL + urlParams.Encode()
-	api.info(requestURL, &blogPosts)
-	return blogPosts
+	err := api.info(requestURL, &blogPosts)
+	return blogPosts, err
+}
+
+// This method retrieves a single post from a blog
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// id - The ID of the post
+// params - A map of the params that are included in this request. Possible parameters:
+//          * post_format - The post format to return: npf (Default) or legacy
+func (api *Tumblr) GetPost(blogHostname string, id int, params map[string]string) Post {
+	api, span := api.startCall("GetPost")
+	defer span.End()
+	post, _ := api.getPost(blogHostname, id, params)
+	return post
+}
+
+func (api *Tumblr) getPost(blogHostname string, id int, params map[string]string) (Post, error) {
+	var post Post
+	requestURL := apiBlogUrl + blogHostname + "/posts/" + strconv.Itoa(id) + "?"
+	urlParams := url.Values{}
+	urlParams.Set("api_key", api.apiKey)
+	for key, value := range params {
+		urlParams.Set(key, value)
+	}
+	requestURL = requestURL + urlParams.Encode()
+	err := api.info(requestURL, &post)
+	return post, err
 }
 
 // This method retrieves a list of a blog's queued posts.
@@ -135,7 +327,9 @@ func (api Tumblr) BlogPosts(blogHostname string, params map[string]string) BlogP
 //          * offset - Post number to start at (Default: 0)
 //          * limit - The number of results to return: 1–20, inclusive.
 //          * filter - Specifies the post format to return, other than HTML (text or raw)
-func (api Tumblr) BlogQueuedPosts(blogHostname string, params map[string]string) BlogList {
+func (api *Tumblr) BlogQueuedPosts(blogHostname string, params map[string]string) BlogList {
+	api, span := api.startCall("BlogQueuedPosts")
+	defer span.End()
 	var queuedPosts BlogList
 	requestURL := apiBlogUrl + blogHostname + "/posts/queue?"
 	urlParams := url.Values{}
@@ -147,6 +341,25 @@ func (api Tumblr) BlogQueuedPosts(blogHostname string, params map[string]string)
 	return queuedPosts
 }
 
+// This method retrieves a list of a blog's draft posts.
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// params - A map of the params that are included in this request. Possible parameters:
+//          * before_id - Return posts that have appeared before this ID (Default: 0)
+//          * filter - Specifies the post format to return, other than HTML (text or raw)
+func (api *Tumblr) BlogDraftPosts(blogHostname string, params map[string]string) BlogList {
+	api, span := api.startCall("BlogDraftPosts")
+	defer span.End()
+	var draftPosts BlogList
+	requestURL := apiBlogUrl + blogHostname + "/posts/draft?"
+	urlParams := url.Values{}
+	for key, value := range params {
+		urlParams.Set(key, value)
+	}
+	requestURL = requestURL + urlParams.Encode()
+	api.info(requestURL, &draftPosts)
+	return draftPosts
+}
+
 // This method is used to post a blog post to a blog
 // blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
 // params - A map of the params that are included in this request. Possible parameters:
@@ -185,21 +398,41 @@ func (api Tumblr) BlogQueuedPosts(blogHostname string, params map[string]string)
 //          * caption - The user-supplied caption
 //          * embed - HTML embed code for the video
 //          * data - A video file
-func (api Tumblr) Post(blogHostname string, params map[string]string) Meta {
+func (api *Tumblr) Post(blogHostname string, params map[string]string) Meta {
+	api, span := api.startCall("Post")
+	defer span.End()
+	return api.CreatePost(blogHostname, params).Meta
+}
+
+// This method creates a post like Post, also returning the ID of the new post
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// params - The list of possible parameters are listed above the Post method, along with:
+//          * publish_on - When a queued post is published, in ISO 8601 format
+//          * data[0], data[1]... - The photos of a photo post with several photos
+func (api *Tumblr) CreatePost(blogHostname string, params map[string]string) CreatedPost {
+	api, span := api.startCall("CreatePost")
+	defer span.End()
+	var createdPost CreatedPost
 	requestURL := apiBlogUrl + blogHostname + "/post"
 	urlParams := url.Values{}
 	for key, value := range params {
 		urlParams.Set(key, value)
 	}
 	response := api.post(requestURL, urlParams.Encode())
-	return response.Meta
+	if response.Meta.Status >= 200 && response.Meta.Status < 300 && json.Unmarshal(response.Response, &createdPost) == nil && api.preserveRaw {
+		fillRaw(reflect.ValueOf(&createdPost), response.Response)
+	}
+	createdPost.Meta = response.Meta
+	return createdPost
 }
 
 // This method is used to edit a blog post to a blog
 // blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
 // id - The id of the blog post
 // params - The list of possible parameters are listed above the Post method
-func (api Tumblr) PostEdit(blogHostname string, id int, params map[string]string) Meta {
+func (api *Tumblr) PostEdit(blogHostname string, id int, params map[string]string) Meta {
+	api, span := api.startCall("PostEdit")
+	defer span.End()
 	requestURL := apiBlogUrl + blogHostname + "/post/edit"
 	urlParams := url.Values{}
 	urlParams.Set("id", strconv.Itoa(id))
@@ -216,7 +449,9 @@ func (api Tumblr) PostEdit(blogHostname string, id int, params map[string]string
 // reblogKey - The reblog key for the reblogged post – get the reblog key with a BlogPosts request
 // params - The list of possible parameters are listed above the Post method, along with:
 //          * comment - A comment added to the reblogged post
-func (api Tumblr) PostReblog(blogHostname string, id int, reblogKey string, params map[string]string) Meta {
+func (api *Tumblr) PostReblog(blogHostname string, id int, reblogKey string, params map[string]string) Meta {
+	api, span := api.startCall("PostReblog")
+	defer span.End()
 	requestURL := apiBlogUrl + blogHostname + "/post/reblog"
 	urlParams := url.Values{}
 	urlParams.Set("id", strconv.Itoa(id))
@@ -231,7 +466,9 @@ func (api Tumblr) PostReblog(blogHostname string, id int, reblogKey string, para
 // This method is used to delete a blog post from a blog
 // blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
 // id - The ID of the post to delete
-func (api Tumblr) PostDelete(blogHostname string, id int) Meta {
+func (api *Tumblr) PostDelete(blogHostname string, id int) Meta {
+	api, span := api.startCall("PostDelete")
+	defer span.End()
 	requestURL := apiBlogUrl + blogHostname + "/post/delete"
 	urlParams := url.Values{}
 	urlParams.Set("id", strconv.Itoa(id))
@@ -239,15 +476,198 @@ func (api Tumblr) PostDelete(blogHostname string, id int) Meta {
 	return response.Meta
 }
 
+// This method is used to mute the notifications of a blog post
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// id - The ID of the post to mute
+// duration - How long to mute the post for, rounded down to seconds. 0 mutes it forever
+func (api *Tumblr) PostMute(blogHostname string, id int, duration time.Duration) Meta {
+	api, span := api.startCall("PostMute")
+	defer span.End()
+	meta, _ := api.mute(blogHostname, id, duration)
+	return meta
+}
+
+func (api *Tumblr) mute(blogHostname string, id int, duration time.Duration) (Meta, error) {
+	requestURL := apiBlogUrl + blogHostname + "/posts/" + strconv.Itoa(id) + "/mute"
+	urlParams := url.Values{}
+	urlParams.Set("mute_length_seconds", strconv.Itoa(int(duration/time.Second)))
+	request, err := api.newRequest("POST", requestURL, strings.NewReader(urlParams.Encode()))
+	if err != nil {
+		return Meta{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := api.send(request)
+	if err == nil && response.Meta.Status != 200 {
+		err = &APIError{Meta: response.Meta}
+	}
+	return response.Meta, err
+}
+
+// This method is used to unmute the notifications of a blog post
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// id - The ID of the post to unmute
+func (api *Tumblr) PostUnmute(blogHostname string, id int) Meta {
+	api, span := api.startCall("PostUnmute")
+	defer span.End()
+	requestURL := apiBlogUrl + blogHostname + "/posts/" + strconv.Itoa(id) + "/mute"
+	response := api.delete(requestURL, "")
+	return response.Meta
+}
+
+// This method pages through a blog's published posts and mutes every post whose
+// note count exceeds a threshold. It returns a result for each of these posts, with
+// an error when it couldn't be muted, and an error when a page of posts couldn't be
+// retrieved, along with the results of the posts before it.
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// threshold - Posts with more notes than this are muted
+// duration - How long to mute the posts for. 0 mutes them forever
+func (api *Tumblr) PostMutePopular(blogHostname string, threshold int, duration time.Duration) ([]PostResult, error) {
+	api, span := api.startCall("PostMutePopular")
+	defer span.End()
+	var results []PostResult
+	params := map[string]string{
+		"limit": "20",
+	}
+	for offset := 0; ; {
+		params["offset"] = strconv.Itoa(offset)
+		blogPosts, err := api.blogPosts(blogHostname, params)
+		if err != nil {
+			return results, err
+		}
+		for _, post := range blogPosts.Posts {
+			if post.NoteCount <= threshold {
+				continue
+			}
+			_, err := api.mute(blogHostname, post.ID, duration)
+			results = append(results, PostResult{ID: post.ID, Post: post, Err: err})
+		}
+		offset += len(blogPosts.Posts)
+		if len(blogPosts.Posts) == 0 || offset >= blogPosts.TotalPosts {
+			break
+		}
+	}
+	return results, nil
+}
+
+// This method retrieves a list of a blog's submission posts, including asks waiting for an answer
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// params - A map of the params that are included in this request. Possible parameters:
+//          * offset - Post number to start at (Default: 0)
+//          * filter - Specifies the post format to return, other than HTML (text or raw)
+func (api *Tumblr) BlogSubmissions(blogHostname string, params map[string]string) BlogList {
+	api, span := api.startCall("BlogSubmissions")
+	defer span.End()
+	var submissions BlogList
+	requestURL := apiBlogUrl + blogHostname + "/posts/submission?"
+	urlParams := url.Values{}
+	for key, value := range params {
+		urlParams.Set(key, value)
+	}
+	requestURL = requestURL + urlParams.Encode()
+	api.info(requestURL, &submissions)
+	return submissions
+}
+
+// This method retrieves the asks in a blog's inbox that are waiting for an answer
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// params - The list of possible parameters are listed above the BlogSubmissions method
+func (api *Tumblr) BlogAsks(blogHostname string, params map[string]string) []Post {
+	api, span := api.startCall("BlogAsks")
+	defer span.End()
+	var asks []Post
+	for _, post := range api.BlogSubmissions(blogHostname, params).Posts {
+		if post.Type == "answer" {
+			asks = append(asks, post)
+		}
+	}
+	return asks
+}
+
+// This method is used to answer an ask
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// id - The ID of the ask
+// answer - The answer, HTML allowed
+// state - The state of the answer post. Specify one of the following:  published, draft, queue, private
+func (api *Tumblr) AskAnswer(blogHostname string, id int, answer string, state string) Meta {
+	api, span := api.startCall("AskAnswer")
+	defer span.End()
+	params := map[string]string{
+		"answer": answer,
+		"state":  state,
+	}
+	return api.PostEdit(blogHostname, id, params)
+}
+
+// Returned by AskAnswerNPF when the ask has no content blocks to answer below
+var ErrAskWithoutContent = errors.New("tumblr: the ask has no content blocks")
+
+// This method is used to answer an ask with Neue Post Format content blocks. Unlike
+// the other methods it also returns an error: the ask is retrieved first, and when
+// it can't be or has no content blocks nothing is edited, so there is no response
+// whose Meta could report it. Editing without the question's blocks would replace
+// the ask with the answer.
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// id - The ID of the ask
+// answer - The content blocks of the answer, shown below the question
+// state - The state of the answer post. Specify one of the following:  published, draft, queue, private
+func (api *Tumblr) AskAnswerNPF(blogHostname string, id int, answer []ContentBlock, state string) (Meta, error) {
+	api, span := api.startCall("AskAnswerNPF")
+	defer span.End()
+	ask, err := api.getPost(blogHostname, id, map[string]string{"post_format": "npf"})
+	if err != nil {
+		return Meta{}, fmt.Errorf("tumblr: retrieving ask %d: %w", id, err)
+	}
+	if len(ask.Content) == 0 {
+		return Meta{}, ErrAskWithoutContent
+	}
+	requestURL := apiBlogUrl + blogHostname + "/posts/" + strconv.Itoa(id)
+	body, err := json.Marshal(struct {
+		Content []ContentBlock `json:"content"`
+		Layout  []LayoutBlock  `json:"layout"`
+		State   string         `json:"state"`
+	}{
+		Content: append(ask.Content, answer...),
+		Layout:  ask.Layout,
+		State:   state,
+	})
+	if err != nil {
+		return Meta{}, err
+	}
+	response := api.put(requestURL, body)
+	return response.Meta, nil
+}
+
+// This method is used to delete an ask without answering it
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+// id - The ID of the ask
+func (api *Tumblr) AskDelete(blogHostname string, id int) Meta {
+	api, span := api.startCall("AskDelete")
+	defer span.End()
+	return api.PostDelete(blogHostname, id)
+}
+
 // This method is used to retrieve the user's account information that matches
 // the OAuth credentials submitted with the request.
-func (api Tumblr) UserInfo() UserInfo {
+func (api *Tumblr) UserInfo() UserInfo {
+	api, span := api.startCall("UserInfo")
+	defer span.End()
 	var userInfo UserInfo
 	requestURL := apiUserUrl + "info"
 	api.info(requestURL, &userInfo)
 	return userInfo
 }
 
+// This method is used to retrieve the user's posting limits, such as the number of
+// posts, photos, videos and follows remaining for the day.
+func (api *Tumblr) UserLimits() UserLimits {
+	api, span := api.startCall("UserLimits")
+	defer span.End()
+	var userLimits UserLimits
+	requestURL := apiUserUrl + "limits"
+	api.info(requestURL, &userLimits)
+	return userLimits
+}
+
 // This method is used to retrieve the dashboard that matches the OAuth credentials
 // submitted with the request.
 // params - A map of the params that are included in this request. Possible parameters:
@@ -257,7 +677,9 @@ func (api Tumblr) UserInfo() UserInfo {
 //          * since_id - Return posts that have appeared after this ID
 //          * reblog_info - Indicates whether to return reblog information (specify true or false).
 //          * notes_info - Indicates whether to return notes information (specify true or false).
-func (api Tumblr) UserDashboard(params map[string]string) BlogList {
+func (api *Tumblr) UserDashboard(params map[string]string) BlogList {
+	api, span := api.startCall("UserDashboard")
+	defer span.End()
 	var userDashboard BlogList
 	requestURL := apiUserUrl + "dashboard?"
 	urlParams := url.Values{}
@@ -275,7 +697,9 @@ func (api Tumblr) UserDashboard(params map[string]string) BlogList {
 //          * offset - Liked post number to start at.  Default: 0 (First post)
 //          * before - Retrieve posts liked before the specified timestamp. Default: None
 //          * after - Retrieve posts liked after the specified timestamp. Default: None
-func (api Tumblr) UserLikes(params map[string]string) Likes {
+func (api *Tumblr) UserLikes(params map[string]string) Likes {
+	api, span := api.startCall("UserLikes")
+	defer span.End()
 	var userLikes Likes
 	requestURL := apiUserUrl + "likes?"
 	urlParams := url.Values{}
@@ -292,7 +716,9 @@ func (api Tumblr) UserLikes(params map[string]string) Likes {
 // params - A map of the params that are included in this request. Possible parameters:
 //          * limit - The number of results to return.  Default: 20 (1–20, inclusive)
 //          * offset - Liked post number to start at.  Default: 0 (First post)
-func (api Tumblr) UserFollowing(params map[string]string) UserFollowing {
+func (api *Tumblr) UserFollowing(params map[string]string) UserFollowing {
+	api, span := api.startCall("UserFollowing")
+	defer span.End()
 	var userFollowing UserFollowing
 	requestURL := apiUserUrl + "following?"
 	urlParams := url.Values{}
@@ -306,7 +732,9 @@ func (api Tumblr) UserFollowing(params map[string]string) UserFollowing {
 
 // This method is used to follow a specific URL
 // followURL - The url to follow, formatted (blogname.tumblr.com, blogname.com)
-func (api Tumblr) UserFollow(followURL string) Meta {
+func (api *Tumblr) UserFollow(followURL string) Meta {
+	api, span := api.startCall("UserFollow")
+	defer span.End()
 	requestURL := apiUserUrl + "follow"
 	urlParams := url.Values{}
 	urlParams.Set("url", followURL)
@@ -316,7 +744,9 @@ func (api Tumblr) UserFollow(followURL string) Meta {
 
 // This method is used to unfollow a specific URL
 // unfollowURL - The url to unfollow, formatted (blogname.tumblr.com, blogname.com)
-func (api Tumblr) UserUnfollow(unfollowURL string) Meta {
+func (api *Tumblr) UserUnfollow(unfollowURL string) Meta {
+	api, span := api.startCall("UserUnfollow")
+	defer span.End()
 	requestURL := apiUserUrl + "unfollow"
 	urlParams := url.Values{}
 	urlParams.Set("url", unfollowURL)
@@ -324,10 +754,82 @@ func (api Tumblr) UserUnfollow(unfollowURL string) Meta {
 	return response.Meta
 }
 
+// This method is used to retrieve the tags filtered out of the user's dashboard and search
+func (api *Tumblr) UserFilteredTags() UserFilteredTags {
+	api, span := api.startCall("UserFilteredTags")
+	defer span.End()
+	var userFilteredTags UserFilteredTags
+	requestURL := apiUserUrl + "filtered_tags"
+	api.info(requestURL, &userFilteredTags)
+	return userFilteredTags
+}
+
+// This method is used to add tags to the user's tag filters
+// tags - The tags to filter
+func (api *Tumblr) UserFilterTags(tags []string) Meta {
+	api, span := api.startCall("UserFilterTags")
+	defer span.End()
+	requestURL := apiUserUrl + "filtered_tags"
+	urlParams := url.Values{}
+	for _, tag := range tags {
+		urlParams.Add("filtered_tags[]", tag)
+	}
+	response := api.post(requestURL, urlParams.Encode())
+	return response.Meta
+}
+
+// This method is used to remove a tag from the user's tag filters
+// tag - The tag to stop filtering
+func (api *Tumblr) UserUnfilterTag(tag string) Meta {
+	api, span := api.startCall("UserUnfilterTag")
+	defer span.End()
+	requestURL := apiUserUrl + "filtered_tags/" + url.PathEscape(tag)
+	response := api.delete(requestURL, "")
+	return response.Meta
+}
+
+// This method is used to retrieve the strings filtered out of the user's dashboard and search
+func (api *Tumblr) UserFilteredContent() UserFilteredContent {
+	api, span := api.startCall("UserFilteredContent")
+	defer span.End()
+	var userFilteredContent UserFilteredContent
+	requestURL := apiUserUrl + "filtered_content"
+	api.info(requestURL, &userFilteredContent)
+	return userFilteredContent
+}
+
+// This method is used to add strings to the user's content filters
+// content - The strings to filter
+func (api *Tumblr) UserFilterContent(content []string) Meta {
+	api, span := api.startCall("UserFilterContent")
+	defer span.End()
+	requestURL := apiUserUrl + "filtered_content"
+	urlParams := url.Values{}
+	for _, value := range content {
+		urlParams.Add("filtered_content[]", value)
+	}
+	response := api.post(requestURL, urlParams.Encode())
+	return response.Meta
+}
+
+// This method is used to remove a string from the user's content filters
+// content - The string to stop filtering
+func (api *Tumblr) UserUnfilterContent(content string) Meta {
+	api, span := api.startCall("UserUnfilterContent")
+	defer span.End()
+	requestURL := apiUserUrl + "filtered_content"
+	urlParams := url.Values{}
+	urlParams.Set("filtered_content", content)
+	response := api.delete(requestURL, urlParams.Encode())
+	return response.Meta
+}
+
 // This method is used to like a specific blog post
 // id - The ID of the blog post to be liked
 // reblogKey - The reblog key string
-func (api Tumblr) UserLike(id int, reblogKey string) Meta {
+func (api *Tumblr) UserLike(id int, reblogKey string) Meta {
+	api, span := api.startCall("UserLike")
+	defer span.End()
 	requestURL := apiUserUrl + "like"
 	urlParams := url.Values{}
 	urlParams.Set("id", strconv.Itoa(id))
@@ -339,7 +841,9 @@ func (api Tumblr) UserLike(id int, reblogKey string) Meta {
 // This method is used to unlike a specific blog post
 // id - The ID of the blog post to be unliked
 // reblogKey - The reblog key string
-func (api Tumblr) UserUnlike(id int, reblogKey string) Meta {
+func (api *Tumblr) UserUnlike(id int, reblogKey string) Meta {
+	api, span := api.startCall("UserUnlike")
+	defer span.End()
 	requestURL := apiUserUrl + "unlike"
 	urlParams := url.Values{}
 	urlParams.Set("id", strconv.Itoa(id))
@@ -356,7 +860,9 @@ func (api Tumblr) UserUnlike(id int, reblogKey string) Meta {
 //                     on the post object for pagination.
 //          * limit - The number of results to return: 1–20, inclusive
 //          * filter - Specifies the post format to return, other than HTML (text or raw)
-func (api Tumblr) TaggedPosts(tag string, params map[string]string) []Post {
+func (api *Tumblr) TaggedPosts(tag string, params map[string]string) []Post {
+	api, span := api.startCall("TaggedPosts")
+	defer span.End()
 	var taggedPosts []Post
 	requestURL := apiTaggedUrl
 	urlParams := url.Values{}
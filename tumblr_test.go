@@ -2,10 +2,15 @@ package tumblr
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 var testClient *Tumblr
@@ -97,6 +102,57 @@ func TestBlogFollowers(t *testing.T) {
 	}
 }
 
+func TestBlogFollowing(t *testing.T) {
+	setup()
+	params := map[string]string{
+		"limit": "20",
+	}
+	blogFollowing := testClient.BlogFollowing("mattcunningham.net", params)
+	if blogFollowing.TotalBlogs <= 0 {
+		t.Error("Incorrect following count returned")
+	}
+	for _, blog := range blogFollowing.Blogs {
+		if blog.Name == "" {
+			t.Error("Invalid followed blog name returned")
+		}
+	}
+}
+
+func TestBlogFollowedBy(t *testing.T) {
+	setup()
+	blogFollowedBy := testClient.BlogFollowedBy("testnames.tumblr.com", "mattcunningham")
+	if !blogFollowedBy.FollowedBy {
+		t.Error("Blog was not reported as followed")
+	}
+}
+
+func TestBlogNotifications(t *testing.T) {
+	setup()
+	params := map[string]string{
+		"types": "like,reblog_naked",
+	}
+	blogNotifications := testClient.BlogNotifications("mattcunningham.net", params)
+	for _, notification := range blogNotifications.Notifications {
+		if notification.Type != "like" && notification.Type != "reblog_naked" {
+			t.Errorf("Unfiltered notification type %s returned", notification.Type)
+		}
+	}
+}
+
+func TestNotificationPoller(t *testing.T) {
+	setup()
+	poller := testClient.NewNotificationPoller("mattcunningham.net", make(map[string]string), 0)
+	first := poller.Poll()
+	if len(first) > 0 && poller.LastSeen != first[len(first)-1].Timestamp {
+		t.Error("Poller did not record the newest notification")
+	}
+	for _, notification := range poller.Poll() {
+		if notification.Timestamp < poller.LastSeen {
+			t.Error("Poller returned an already seen notification")
+		}
+	}
+}
+
 func TestBlogPosts(t *testing.T) {
 	setup()
 	params := map[string]string{
@@ -108,6 +164,24 @@ func TestBlogPosts(t *testing.T) {
 	}
 }
 
+func TestGetPost(t *testing.T) {
+	setup()
+	post := testClient.GetPost("testnames.tumblr.com", 122517491420, make(map[string]string))
+	if post.ID != 122517491420 {
+		t.Error("Incorrect post returned")
+	}
+	if len(post.Content) <= 0 {
+		t.Error("NPF content was not returned")
+	}
+	params := map[string]string{
+		"post_format": "legacy",
+	}
+	post = testClient.GetPost("testnames.tumblr.com", 122517491420, params)
+	if post.Type == "" || len(post.Content) > 0 {
+		t.Error("Legacy post was not returned")
+	}
+}
+
 func TestBlogQueuedPosts(t *testing.T) {
 	setup()
 	params := map[string]string{
@@ -135,6 +209,20 @@ func TestPost(t *testing.T) {
 	}
 }
 
+func TestCreatePost(t *testing.T) {
+	setup()
+	params := map[string]string{
+		"state": "private",
+		"type":  "text",
+		"title": "Testing Title",
+		"body":  "Test text",
+	}
+	createdPost := testClient.CreatePost("testnames.tumblr.com", params)
+	if createdPost.Meta.Status != 201 || createdPost.ID == 0 {
+		t.Errorf("Test post did not post, response returned %d with ID %d\n", createdPost.Meta.Status, createdPost.ID)
+	}
+}
+
 func TestPostEdit(t *testing.T) {
 	setup()
 	params := map[string]string{
@@ -173,6 +261,159 @@ func TestPostDelete(t *testing.T) {
 	}
 }
 
+func TestPostMute(t *testing.T) {
+	setup()
+	response := testClient.PostMute("testnames.tumblr.com", 122517491420, time.Hour)
+	if response.Status != 200 {
+		t.Errorf("Test post was not muted, response returned %d", response.Status)
+	}
+}
+
+func TestPostUnmute(t *testing.T) {
+	setup()
+	response := testClient.PostUnmute("testnames.tumblr.com", 122517491420)
+	if response.Status != 200 {
+		t.Errorf("Test post was not unmuted, response returned %d", response.Status)
+	}
+}
+
+func TestPostMutePopular(t *testing.T) {
+	setup()
+	results, err := testClient.PostMutePopular("testnames.tumblr.com", 0, time.Minute)
+	if err != nil {
+		t.Fatalf("Posts could not be listed: %v", err)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("Post %d was not muted: %v", result.ID, result.Err)
+		}
+		if result.Post.NoteCount <= 0 {
+			t.Error("Post below the threshold was muted")
+		}
+	}
+}
+
+func TestPostMutePopularFailures(t *testing.T) {
+	var muted []string
+	server := newTestServer(false, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/blog/staff.tumblr.com/posts" && r.URL.Query().Get("offset") == "0":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"total_posts":5,"posts":[
+				{"id":1,"note_count":10},{"id":2,"note_count":0},{"id":3,"note_count":20}]}}`)
+		case r.URL.Path == "/v2/blog/staff.tumblr.com/posts":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"meta":{"status":500,"msg":"Server Error"},"response":[]}`)
+		case r.URL.Path == "/v2/blog/staff.tumblr.com/posts/3/mute":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"meta":{"status":403,"msg":"Forbidden"},"response":[]}`)
+		default:
+			muted = append(muted, r.URL.Path)
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":[]}`)
+		}
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetMaxRetries(0)
+	results, err := client.PostMutePopular("staff.tumblr.com", 5, 0)
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.Meta.Status != 500 {
+		t.Errorf("A page that could not be listed returned %v", err)
+	}
+	if len(results) != 2 || results[0].ID != 1 || results[0].Err != nil || results[1].ID != 3 || !errors.As(results[1].Err, &apiError) || apiError.Meta.Status != 403 {
+		t.Errorf("Muting returned %+v", results)
+	}
+	if len(muted) != 1 || muted[0] != "/v2/blog/staff.tumblr.com/posts/1/mute" {
+		t.Errorf("Muted %v", muted)
+	}
+}
+
+func TestBlogSubmissions(t *testing.T) {
+	setup()
+	submissions := testClient.BlogSubmissions("testnames.tumblr.com", make(map[string]string))
+	for _, post := range submissions.Posts {
+		if post.BlogName == "" {
+			t.Error("Incorrect short blog name")
+		}
+	}
+}
+
+func TestBlogAsks(t *testing.T) {
+	setup()
+	asks := testClient.BlogAsks("testnames.tumblr.com", make(map[string]string))
+	for _, ask := range asks {
+		if ask.Type != "answer" || ask.Question == "" {
+			t.Error("Submission returned that is not an ask")
+		}
+	}
+}
+
+func TestAskAnswer(t *testing.T) {
+	setup()
+	asks := testClient.BlogAsks("testnames.tumblr.com", make(map[string]string))
+	if len(asks) <= 0 {
+		t.Skip("No asks waiting in the test blog's inbox")
+	}
+	response := testClient.AskAnswer("testnames.tumblr.com", asks[0].ID, "Test answer", "private")
+	if response.Status != 200 {
+		t.Errorf("Test ask was not answered, response returned %d", response.Status)
+	}
+}
+
+func TestAskAnswerNPF(t *testing.T) {
+	setup()
+	asks := testClient.BlogAsks("testnames.tumblr.com", make(map[string]string))
+	if len(asks) <= 0 {
+		t.Skip("No asks waiting in the test blog's inbox")
+	}
+	answer := []ContentBlock{{Type: "text", Text: "Test answer"}}
+	response, err := testClient.AskAnswerNPF("testnames.tumblr.com", asks[0].ID, answer, "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != 200 {
+		t.Errorf("Test ask was not answered, response returned %d", response.Status)
+	}
+}
+
+func TestAskAnswerNPFErrors(t *testing.T) {
+	server := newTestServer(false, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != "GET":
+			t.Errorf("Ask was edited with %s %s", r.Method, r.URL.Path)
+		case strings.HasSuffix(r.URL.Path, "/posts/1"):
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"id":1,"type":"blocks","content":[]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"meta":{"status":404,"msg":"Not Found"},"response":[]}`)
+		}
+	})
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetMaxRetries(0)
+	answer := []ContentBlock{{Type: "text", Text: "Answer"}}
+	if _, err := client.AskAnswerNPF("staff.tumblr.com", 1, answer, "published"); !errors.Is(err, ErrAskWithoutContent) {
+		t.Errorf("Answering an ask without content returned %v", err)
+	}
+	var apiError *APIError
+	if _, err := client.AskAnswerNPF("staff.tumblr.com", 2, answer, "published"); !errors.As(err, &apiError) || apiError.Meta.Status != 404 {
+		t.Errorf("Answering a missing ask returned %v", err)
+	}
+}
+
+func TestAskDelete(t *testing.T) {
+	setup()
+	asks := testClient.BlogAsks("testnames.tumblr.com", make(map[string]string))
+	if len(asks) <= 0 {
+		t.Skip("No asks waiting in the test blog's inbox")
+	}
+	response := testClient.AskDelete("testnames.tumblr.com", asks[0].ID)
+	if response.Status != 200 {
+		t.Errorf("Test ask was not deleted, response returned %d", response.Status)
+	}
+}
+
 func TestUserInfo(t *testing.T) {
 	setup()
 	userInfo := testClient.UserInfo()
@@ -181,6 +422,14 @@ func TestUserInfo(t *testing.T) {
 	}
 }
 
+func TestUserLimits(t *testing.T) {
+	setup()
+	userLimits := testClient.UserLimits()
+	if userLimits.User.Posts.Limit <= 0 {
+		t.Errorf("User limits didn't return the post limit")
+	}
+}
+
 func TestUserDashboard(t *testing.T) {
 	setup()
 	params := map[string]string{
@@ -230,6 +479,50 @@ func TestUserUnfollow(t *testing.T) {
 	}
 }
 
+func TestUserFilterTags(t *testing.T) {
+	setup()
+	response := testClient.UserFilterTags([]string{"gumblrtest"})
+	if response.Status != 200 {
+		t.Errorf("Test tag was not filtered, response returned %d", response.Status)
+	}
+	userFilteredTags := testClient.UserFilteredTags()
+	found := false
+	for _, tag := range userFilteredTags.FilteredTags {
+		if tag == "gumblrtest" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Filtered tag was not returned")
+	}
+	response = testClient.UserUnfilterTag("gumblrtest")
+	if response.Status != 200 {
+		t.Errorf("Test tag was not unfiltered, response returned %d", response.Status)
+	}
+}
+
+func TestUserFilterContent(t *testing.T) {
+	setup()
+	response := testClient.UserFilterContent([]string{"gumblr test"})
+	if response.Status != 200 {
+		t.Errorf("Test content was not filtered, response returned %d", response.Status)
+	}
+	userFilteredContent := testClient.UserFilteredContent()
+	found := false
+	for _, content := range userFilteredContent.FilteredContent {
+		if content == "gumblr test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Filtered content was not returned")
+	}
+	response = testClient.UserUnfilterContent("gumblr test")
+	if response.Status != 200 {
+		t.Errorf("Test content was not unfiltered, response returned %d", response.Status)
+	}
+}
+
 func TestUserLike(t *testing.T) {
 	setup()
 	response := testClient.UserLike(122517491420, "kaGXZHdj")
@@ -15,32 +15,40 @@ type Meta struct {
 
 // /info — Retrieve Blog Info
 type BlogInfo struct {
-	Blog struct {
-		Title                string `json:"title"`                   // The display title of the blog
-		PostCount            int    `json:"posts"`                   // The total number of posts to this blog
-		Name                 string `json:"name"`                    // The short blog name that appears before tumblr.com in a standard blog hostname
-		Updated              int    `json:"updated"`                 // The time of the most recent post, in seconds since the epoch
-		Description          string `json:"description"`             // The blog's description
-		Ask                  bool   `json:"ask"`                     // Indicates whether the blog allows questions
-		AskAnon              bool   `json:"ask_anon"`                // Indicates whether the blog allows anonymous questions
-		Likes                int    `json:"likes"`                   // Number of likes for this user
-		IsBlockedFromPrimary bool   `json:"is_blocked_from_primary"` // Indicates whether this blog has been blocked by the calling user's primary blog
-	} `json:"blog"`
+	RawFields
+	Blog Blog `json:"blog"`
+}
+
+// The blog object of an /info response
+type Blog struct {
+	RawFields
+	Title                string `json:"title"`                   // The display title of the blog
+	PostCount            int    `json:"posts"`                   // The total number of posts to this blog
+	Name                 string `json:"name"`                    // The short blog name that appears before tumblr.com in a standard blog hostname
+	Updated              int    `json:"updated"`                 // The time of the most recent post, in seconds since the epoch
+	Description          string `json:"description"`             // The blog's description
+	Ask                  bool   `json:"ask"`                     // Indicates whether the blog allows questions
+	AskAnon              bool   `json:"ask_anon"`                // Indicates whether the blog allows anonymous questions
+	Likes                int    `json:"likes"`                   // Number of likes for this user
+	IsBlockedFromPrimary bool   `json:"is_blocked_from_primary"` // Indicates whether this blog has been blocked by the calling user's primary blog
 }
 
 // /avatar — Retrieve a Blog Avatar
 type BlogAvatar struct {
+	RawFields
 	AvatarURL string `json:"avatar_url"` // The URL of the avatar image.
 }
 
 // /likes - Retrieve Blog's Likes
 type Likes struct {
+	RawFields
 	LikedPost  []Post `json:"liked_posts"` // An array of post objects (posts liked by the user)
 	LikedCount int    `json:"liked_count"` // Total number of liked posts
 }
 
 // /followers — Retrieve a Blog's Followers
 type BlogFollowers struct {
+	RawFields
 	TotalUsers int `json:"total_users"` // The number of users currently following the blog
 	Users      []struct {
 		Name      string `json:"name"`      // The user's name on tumblr
@@ -50,18 +58,68 @@ type BlogFollowers struct {
 	} `json:"users"`
 }
 
+// /following — Retrieve Blogs Followed by a Blog
+type BlogFollowing struct {
+	RawFields
+	TotalBlogs int `json:"total_blogs"` // The number of blogs the blog is following
+	Blogs      []struct {
+		Name        string `json:"name"`        // the short name of the blog that's being followed
+		URL         string `json:"url"`         // the URL of the blog that's being followed
+		Updated     int    `json:"updated"`     // the time of the most recent post, in seconds since the epoch
+		Title       string `json:"title"`       // the title of the blog
+		Description string `json:"description"` // the description of the blog
+	} `json:"blogs"`
+}
+
+// /followed_by — Check If Followed By Blog
+type BlogFollowedBy struct {
+	RawFields
+	FollowedBy bool `json:"followed_by"` // Whether the queried blog follows the blog
+}
+
+// /notifications — Retrieve a Blog's Activity
+type BlogNotifications struct {
+	RawFields
+	Notifications []Notification `json:"notifications"` // The notifications, newest first
+	Links         struct {
+		Next struct {
+			Href string `json:"href"` // the path of the next page of notifications
+		} `json:"next"`
+	} `json:"_links"`
+}
+
+type Notification struct {
+	RawFields
+	ID                   string `json:"id"`                      // The notification's unique ID
+	Type                 string `json:"type"`                    // The type of notification, e.g. like, reblog_naked, follow, ask
+	Timestamp            int    `json:"timestamp"`               // The time of the notification, in seconds since the epoch
+	Unread               bool   `json:"unread"`                  // Indicates whether the notification is unread
+	TargetPostID         string `json:"target_post_id"`          // The ID of the post the notification is about
+	TargetPostSummary    string `json:"target_post_summary"`     // A summary of the post the notification is about
+	TargetPostType       string `json:"target_post_type"`        // The type of the post the notification is about
+	TargetTumblelogName  string `json:"target_tumblelog_name"`   // The name of the blog that received the notification
+	FromTumblelogName    string `json:"from_tumblelog_name"`     // The name of the blog that triggered the notification
+	FromTumblelogIsAdult bool   `json:"from_tumblelog_is_adult"` // Indicates whether the triggering blog is marked as adult
+	Followed             bool   `json:"followed"`                // Indicates whether the blog follows the triggering blog
+	PostID               string `json:"post_id"`                 // The ID of the reblog, reply or answer, if any
+	ReplyText            string `json:"reply_text"`              // The text of a reply
+}
+
 type BlogList struct {
+	RawFields
 	Posts []Post `json:"posts"`
 }
 
 // /posts – Retrieve Published Posts
 type BlogPosts struct {
+	RawFields
 	BlogInfo          // Each response includes a blog object that is the equivalent of an /info response.
 	Posts      []Post `json:"posts"`
 	TotalPosts int    `json:"total_posts"` // The total number of post available for this request, useful for paginating through results
 }
 
 type Post struct {
+	RawFields
 	BlogName    string   `json:"blog_name"`    // The short name used to uniquely identify a blog
 	ID          int      `json:"id"`           // The post's unique ID
 	PostURL     string   `json:"post_url"`     // The location of the post
@@ -78,6 +136,7 @@ type Post struct {
 	Liked       bool     `json:"liked"`        // Indicates if a user has already liked a post or not
 	NoteCount   int      `json:"note_count"`   // Indicates total count of likes, reposts, etc...
 	State       string   `json:"state"`        // Indicates the current state of the post
+	Slug        string   `json:"slug"`         // The short text summary at the end of the post's URL
 	// Text posts
 	Title string `json:"title,omitempty"` // The optional title of the post
 	Body  string `json:"body,omitempty"`  // The full post body
@@ -112,7 +171,7 @@ type Post struct {
 		Phrase string `json:"phrase,omitempty"` // text
 	} `json:"dialogue,omitempty"`
 	// Audio posts
-	AudioPlayer string `json:"player,omitempty"`       // HTML for embedding the audio player
+	AudioPlayer string `json:"-"`                      // HTML for embedding the audio player, decoded by postPlayer
 	PlayCount   int    `json:"plays,omitempty"`        // Number of times the audio post has been played
 	AlbumArt    string `json:"album_art,omitempty"`    // Location of the audio file's ID3 album art image
 	Artist      string `json:"artist,omitempty"`       // The audio file's ID3 artist value
@@ -124,16 +183,146 @@ type Post struct {
 	Player []struct {
 		Width     int    `json:"width,omitempty"`      // the width of the video player
 		EmbedCode string `json:"embed_code,omitempty"` // HTML for embedding the video player
-	} `json:"player,omitempty"`
+	} `json:"-"` // decoded by postPlayer
 	// Answer posts
 	AskingName string `json:"asking_name,omitempty"` // The blog name of the user asking the question
 	AskingURL  string `json:"asking_url,omitempty"`  // The blog URL of the user asking the question
 	Question   string `json:"question,omitempty"`    // The question being asked
 	Answer     string `json:"answer,omitempty"`      // The answer given
+	// Neue Post Format (NPF) posts
+	Content []ContentBlock `json:"content,omitempty"` // The content blocks of the post
+	Layout  []LayoutBlock  `json:"layout,omitempty"`  // The layout of the content blocks
+	Trail   []struct {
+		Post struct {
+			ID string `json:"id,omitempty"` // the ID of the post in the trail
+		} `json:"post,omitempty"`
+		Blog struct {
+			Name string `json:"name,omitempty"` // the short name of the blog in the trail
+		} `json:"blog,omitempty"`
+		Content []ContentBlock `json:"content,omitempty"` // the content blocks of the trail item
+		Layout  []LayoutBlock  `json:"layout,omitempty"`  // the layout of the trail item
+	} `json:"trail,omitempty"` // The reblog trail of the post
+}
+
+// The result of creating a post with CreatePost
+type CreatedPost struct {
+	RawFields
+	Meta  Meta   `json:"-"`               // The status of the request
+	ID    int    `json:"id"`              // The ID of the new post, 0 when it wasn't created
+	State string `json:"state,omitempty"` // The state of the new post, e.g. published or queued
+}
+
+// A content block of a Neue Post Format post (https://www.tumblr.com/docs/npf)
+type ContentBlock struct {
+	RawFields
+	Type        string       `json:"type"`                  // The type of block: text, image, link, audio, video or poll
+	Subtype     string       `json:"subtype,omitempty"`     // The text subtype: heading1, heading2, quirky, quote, indented, chat, ordered-list-item or unordered-list-item
+	Text        string       `json:"text,omitempty"`        // The text of a text block
+	Formatting  []Formatting `json:"formatting,omitempty"`  // Inline formatting applied to the text
+	Media       MediaList    `json:"media,omitempty"`       // The media objects of an image, audio or video block
+	URL         string       `json:"url,omitempty"`         // The URL of a link, audio or video block
+	Title       string       `json:"title,omitempty"`       // The title of a link or audio block
+	Description string       `json:"description,omitempty"` // The description of a link block
+	Provider    string       `json:"provider,omitempty"`    // The provider of an audio or video block
+	Artist      string       `json:"artist,omitempty"`      // The artist of an audio block
+	Album       string       `json:"album,omitempty"`       // The album of an audio block
+	EmbedHTML   string       `json:"embed_html,omitempty"`  // HTML for embedding an audio or video player
+	AltText     string       `json:"alt_text,omitempty"`    // The alt text of an image block
+	Caption     string       `json:"caption,omitempty"`     // The caption of an image block
+}
+
+// Inline formatting of a range of text within a text block
+type Formatting struct {
+	RawFields
+	Start int    `json:"start"`         // the starting index of the range
+	End   int    `json:"end"`           // the ending index of the range
+	Type  string `json:"type"`          // bold, italic, strikethrough, small, link, mention or color
+	URL   string `json:"url,omitempty"` // the URL of a link
+	Blog  struct {
+		UUID string `json:"uuid,omitempty"` // the UUID of a mentioned blog
+		Name string `json:"name,omitempty"` // the name of a mentioned blog
+		URL  string `json:"url,omitempty"`  // the URL of a mentioned blog
+	} `json:"blog,omitempty"` // the blog of a mention
+	Hex string `json:"hex,omitempty"` // the color of a color range
+}
+
+// A media object of an image, audio or video block
+type Media struct {
+	RawFields
+	URL    string `json:"url"`              // location of the media file
+	Type   string `json:"type,omitempty"`   // MIME type of the media file
+	Width  int    `json:"width,omitempty"`  // width of the media
+	Height int    `json:"height,omitempty"` // height of the media
+}
+
+// Audio and video posts both return their player as player, HTML for audio and a
+// list of sizes for video, so it is decoded through postPlayer into AudioPlayer or
+// Player.
+func (post *Post) UnmarshalJSON(data []byte) error {
+	type plain Post
+	return json.Unmarshal(data, &struct {
+		*plain
+		Player postPlayer `json:"player"`
+	}{(*plain)(post), postPlayer{post}})
+}
+
+// The player of a post being decoded, see Post.UnmarshalJSON
+type postPlayer struct {
+	post *Post
+}
+
+func (player postPlayer) UnmarshalJSON(data []byte) error {
+	switch data[0] {
+	case '"':
+		return json.Unmarshal(data, &player.post.AudioPlayer)
+	case '[':
+		return json.Unmarshal(data, &player.post.Player)
+	}
+	return nil
+}
+
+// Image blocks return a list of media objects while audio and video blocks
+// return a single one, so both are decoded into a list.
+type MediaList []Media
+
+func (m *MediaList) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var media Media
+		if err := json.Unmarshal(data, &media); err != nil {
+			return err
+		}
+		*m = MediaList{media}
+		return nil
+	}
+	var list []Media
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*m = list
+	return nil
+}
+
+// A layout block of a Neue Post Format post
+type LayoutBlock struct {
+	RawFields
+	Type    string `json:"type"` // rows, condensed or ask
+	Display []struct {
+		Blocks []int `json:"blocks"` // the content block indices shown in a row
+	} `json:"display,omitempty"` // the rows of a rows layout
+	Blocks        []int `json:"blocks,omitempty"`         // the content block indices of an ask or condensed layout
+	TruncateAfter int   `json:"truncate_after,omitempty"` // the last block index shown before the "keep reading" break
+	Attribution   *struct {
+		Type string `json:"type"` // the type of the attribution, e.g. blog
+		Blog struct {
+			Name string `json:"name,omitempty"` // the name of the asking blog
+			URL  string `json:"url,omitempty"`  // the URL of the asking blog
+		} `json:"blog,omitempty"`
+	} `json:"attribution,omitempty"` // the asker of an ask layout, absent for anonymous asks
 }
 
 // /user/info – Get a User's Information
 type UserInfo struct {
+	RawFields
 	User struct {
 		Following         int    `json:"following"`           // The number of blogs the user is following
 		DefaultPostFormat string `json:"default_post_format"` // The default posting format - html, markdown or raw
@@ -152,8 +341,43 @@ type UserInfo struct {
 	} `json:"user"`
 }
 
+// /user/limits – Get a User's Limits
+type UserLimits struct {
+	RawFields
+	User struct {
+		Blogs        Limit `json:"blogs"`         // Blogs the user can create
+		Follows      Limit `json:"follows"`       // Blogs the user can follow
+		Likes        Limit `json:"likes"`         // Posts the user can like
+		Photos       Limit `json:"photos"`        // Photos the user can upload
+		Posts        Limit `json:"posts"`         // Posts the user can publish
+		VideoSeconds Limit `json:"video_seconds"` // Seconds of video the user can upload
+		Videos       Limit `json:"videos"`        // Videos the user can upload
+	} `json:"user"`
+}
+
+type Limit struct {
+	RawFields
+	Description string `json:"description"` // A description of the limit
+	Limit       int    `json:"limit"`       // The total allowed in a period
+	Remaining   int    `json:"remaining"`   // The amount remaining in the current period
+	ResetAt     int    `json:"reset_at"`    // The time the limit resets, in seconds since the epoch
+}
+
+// /user/filtered_tags – Get a User's Tag Filters
+type UserFilteredTags struct {
+	RawFields
+	FilteredTags []string `json:"filtered_tags"` // The tags filtered by the user
+}
+
+// /user/filtered_content – Get a User's Content Filters
+type UserFilteredContent struct {
+	RawFields
+	FilteredContent []string `json:"filtered_content"` // The strings filtered by the user
+}
+
 // /user/following
 type UserFollowing struct {
+	RawFields
 	TotalBlogs int `json:"total_blogs"` // The number of blogs the user is following
 	Blogs      []struct {
 		Name        string `json:"name"`        // the user name attached to the blog that's being followed
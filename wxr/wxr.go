@@ -0,0 +1,517 @@
+// Package wxr exports Tumblr blogs to WXR, the WordPress eXtended RSS format that
+// the WordPress importer reads.
+//
+// Every post becomes a WordPress post with the matching post format: photo posts
+// are image or gallery posts, quote, link, chat, audio and video posts keep their
+// kind, and text and answer posts are standard posts. Tags become post tags, and
+// photos become attachments of their post, which the importer downloads when
+// asked to import file attachments; the first photo is the post's featured image.
+package wxr
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tumblr "github.com/mattcunningham/gumblr"
+)
+
+// The version of WXR written
+const Version = "1.2"
+
+// An export of a blog to WXR
+type Exporter struct {
+	BlogHostname string       // The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+	Author       string       // The WordPress login the posts are attributed to. Default: the blog's name
+	Logger       *slog.Logger // Receives the progress of an export. Default: silent
+
+	api *tumblr.Tumblr
+}
+
+// The numbers of items an export wrote
+type ExportResult struct {
+	Posts       int `json:"posts"`       // Posts
+	Attachments int `json:"attachments"` // Photos, as attachments
+	Tags        int `json:"tags"`        // Distinct tags
+}
+
+// This method creates an export of a blog
+// blogHostname - The standard or custom blog hostname (e.g., example.tumblr.com, example.com)
+func NewExporter(api *tumblr.Tumblr, blogHostname string) *Exporter {
+	return &Exporter{BlogHostname: blogHostname, Logger: slog.New(slog.DiscardHandler), api: api}
+}
+
+// This method pages through the blog's published posts and writes them as WXR
+// ctx - The context of every request
+// w - Where the WXR document is written
+func (exporter *Exporter) Export(ctx context.Context, w io.Writer) (ExportResult, error) {
+	api := exporter.api.WithContext(ctx)
+	api.SetPreserveRaw(true) // for the slugs and media URLs of posts
+
+	var blog tumblr.Blog
+	if err := api.Try(ctx, func(api *tumblr.Tumblr) { blog = api.BlogInfo(exporter.BlogHostname).Blog }); err != nil {
+		return ExportResult{}, fmt.Errorf("wxr: %w", err)
+	}
+	var posts []tumblr.Post
+	for {
+		var page tumblr.BlogPosts
+		err := api.Try(ctx, func(api *tumblr.Tumblr) {
+			page = api.BlogPosts(exporter.BlogHostname, map[string]string{"offset": strconv.Itoa(len(posts)), "limit": "20"})
+		})
+		if err != nil {
+			return ExportResult{}, fmt.Errorf("wxr: %w", err)
+		}
+		posts = append(posts, page.Posts...)
+		exporter.Logger.Info("posts fetched", "posts", len(posts), "total", page.TotalPosts)
+		if len(page.Posts) == 0 || len(posts) >= page.TotalPosts {
+			break
+		}
+	}
+	return exporter.Write(w, blog, posts)
+}
+
+// This method writes posts as WXR, e.g. posts read with archive.ReadPosts
+// w - Where the WXR document is written
+// blog - The blog of the posts
+// posts - The posts
+func (exporter *Exporter) Write(w io.Writer, blog tumblr.Blog, posts []tumblr.Post) (ExportResult, error) {
+	var result ExportResult
+	var siteURL string
+	json.Unmarshal(blog.Extra["url"], &siteURL)
+	if siteURL == "" {
+		siteURL = "https://" + exporter.BlogHostname + "/"
+	}
+	author := exporter.Author
+	if author == "" {
+		author = blog.Name
+	}
+	document := rss{
+		Version: "2.0",
+		Excerpt: "http://wordpress.org/export/" + Version + "/excerpt/",
+		Content: "http://purl.org/rss/1.0/modules/content/",
+		WFW:     "http://wellformedweb.org/CommentAPI/",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		WP:      "http://wordpress.org/export/" + Version + "/",
+		Channel: channel{
+			Title:       blog.Title,
+			Link:        siteURL,
+			Description: blog.Description,
+			PubDate:     time.Now().UTC().Format(time.RFC1123Z),
+			Language:    "en",
+			Version:     Version,
+			BaseSiteURL: siteURL,
+			BaseBlogURL: siteURL,
+			Authors:     []wpAuthor{{ID: 1, Login: cdata(author), DisplayName: cdata(author)}},
+			Generator:   "gumblr",
+		},
+	}
+
+	// Attachments are numbered after the posts, whose IDs they mustn't take
+	nextID := 0
+	tags := make(map[string]string) // slugs by tag
+	for _, post := range posts {
+		nextID = max(nextID, post.ID)
+		for _, tag := range post.Tags {
+			if _, found := tags[tag]; !found {
+				tags[tag] = slug(tag)
+			}
+		}
+	}
+	// Tumblr tags ignore case, so tags with the same slug are one term, named as
+	// first seen
+	names := make(map[string]string) // tags by slug
+	for _, post := range posts {
+		for _, tag := range post.Tags {
+			if _, found := names[tags[tag]]; !found {
+				names[tags[tag]] = tag
+			}
+		}
+	}
+	slugs := make([]string, 0, len(names))
+	for tagSlug := range names {
+		slugs = append(slugs, tagSlug)
+	}
+	sort.Strings(slugs)
+	for i, tagSlug := range slugs {
+		document.Channel.Tags = append(document.Channel.Tags, wpTag{ID: i + 1, Slug: tagSlug, Name: cdata(names[tagSlug])})
+	}
+	result.Tags = len(slugs)
+	for i, format := range postFormats {
+		document.Channel.Terms = append(document.Channel.Terms, wpTerm{ID: len(slugs) + i + 1, Taxonomy: "post_format",
+			Slug: "post-format-" + format, Name: cdata(strings.ToUpper(format[:1]) + format[1:])})
+	}
+
+	for _, post := range posts {
+		item, photos := exporter.item(post, author, tags, names)
+		for i, photo := range photos {
+			nextID++
+			if i == 0 {
+				item.Meta = append(item.Meta, wpMeta{Key: "_thumbnail_id", Value: cdata(strconv.Itoa(nextID))})
+			}
+			attachment := attachmentItem(post, photo, nextID, author)
+			document.Channel.Items = append(document.Channel.Items, attachment)
+			result.Attachments++
+		}
+		document.Channel.Items = append(document.Channel.Items, item)
+		result.Posts++
+	}
+
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return result, err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return result, err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return result, err
+}
+
+// The post formats of WordPress used by exports
+var postFormats = []string{"image", "gallery", "quote", "link", "chat", "audio", "video"}
+
+// A photo of a post
+type photo struct {
+	url     string
+	caption string
+}
+
+// This method returns the item of a post and the photos to attach to it
+func (exporter *Exporter) item(post tumblr.Post, author string, tags map[string]string, names map[string]string) (item, []photo) {
+	date := time.Unix(int64(post.Timestamp), 0).UTC()
+	item := item{
+		Title:         cdata(post.Title),
+		Link:          post.PostURL,
+		PubDate:       date.Format(time.RFC1123Z),
+		Creator:       cdata(author),
+		GUID:          guid{Value: post.PostURL},
+		ID:            post.ID,
+		Date:          cdata(date.Format(time.DateTime)),
+		DateGMT:       cdata(date.Format(time.DateTime)),
+		CommentStatus: cdata("open"),
+		PingStatus:    cdata("open"),
+		Name:          cdata(post.Slug),
+		Status:        cdata("publish"),
+		Type:          cdata("post"),
+		Password:      cdata(""),
+		Meta: []wpMeta{
+			{Key: "_tumblr_post_id", Value: cdata(strconv.Itoa(post.ID))},
+			{Key: "_tumblr_url", Value: cdata(post.PostURL)},
+		},
+	}
+	switch post.State {
+	case "private":
+		item.Status = cdata("private")
+	case "draft":
+		item.Status = cdata("draft")
+	case "queued", "queue":
+		item.Status = cdata("future")
+	}
+	for _, tag := range post.Tags {
+		if !slices.ContainsFunc(item.Categories, func(c category) bool { return c.Nicename == tags[tag] }) {
+			item.Categories = append(item.Categories, category{Domain: "post_tag", Nicename: tags[tag], Name: names[tags[tag]]})
+		}
+	}
+
+	var content strings.Builder
+	var photos []photo
+	format := ""
+	switch post.Type {
+	case "text":
+		content.WriteString(post.Body)
+	case "photo":
+		format = "image"
+		if len(post.Photos) > 1 {
+			format = "gallery"
+		}
+		for _, p := range post.Photos {
+			if p.OriginalSize.URL == "" {
+				continue
+			}
+			photos = append(photos, photo{url: p.OriginalSize.URL, caption: p.Caption})
+			content.WriteString(`<figure><img src="` + html.EscapeString(p.OriginalSize.URL) + `" alt="` + html.EscapeString(p.Caption) + `">`)
+			if p.Caption != "" {
+				content.WriteString("<figcaption>" + html.EscapeString(p.Caption) + "</figcaption>")
+			}
+			content.WriteString("</figure>\n")
+		}
+		content.WriteString(post.Caption)
+	case "quote":
+		format = "quote"
+		content.WriteString("<blockquote>" + post.Text)
+		if post.Source != "" {
+			content.WriteString("<cite>" + post.Source + "</cite>")
+		}
+		content.WriteString("</blockquote>")
+	case "link":
+		format = "link"
+		if post.Title == "" {
+			item.Title = cdata(post.URL)
+		}
+		content.WriteString(`<p><a href="` + html.EscapeString(post.URL) + `">` + html.EscapeString(string(item.Title)) + "</a></p>\n")
+		if post.Excerpt != "" {
+			content.WriteString("<blockquote>" + post.Excerpt + "</blockquote>\n")
+		}
+		content.WriteString(post.Description)
+	case "chat":
+		// The chat format reads a line per message, each starting with the speaker
+		format = "chat"
+		for _, line := range post.Dialogue {
+			label := strings.TrimSpace(line.Label)
+			if label == "" {
+				label = line.Name + ":"
+			}
+			content.WriteString(html.EscapeString(label+" "+line.Phrase) + "\n")
+		}
+	case "answer":
+		asker := html.EscapeString(post.AskingName)
+		if asker == "" {
+			asker = "Anonymous"
+		} else if post.AskingURL != "" {
+			asker = `<a href="` + html.EscapeString(post.AskingURL) + `">` + asker + "</a>"
+		}
+		if post.Title == "" {
+			item.Title = cdata(summarize(post.Question))
+		}
+		content.WriteString("<blockquote><p>" + asker + " asked:</p>\n<p>" + post.Question + "</p></blockquote>\n")
+		content.WriteString(post.Answer)
+	case "audio":
+		format = "audio"
+		content.WriteString(media(post, "audio", post.AudioPlayer) + "\n")
+		content.WriteString(post.Caption)
+	case "video":
+		format = "video"
+		var player string
+		if len(post.Player) > 0 {
+			// Players are listed smallest first
+			player = post.Player[len(post.Player)-1].EmbedCode
+		}
+		content.WriteString(media(post, "video", player) + "\n")
+		content.WriteString(post.Caption)
+	}
+	if content.Len() == 0 {
+		for _, block := range post.Content {
+			switch block.Type {
+			case "text":
+				content.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(block.Text), "\n", "<br>") + "</p>\n")
+			case "image":
+				if len(block.Media) > 0 {
+					photos = append(photos, photo{url: block.Media[0].URL, caption: block.AltText})
+					content.WriteString(`<figure><img src="` + html.EscapeString(block.Media[0].URL) + `" alt="` + html.EscapeString(block.AltText) + "\"></figure>\n")
+				}
+			case "link":
+				content.WriteString(`<p><a href="` + html.EscapeString(block.URL) + `">` + html.EscapeString(block.URL) + "</a></p>\n")
+			}
+		}
+	}
+	if format != "" {
+		item.Categories = append(item.Categories, category{Domain: "post_format", Nicename: "post-format-" + format,
+			Name: strings.ToUpper(format[:1]) + format[1:]})
+	}
+	item.Content = cdata(content.String())
+	return item, photos
+}
+
+// This method returns what embeds the audio or video of a post: a shortcode for
+// Tumblr hosted media, which WordPress plays itself, the URL of media hosted
+// elsewhere, which WordPress embeds, or else Tumblr's player
+func media(post tumblr.Post, kind string, player string) string {
+	var mediaURL, mediaType, permalink string
+	json.Unmarshal(post.Extra[kind+"_url"], &mediaURL)
+	json.Unmarshal(post.Extra[kind+"_type"], &mediaType)
+	json.Unmarshal(post.Extra["permalink_url"], &permalink)
+	switch {
+	case mediaType == "tumblr" && mediaURL != "":
+		return "[" + kind + ` src="` + html.EscapeString(mediaURL) + `"]`
+	case permalink != "":
+		return "\n" + html.EscapeString(permalink) + "\n"
+	}
+	return player
+}
+
+// This method returns the attachment item of a photo
+func attachmentItem(post tumblr.Post, p photo, id int, author string) item {
+	date := time.Unix(int64(post.Timestamp), 0).UTC()
+	name := p.url[strings.LastIndex(p.url, "/")+1:]
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name = name[:i]
+	}
+	title := p.caption
+	if title == "" {
+		title = name
+	}
+	return item{
+		Title:         cdata(title),
+		PubDate:       date.Format(time.RFC1123Z),
+		Creator:       cdata(author),
+		GUID:          guid{Value: p.url},
+		Content:       cdata(""),
+		Excerpt:       cdata(p.caption),
+		ID:            id,
+		Date:          cdata(date.Format(time.DateTime)),
+		DateGMT:       cdata(date.Format(time.DateTime)),
+		CommentStatus: cdata("closed"),
+		PingStatus:    cdata("closed"),
+		Name:          cdata(slug(name)),
+		Status:        cdata("inherit"),
+		Parent:        post.ID,
+		Type:          cdata("attachment"),
+		Password:      cdata(""),
+		AttachmentURL: cdata(p.url),
+	}
+}
+
+// This method returns the WordPress slug of a name: lower case letters and digits
+// separated by hyphens
+func slug(name string) string {
+	var out strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127 && !isSpace(r) {
+			if hyphen && out.Len() > 0 {
+				out.WriteByte('-')
+			}
+			out.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	if out.Len() == 0 {
+		return "tag"
+	}
+	return out.String()
+}
+
+func isSpace(r rune) bool {
+	return strings.TrimSpace(string(r)) == ""
+}
+
+// This method shortens the text of HTML to a title
+func summarize(source string) string {
+	var text strings.Builder
+	inTag := false
+	for _, r := range source {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			text.WriteRune(r)
+		}
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(text.String())), " ")
+	if runes := []rune(title); len(runes) > 80 {
+		title = string(runes[:79]) + "…"
+	}
+	return title
+}
+
+// Text written as a CDATA section, as WordPress writes it
+type cdata string
+
+func (text cdata) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	return encoder.EncodeElement(struct {
+		Value string `xml:",cdata"`
+	}{string(text)}, start)
+}
+
+// The elements of a WXR document
+type rss struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Excerpt string   `xml:"xmlns:excerpt,attr"`
+	Content string   `xml:"xmlns:content,attr"`
+	WFW     string   `xml:"xmlns:wfw,attr"`
+	DC      string   `xml:"xmlns:dc,attr"`
+	WP      string   `xml:"xmlns:wp,attr"`
+	Channel channel  `xml:"channel"`
+}
+
+type channel struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	Description string     `xml:"description"`
+	PubDate     string     `xml:"pubDate"`
+	Language    string     `xml:"language"`
+	Version     string     `xml:"wp:wxr_version"`
+	BaseSiteURL string     `xml:"wp:base_site_url"`
+	BaseBlogURL string     `xml:"wp:base_blog_url"`
+	Authors     []wpAuthor `xml:"wp:author"`
+	Tags        []wpTag    `xml:"wp:tag"`
+	Terms       []wpTerm   `xml:"wp:term"`
+	Generator   string     `xml:"generator"`
+	Items       []item     `xml:"item"`
+}
+
+type wpAuthor struct {
+	ID          int   `xml:"wp:author_id"`
+	Login       cdata `xml:"wp:author_login"`
+	Email       cdata `xml:"wp:author_email"`
+	DisplayName cdata `xml:"wp:author_display_name"`
+}
+
+type wpTag struct {
+	ID   int    `xml:"wp:term_id"`
+	Slug string `xml:"wp:tag_slug"`
+	Name cdata  `xml:"wp:tag_name"`
+}
+
+type wpTerm struct {
+	ID       int    `xml:"wp:term_id"`
+	Taxonomy string `xml:"wp:term_taxonomy"`
+	Slug     string `xml:"wp:term_slug"`
+	Name     cdata  `xml:"wp:term_name"`
+}
+
+type item struct {
+	Title         cdata      `xml:"title"`
+	Link          string     `xml:"link"`
+	PubDate       string     `xml:"pubDate"`
+	Creator       cdata      `xml:"dc:creator"`
+	GUID          guid       `xml:"guid"`
+	Description   string     `xml:"description"`
+	Content       cdata      `xml:"content:encoded"`
+	Excerpt       cdata      `xml:"excerpt:encoded"`
+	ID            int        `xml:"wp:post_id"`
+	Date          cdata      `xml:"wp:post_date"`
+	DateGMT       cdata      `xml:"wp:post_date_gmt"`
+	CommentStatus cdata      `xml:"wp:comment_status"`
+	PingStatus    cdata      `xml:"wp:ping_status"`
+	Name          cdata      `xml:"wp:post_name"`
+	Status        cdata      `xml:"wp:status"`
+	Parent        int        `xml:"wp:post_parent"`
+	MenuOrder     int        `xml:"wp:menu_order"`
+	Type          cdata      `xml:"wp:post_type"`
+	Password      cdata      `xml:"wp:post_password"`
+	Sticky        int        `xml:"wp:is_sticky"`
+	AttachmentURL cdata      `xml:"wp:attachment_url,omitempty"`
+	Categories    []category `xml:"category"`
+	Meta          []wpMeta   `xml:"wp:postmeta"`
+}
+
+type guid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type category struct {
+	Domain   string `xml:"domain,attr"`
+	Nicename string `xml:"nicename,attr"`
+	Name     string `xml:",cdata"`
+}
+
+type wpMeta struct {
+	Key   cdata `xml:"wp:meta_key"`
+	Value cdata `xml:"wp:meta_value"`
+}
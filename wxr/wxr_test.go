@@ -0,0 +1,207 @@
+package wxr
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	tumblr "github.com/mattcunningham/gumblr"
+	"github.com/mattcunningham/gumblr/internal/tumblrtest"
+)
+
+var pages = map[string]string{
+	"0": `[
+		{"id":30,"blog_name":"staff","type":"photo","timestamp":300,"post_url":"https://staff.tumblr.com/post/30/sunset","slug":"sunset",
+			"tags":["Art","Golden Hour"],"caption":"<p>Sunset</p>","photos":[
+			{"caption":"West","original_size":{"url":"https://64.media.tumblr.com/a/west.png"}},
+			{"original_size":{"url":"https://64.media.tumblr.com/b/east.jpg"}}]},
+		{"id":20,"blog_name":"staff","type":"answer","timestamp":200,"post_url":"https://staff.tumblr.com/post/20","tags":["art"],
+			"asking_name":"david","asking_url":"https://david.tumblr.com/","question":"Why <b>this</b>?","answer":"<p>Because.</p>"}
+	]`,
+	"2": `[
+		{"id":10,"blog_name":"staff","type":"chat","timestamp":100,"post_url":"https://staff.tumblr.com/post/10","title":"Talk",
+			"dialogue":[{"label":"A:","name":"A","phrase":"<hi>"},{"label":"B:","name":"B","phrase":"]]> bye"}]},
+		{"id":5,"blog_name":"staff","type":"video","timestamp":50,"post_url":"https://staff.tumblr.com/post/5","state":"private",
+			"video_type":"youtube","permalink_url":"https://www.youtube.com/watch?v=1","caption":"<p>Watch</p>"}
+	]`,
+}
+
+// The elements of a WXR document read back by the tests
+type parsedItem struct {
+	Title      string `xml:"title"`
+	Content    string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	ID         int    `xml:"http://wordpress.org/export/1.2/ post_id"`
+	Name       string `xml:"http://wordpress.org/export/1.2/ post_name"`
+	Status     string `xml:"http://wordpress.org/export/1.2/ status"`
+	Type       string `xml:"http://wordpress.org/export/1.2/ post_type"`
+	Parent     int    `xml:"http://wordpress.org/export/1.2/ post_parent"`
+	URL        string `xml:"http://wordpress.org/export/1.2/ attachment_url"`
+	Categories []struct {
+		Domain   string `xml:"domain,attr"`
+		Nicename string `xml:"nicename,attr"`
+	} `xml:"category"`
+	Meta []struct {
+		Key   string `xml:"http://wordpress.org/export/1.2/ meta_key"`
+		Value string `xml:"http://wordpress.org/export/1.2/ meta_value"`
+	} `xml:"http://wordpress.org/export/1.2/ postmeta"`
+}
+
+type parsedDocument struct {
+	Channel struct {
+		Title   string `xml:"title"`
+		Version string `xml:"http://wordpress.org/export/1.2/ wxr_version"`
+		Tags    []struct {
+			Slug string `xml:"http://wordpress.org/export/1.2/ tag_slug"`
+			Name string `xml:"http://wordpress.org/export/1.2/ tag_name"`
+		} `xml:"http://wordpress.org/export/1.2/ tag"`
+		Items []parsedItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+func (item parsedItem) terms() string {
+	var terms []string
+	for _, category := range item.Categories {
+		terms = append(terms, category.Domain+":"+category.Nicename)
+	}
+	return strings.Join(terms, " ")
+}
+
+func TestExport(t *testing.T) {
+	limited := true
+	_, client := tumblrtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/blog/staff.tumblr.com/info":
+			fmt.Fprint(w, `{"meta":{"status":200,"msg":"OK"},"response":{"blog":{"name":"staff","title":"Staff","url":"https://staff.tumblr.com/"}}}`)
+		case "/v2/blog/staff.tumblr.com/posts":
+			// The second page is rate limited once, which the retry overcomes
+			if r.URL.Query().Get("offset") == "2" && limited {
+				limited = false
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"meta":{"status":429,"msg":"Limit Exceeded"},"response":[]}`)
+				return
+			}
+			fmt.Fprintf(w, `{"meta":{"status":200,"msg":"OK"},"response":{"total_posts":4,"posts":%s}}`, pages[r.URL.Query().Get("offset")])
+		default:
+			t.Errorf("Requested %s", r.URL)
+		}
+	}))
+	client.SetMaxRetries(1)
+
+	var out bytes.Buffer
+	result, err := NewExporter(client, "staff.tumblr.com").Export(context.Background(), &out)
+	if err != nil || limited {
+		t.Fatalf("Export returned %v, rate limited: %t", err, limited)
+	}
+	if result != (ExportResult{Posts: 4, Attachments: 2, Tags: 2}) {
+		t.Errorf("Exported %+v", result)
+	}
+	var document parsedDocument
+	if err := xml.Unmarshal(out.Bytes(), &document); err != nil {
+		t.Fatalf("Invalid XML %s: %v", out.Bytes(), err)
+	}
+	channel := document.Channel
+	if channel.Title != "Staff" || channel.Version != "1.2" {
+		t.Errorf("Channel is %q, version %q", channel.Title, channel.Version)
+	}
+	var tags []string
+	for _, tag := range channel.Tags {
+		tags = append(tags, tag.Slug+"="+tag.Name)
+	}
+	if strings.Join(tags, " ") != "art=Art golden-hour=Golden Hour" {
+		t.Errorf("Tags are %v", tags)
+	}
+	if len(channel.Items) != 6 {
+		t.Fatalf("Wrote %d items", len(channel.Items))
+	}
+
+	// The attachments of a post come before it, numbered after the posts
+	west, east, photo := channel.Items[0], channel.Items[1], channel.Items[2]
+	if west.Type != "attachment" || west.ID != 31 || west.Parent != 30 || west.URL != "https://64.media.tumblr.com/a/west.png" || west.Title != "West" {
+		t.Errorf("First attachment is %+v", west)
+	}
+	if east.ID != 32 || east.Name != "east" || east.Title != "east" || east.Status != "inherit" {
+		t.Errorf("Second attachment is %+v", east)
+	}
+	if photo.Type != "post" || photo.ID != 30 || photo.Name != "sunset" || photo.Status != "publish" ||
+		photo.terms() != "post_tag:art post_tag:golden-hour post_format:post-format-gallery" {
+		t.Errorf("Photo post is %+v", photo)
+	}
+	if !strings.Contains(photo.Content, `<img src="https://64.media.tumblr.com/a/west.png" alt="West"><figcaption>West</figcaption>`) ||
+		!strings.HasSuffix(photo.Content, "<p>Sunset</p>") {
+		t.Errorf("Photo post's content is %q", photo.Content)
+	}
+	var meta []string
+	for _, m := range photo.Meta {
+		meta = append(meta, m.Key+"="+m.Value)
+	}
+	if strings.Join(meta, " ") != "_tumblr_post_id=30 _tumblr_url=https://staff.tumblr.com/post/30/sunset _thumbnail_id=31" {
+		t.Errorf("Photo post's meta is %v", meta)
+	}
+
+	answer := channel.Items[3]
+	if answer.Title != "Why this?" || answer.terms() != "post_tag:art" || answer.Categories[0].Nicename != "art" ||
+		answer.Content != "<blockquote><p><a href=\"https://david.tumblr.com/\">david</a> asked:</p>\n<p>Why <b>this</b>?</p></blockquote>\n<p>Because.</p>" {
+		t.Errorf("Answer is %+v", answer)
+	}
+	chat := channel.Items[4]
+	if chat.Title != "Talk" || chat.terms() != "post_format:post-format-chat" || chat.Content != "A: &lt;hi&gt;\nB: ]]&gt; bye\n" {
+		t.Errorf("Chat is %+v", chat)
+	}
+	video := channel.Items[5]
+	if video.Status != "private" || video.terms() != "post_format:post-format-video" ||
+		video.Content != "\nhttps://www.youtube.com/watch?v=1\n\n<p>Watch</p>" {
+		t.Errorf("Video is %+v", video)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for name, want := range map[string]string{
+		"Golden Hour": "golden-hour",
+		"  c++ & go ": "c-go",
+		"Café":        "café",
+		"!!!":         "tag",
+	} {
+		if got := slug(name); got != want {
+			t.Errorf("slug(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWriteMedia(t *testing.T) {
+	var posts []tumblr.Post
+	for _, data := range []string{
+		`{"id":1,"type":"audio","audio_type":"tumblr","audio_url":"https://a.tumblr.com/1.mp3","player":"<embed src=\"tumblr\">"}`,
+		`{"id":2,"type":"audio","audio_type":"soundcloud","player":"<iframe src=\"https://w.soundcloud.com/player/\"></iframe>"}`,
+		`{"id":3,"type":"video","video_type":"unknown","player":[{"width":250,"embed_code":"<iframe width=\"250\"></iframe>"},
+			{"width":500,"embed_code":"<iframe width=\"500\"></iframe>"}]}`,
+	} {
+		var post tumblr.Post
+		if err := tumblr.UnmarshalRaw([]byte(data), &post); err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, post)
+	}
+	var out bytes.Buffer
+	if _, err := NewExporter(nil, "staff.tumblr.com").Write(&out, tumblr.Blog{Name: "staff"}, posts); err != nil {
+		t.Fatal(err)
+	}
+	var document parsedDocument
+	if err := xml.Unmarshal(out.Bytes(), &document); err != nil {
+		t.Fatalf("Invalid XML %s: %v", out.Bytes(), err)
+	}
+	// Tumblr hosted audio is played by WordPress, other media by its player
+	for i, want := range []string{
+		`[audio src="https://a.tumblr.com/1.mp3"]` + "\n",
+		`<iframe src="https://w.soundcloud.com/player/"></iframe>` + "\n",
+		`<iframe width="500"></iframe>` + "\n",
+	} {
+		if got := document.Channel.Items[i].Content; got != want {
+			t.Errorf("Post %d has content %q, want %q", i+1, got, want)
+		}
+	}
+}